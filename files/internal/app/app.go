@@ -1,14 +1,26 @@
 package app
 
 import (
+	"context"
+	_ "expvar"
+	"log"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 
+	"github.com/avran02/decoplan/files/internal/blobs"
 	"github.com/avran02/decoplan/files/internal/config"
 	"github.com/avran02/decoplan/files/internal/controller"
+	"github.com/avran02/decoplan/files/internal/envelope"
+	"github.com/avran02/decoplan/files/internal/events"
+	"github.com/avran02/decoplan/files/internal/limits"
+	"github.com/avran02/decoplan/files/internal/policy"
+	"github.com/avran02/decoplan/files/internal/scan"
 	"github.com/avran02/decoplan/files/internal/server"
 	"github.com/avran02/decoplan/files/internal/service"
+	"github.com/avran02/decoplan/files/internal/sse"
+	"github.com/avran02/decoplan/files/internal/webhook"
 	"github.com/avran02/decoplan/files/pb"
 
 	"google.golang.org/grpc"
@@ -23,6 +35,9 @@ type App struct {
 	Controller controller.FileServerController
 	Server     server.FileServer
 	Service    service.FilesService
+	Webhooks   *webhook.Dispatcher
+	Blobs      *blobs.Collector
+	Rotator    *sse.Rotator
 }
 
 func (app *App) Run() {
@@ -35,7 +50,19 @@ func (app *App) Run() {
 
 	slog.Info("Listening on " + host)
 
-	grpcServer := grpc.NewServer(opts...)
+	if app.Config.Server.MetricsPort != "" {
+		go app.serveMetrics()
+	}
+
+	go app.Webhooks.Run(context.Background())
+	go app.Blobs.Run(context.Background())
+	go app.Service.RunScans(context.Background())
+	go app.Service.RunThumbnails(context.Background())
+	if app.Rotator != nil {
+		go app.Rotator.Run(context.Background())
+	}
+
+	grpcServer := grpc.NewServer(append(opts, server.ReservedBuckets(app.Config.Webhooks.Bucket, app.Config.Dedup.Bucket)...)...)
 	pb.RegisterFileServiceServer(grpcServer, app.Server)
 
 	healthServer := health.NewServer()
@@ -49,16 +76,66 @@ func (app *App) Run() {
 	}
 }
 
+// serveMetrics exposes the expvar counters, such as the bucket cache hits and
+// misses, on /debug/vars.
+func (app *App) serveMetrics() {
+	host := ":" + app.Config.Server.MetricsPort
+	slog.Info("Serving metrics on " + host)
+	if err := http.ListenAndServe(host, nil); err != nil {
+		slog.Error("failed to serve metrics:\n" + err.Error())
+	}
+}
+
 func New() *App {
 	conf := config.New()
-	service := service.New(conf.Minio)
-	controller := controller.New(service)
+	hub := events.NewHub(config.EventsBufferSize)
+	minioClient := service.NewMinioClient(conf.Minio)
+	store := webhook.NewStore(minioClient, conf.Webhooks.Bucket)
+	sender := webhook.NewSender(conf.Webhooks.Timeout)
+	webhooks := webhook.NewDispatcher(store, sender, hub, conf.Webhooks)
+	keys, err := sse.New(conf.Encryption)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var keyring *envelope.Keyring
+	if conf.Envelope.KeyringFile != "" {
+		if keyring, err = envelope.LoadKeyring(conf.Envelope.KeyringFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	uploads := &policy.Policy{}
+	if conf.Uploads.PolicyFile != "" {
+		if uploads, err = policy.Load(conf.Uploads.PolicyFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	scanner, err := scan.New(conf.Scan)
+	if err != nil {
+		log.Fatal(err)
+	}
+	blobStore := blobs.NewStore(minioClient, conf.Dedup.Bucket, keys)
+	service := service.New(minioClient, hub, store, sender, blobStore, keys, keyring, uploads, scanner, conf)
+	var overrides *limits.Overrides
+	if conf.Limits.OverridesFile != "" {
+		if overrides, err = limits.LoadOverrides(conf.Limits.OverridesFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	controller := controller.New(service, conf.Streaming, limits.New(conf.Limits, overrides))
 	server := server.New(controller)
 
+	var rotator *sse.Rotator
+	if conf.Encryption.ReEncrypt {
+		rotator = sse.NewRotator(minioClient, keys, conf.Webhooks.Bucket)
+	}
+
 	return &App{
 		Config:     conf,
 		Controller: controller,
 		Server:     server,
 		Service:    service,
+		Webhooks:   webhooks,
+		Blobs:      blobs.NewCollector(blobStore, conf.Dedup),
+		Rotator:    rotator,
 	}
 }
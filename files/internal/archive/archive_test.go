@@ -0,0 +1,259 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type file struct {
+	name    string
+	content string
+}
+
+func writeArchive(t *testing.T, format Format, files []file) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, f := range files {
+		if err := w.Add(f.name, int64(len(f.content)), modified, strings.NewReader(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func readArchive(t *testing.T, format Format, data []byte) []file {
+	t.Helper()
+
+	r, err := NewReader(format, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var files []file
+	for {
+		entry, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f := file{name: entry.Name}
+		if entry.Content != nil {
+			content, err := io.ReadAll(entry.Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if int64(len(content)) != entry.Size {
+				t.Errorf("%s has %d bytes, the archive says %d", entry.Name, len(content), entry.Size)
+			}
+			f.content = string(content)
+		}
+		files = append(files, f)
+	}
+
+	return files
+}
+
+func TestRoundTrip(t *testing.T) {
+	files := []file{
+		{name: "a.txt", content: "hello"},
+		{name: "empty", content: ""},
+		{name: "docs/b.txt", content: strings.Repeat("decoplan ", 10000)},
+	}
+
+	for _, format := range []Format{FormatZip, FormatTar, FormatTarGz} {
+		t.Run(string(format), func(t *testing.T) {
+			got := readArchive(t, format, writeArchive(t, format, files))
+			if len(got) != len(files) {
+				t.Fatalf("read %d entries, want %d", len(got), len(files))
+			}
+			for i := range files {
+				if got[i] != files[i] {
+					t.Errorf("entry %d = %q with %d bytes, want %q with %d bytes",
+						i, got[i].name, len(got[i].content), files[i].name, len(files[i].content))
+				}
+			}
+		})
+	}
+}
+
+func TestAddSizeMismatch(t *testing.T) {
+	for _, format := range []Format{FormatZip, FormatTar, FormatTarGz} {
+		t.Run(string(format), func(t *testing.T) {
+			w, err := NewWriter(format, io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = w.Add("short", 10, time.Now(), strings.NewReader("abc"))
+			if !errors.Is(err, ErrSizeMismatch) {
+				t.Errorf("Add() error = %v, want %v", err, ErrSizeMismatch)
+			}
+		})
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	if _, err := NewWriter("rar", io.Discard); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("NewWriter() error = %v, want %v", err, ErrUnsupportedFormat)
+	}
+	if _, err := NewReader("rar", bytes.NewReader(nil), 0); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("NewReader() error = %v, want %v", err, ErrUnsupportedFormat)
+	}
+}
+
+func TestInvalidArchive(t *testing.T) {
+	garbage := []byte("this is not an archive, just some text long enough to have a header")
+
+	for _, format := range []Format{FormatZip, FormatTar, FormatTarGz} {
+		t.Run(string(format), func(t *testing.T) {
+			r, err := NewReader(format, bytes.NewReader(garbage), int64(len(garbage)))
+			if err == nil {
+				_, err = r.Next()
+			}
+			if !errors.Is(err, ErrInvalidArchive) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidArchive)
+			}
+		})
+	}
+}
+
+func TestReaderSkipsUnreadContent(t *testing.T) {
+	for _, format := range []Format{FormatZip, FormatTar} {
+		t.Run(string(format), func(t *testing.T) {
+			data := writeArchive(t, format, []file{{name: "a", content: "first"}, {name: "b", content: "second"}})
+			r, err := NewReader(format, bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			if _, err := r.Next(); err != nil {
+				t.Fatal(err)
+			}
+			entry, err := r.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if content, _ := io.ReadAll(entry.Content); entry.Name != "b" || string(content) != "second" {
+				t.Errorf("second entry = %q: %q", entry.Name, content)
+			}
+		})
+	}
+}
+
+func TestReaderEntryModes(t *testing.T) {
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	if _, err := zw.Create("dir/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	headers := []*tar.Header{
+		{Typeflag: tar.TypeDir, Name: "dir/", Mode: 0o755},
+		{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "/etc/passwd"},
+	}
+	for _, h := range headers {
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		format Format
+		data   []byte
+		want   []bool
+	}{
+		{name: "zip directory", format: FormatZip, data: zipBuf.Bytes(), want: []bool{false}},
+		{name: "tar directory and link", format: FormatTar, data: tarBuf.Bytes(), want: []bool{false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(tt.format, bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			for i, regular := range tt.want {
+				entry, err := r.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if entry.Mode.IsRegular() != regular {
+					t.Errorf("entry %d %q has mode %v", i, entry.Name, entry.Mode)
+				}
+			}
+			if _, err := r.Next(); !errors.Is(err, io.EOF) {
+				t.Errorf("Next() after the last entry = %v, want EOF", err)
+			}
+		})
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "a.txt", want: "a.txt"},
+		{name: "./docs/a.txt", want: "docs/a.txt"},
+		{name: "docs/sub/a.txt", want: "docs/sub/a.txt"},
+		{name: "../a.txt", wantErr: true},
+		{name: "docs/../../a.txt", wantErr: true},
+		{name: "docs/../a.txt", wantErr: true},
+		{name: "/etc/passwd", wantErr: true},
+		{name: `..\a.txt`, wantErr: true},
+		{name: "docs//a.txt", wantErr: true},
+		{name: "docs/", wantErr: true},
+		{name: ".", wantErr: true},
+		{name: "./", wantErr: true},
+		{name: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CleanName(tt.name)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsafePath) {
+					t.Errorf("CleanName(%q) = %q, %v, want %v", tt.name, got, err, ErrUnsafePath)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("CleanName(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
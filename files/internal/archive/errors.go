@@ -0,0 +1,10 @@
+package archive
+
+import "errors"
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported archive format")
+	ErrSizeMismatch      = errors.New("file size changed while archiving")
+	ErrInvalidArchive    = errors.New("invalid archive")
+	ErrUnsafePath        = errors.New("archive entry path leaves the destination")
+)
@@ -0,0 +1,134 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// Entry is a file, directory or link in an archive.
+type Entry struct {
+	Name string
+	Mode fs.FileMode
+	// Size is what the archive claims, the content may say otherwise.
+	Size    int64
+	Content io.Reader
+}
+
+// Reader walks the entries of an archive. Content is only valid until the
+// next call to Next.
+type Reader interface {
+	// Next returns io.EOF after the last entry.
+	Next() (Entry, error)
+	Close() error
+}
+
+// NewReader opens an archive of size bytes. Zip archives keep their
+// directory at the end, so they need random access.
+func NewReader(format Format, r io.ReaderAt, size int64) (Reader, error) {
+	switch format {
+	case FormatZip:
+		archive, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+		return &zipReader{files: archive.File}, nil
+	case FormatTar:
+		return &tarReader{tar: tar.NewReader(io.NewSectionReader(r, 0, size))}, nil
+	case FormatTarGz:
+		gz, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+		return &tarReader{tar: tar.NewReader(gz), gzip: gz}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type zipReader struct {
+	files []*zip.File
+	open  io.ReadCloser
+}
+
+func (r *zipReader) Next() (Entry, error) {
+	if err := r.Close(); err != nil {
+		return Entry{}, err
+	}
+
+	if len(r.files) == 0 {
+		return Entry{}, io.EOF
+	}
+	file := r.files[0]
+	r.files = r.files[1:]
+
+	entry := Entry{Name: file.Name, Mode: file.Mode(), Size: int64(file.UncompressedSize64)}
+	if !entry.Mode.IsRegular() {
+		return entry, nil
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		return Entry{}, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	r.open = content
+	entry.Content = content
+
+	return entry, nil
+}
+
+func (r *zipReader) Close() error {
+	if r.open == nil {
+		return nil
+	}
+
+	err := r.open.Close()
+	r.open = nil
+	return err
+}
+
+type tarReader struct {
+	tar  *tar.Reader
+	gzip *gzip.Reader
+}
+
+func (r *tarReader) Next() (Entry, error) {
+	header, err := r.tar.Next()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return Entry{}, io.EOF
+		}
+		return Entry{}, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+
+	return Entry{
+		Name:    header.Name,
+		Mode:    header.FileInfo().Mode(),
+		Size:    header.Size,
+		Content: r.tar,
+	}, nil
+}
+
+func (r *tarReader) Close() error {
+	if r.gzip == nil {
+		return nil
+	}
+
+	return r.gzip.Close()
+}
+
+// CleanName returns the entry's name as a relative path that can't leave
+// the folder it's extracted to, tar's leading "./" dropped.
+func CleanName(name string) (string, error) {
+	name = strings.TrimPrefix(name, "./")
+	if strings.Contains(name, `\`) || !fs.ValidPath(name) || name == "." {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,106 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatZip   Format = "zip"
+	FormatTar   Format = "tar"
+	FormatTarGz Format = "tar.gz"
+)
+
+// Writer writes files into an archive as they're added, nothing is kept in
+// memory beyond the compressor's window.
+type Writer interface {
+	// Add writes a file of exactly size bytes read from r.
+	Add(name string, size int64, modified time.Time, r io.Reader) error
+	Close() error
+}
+
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatZip:
+		return &zipWriter{zip: zip.NewWriter(w)}, nil
+	case FormatTar:
+		return &tarWriter{tar: tar.NewWriter(w)}, nil
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarWriter{tar: tar.NewWriter(gz), gzip: gz}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type zipWriter struct {
+	zip *zip.Writer
+}
+
+func (w *zipWriter) Add(name string, size int64, modified time.Time, r io.Reader) error {
+	file, err := w.zip.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	return copyFile(file, r, name, size)
+}
+
+func (w *zipWriter) Close() error {
+	return w.zip.Close()
+}
+
+// tarWriter has no gzip writer for plain tar.
+type tarWriter struct {
+	tar  *tar.Writer
+	gzip *gzip.Writer
+}
+
+func (w *tarWriter) Add(name string, size int64, modified time.Time, r io.Reader) error {
+	err := w.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  modified,
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	return copyFile(w.tar, r, name, size)
+}
+
+func (w *tarWriter) Close() error {
+	if err := w.tar.Close(); err != nil || w.gzip == nil {
+		return err
+	}
+
+	return w.gzip.Close()
+}
+
+// copyFile fails if r doesn't have size bytes, a tar entry would be broken
+// and a zip entry wouldn't match what the listing said.
+func copyFile(w io.Writer, r io.Reader, name string, size int64) error {
+	n, err := io.Copy(w, io.LimitReader(r, size))
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	if n != size {
+		return fmt.Errorf("%w: %s", ErrSizeMismatch, name)
+	}
+
+	return nil
+}
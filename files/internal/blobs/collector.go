@@ -0,0 +1,164 @@
+package blobs
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/avran02/decoplan/files/internal/config"
+)
+
+// Collector periodically removes blobs nobody references anymore, blobs
+// without a reference count left behind by a crash and abandoned staged
+// uploads. Everything younger than the grace period is kept, so in-flight
+// uploads and just released blobs are safe.
+type Collector struct {
+	store *Store
+	conf  config.Dedup
+}
+
+func (c *Collector) Run(ctx context.Context) {
+	slog.Info("starting blob collector")
+
+	if err := c.store.Init(ctx); err != nil {
+		slog.Error("failed to init blob store", "error", err)
+	}
+
+	ticker := time.NewTicker(c.conf.GCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		removed, freed := c.Collect(ctx)
+		if removed > 0 {
+			slog.Info("collected blobs", "removed", removed, "freedBytes", freed)
+		}
+	}
+}
+
+// Collect runs one collection and returns how many blobs it removed and how
+// many bytes that freed.
+func (c *Collector) Collect(ctx context.Context) (int, int64) {
+	before := time.Now().Add(-c.conf.GCGrace)
+	var (
+		removed int
+		freed   int64
+	)
+
+	for object := range c.list(ctx, refsPrefix) {
+		if object.LastModified.After(before) {
+			continue
+		}
+
+		hash := strings.TrimPrefix(object.Key, refsPrefix)
+		size, ok := c.removeUnreferenced(ctx, hash, before)
+		if ok {
+			removed++
+			freed += size
+		}
+	}
+
+	for object := range c.list(ctx, blobsPrefix) {
+		if object.LastModified.After(before) {
+			continue
+		}
+
+		size, ok := c.removeUnreferenced(ctx, path.Base(object.Key), before)
+		if ok {
+			removed++
+			freed += size
+		}
+	}
+
+	for object := range c.list(ctx, stagingPrefix) {
+		if object.LastModified.After(before) {
+			continue
+		}
+
+		if err := c.store.Discard(ctx, object.Key); err != nil {
+			slog.Warn("failed to remove abandoned upload", "key", object.Key, "error", err)
+		}
+	}
+
+	return removed, freed
+}
+
+// removeUnreferenced removes the blob and its count if the count is zero or
+// missing and hasn't changed since before. The count is checked again under
+// the lock, since an upload may have taken a reference since the listing.
+func (c *Collector) removeUnreferenced(ctx context.Context, hash string, before time.Time) (int64, bool) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	ref, _, err := c.store.keys.Stat(ctx, c.store.minio, c.store.bucket, refKey(hash))
+	switch {
+	case err == nil && ref.LastModified.After(before):
+		return 0, false
+	case err != nil && !isNotFound(err):
+		slog.Warn("failed to stat blob reference count", "hash", hash, "error", err)
+		return 0, false
+	}
+
+	count, err := c.store.refCount(ctx, hash)
+	if err != nil {
+		slog.Warn("failed to read blob reference count", "hash", hash, "error", err)
+		return 0, false
+	}
+	if count > 0 {
+		return 0, false
+	}
+
+	info, _, err := c.store.keys.Stat(ctx, c.store.minio, c.store.bucket, blobKey(hash))
+	if err != nil && !isNotFound(err) {
+		slog.Warn("failed to stat blob", "hash", hash, "error", err)
+		return 0, false
+	}
+
+	if err = c.store.minio.RemoveObject(ctx, c.store.bucket, blobKey(hash), minio.RemoveObjectOptions{}); err != nil {
+		slog.Warn("failed to remove blob", "hash", hash, "error", err)
+		return 0, false
+	}
+
+	if err = c.store.minio.RemoveObject(ctx, c.store.bucket, refKey(hash), minio.RemoveObjectOptions{}); err != nil {
+		slog.Warn("failed to remove blob reference count", "hash", hash, "error", err)
+	}
+
+	return info.Size, true
+}
+
+// list streams the objects under prefix, logging and stopping on errors.
+func (c *Collector) list(ctx context.Context, prefix string) <-chan minio.ObjectInfo {
+	objects := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objects)
+		for object := range c.store.minio.ListObjects(ctx, c.store.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if object.Err != nil {
+				slog.Warn("failed to list blob store", "prefix", prefix, "error", object.Err)
+				return
+			}
+			select {
+			case objects <- object:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return objects
+}
+
+func NewCollector(store *Store, conf config.Dedup) *Collector {
+	return &Collector{
+		store: store,
+		conf:  conf,
+	}
+}
@@ -0,0 +1,5 @@
+package blobs
+
+import "errors"
+
+var ErrNegativeRefCount = errors.New("blob reference count would drop below zero")
@@ -0,0 +1,223 @@
+package blobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+
+	"github.com/avran02/decoplan/files/internal/config"
+	"github.com/avran02/decoplan/files/internal/sse"
+)
+
+const (
+	blobsPrefix = "blobs/"
+	// refsPrefix holds the reference count of every blob as a decimal number.
+	refsPrefix = "refs/"
+	// stagingPrefix holds uploads until their hash is known.
+	stagingPrefix = "staging/"
+)
+
+// Store keeps file contents once per SHA-256 in a system bucket. User
+// objects only reference a blob, and the store counts the references so the
+// collector knows which blobs are no longer used.
+//
+// The counts are guarded by an in-process lock, so the store assumes a
+// single files service instance. Everything in the bucket is encrypted like
+// user files, with the key of the blobs bucket.
+type Store struct {
+	minio  *minio.Client
+	bucket string
+	keys   *sse.Keys
+
+	mu sync.Mutex
+}
+
+func (s *Store) Init(ctx context.Context) error {
+	exists, err := s.minio.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to check if blobs bucket exists: %w", err)
+	}
+
+	if !exists {
+		if err = s.minio.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{Region: config.DefaultLocation}); err != nil {
+			return fmt.Errorf("failed to create blobs bucket: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Stage uploads the content under a temporary key. The caller hashes it
+// while it's read and then either commits or discards it.
+func (s *Store) Stage(ctx context.Context, r io.Reader) (string, int64, error) {
+	key := stagingPrefix + uuid.NewString()
+	info, err := s.minio.PutObject(ctx, s.bucket, key, r, -1, minio.PutObjectOptions{
+		ServerSideEncryption: s.keys.ForWrite(s.bucket),
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to stage blob: %w", err)
+	}
+
+	return key, info.Size, nil
+}
+
+func (s *Store) Discard(ctx context.Context, stagingKey string) error {
+	if err := s.minio.RemoveObject(ctx, s.bucket, stagingKey, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to discard staged blob: %w", err)
+	}
+
+	return nil
+}
+
+// Commit turns the staged upload into the blob of hash, unless the blob is
+// already stored, and takes a reference to it. The staged upload is gone
+// afterwards either way.
+func (s *Store) Commit(ctx context.Context, stagingKey, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := blobKey(hash)
+	_, _, err := s.keys.Stat(ctx, s.minio, s.bucket, key)
+	switch {
+	case isNotFound(err):
+		// compose copies server side and, unlike a plain copy, past 5 GiB
+		encryption := s.keys.ForWrite(s.bucket)
+		_, err = s.minio.ComposeObject(ctx,
+			minio.CopyDestOptions{Bucket: s.bucket, Object: key, Encryption: encryption},
+			minio.CopySrcOptions{Bucket: s.bucket, Object: stagingKey, Encryption: sse.CopySource(encryption)},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store blob: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to stat blob: %w", err)
+	default:
+		slog.Debug("blob already stored", "hash", hash)
+	}
+
+	if err = s.adjust(ctx, hash, 1); err != nil {
+		return err
+	}
+
+	if err = s.Discard(ctx, stagingKey); err != nil {
+		// the collector removes it once it's past the grace period
+		slog.Warn("failed to remove staged blob", "key", stagingKey, "error", err)
+	}
+
+	return nil
+}
+
+// Acquire takes another reference to a stored blob.
+func (s *Store) Acquire(ctx context.Context, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.adjust(ctx, hash, 1)
+}
+
+// Release drops a reference to the blob. The blob itself stays until the
+// collector finds it unreferenced.
+func (s *Store) Release(ctx context.Context, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.adjust(ctx, hash, -1)
+}
+
+// Open reads the blob, opts may request a range.
+func (s *Store) Open(ctx context.Context, hash string, opts minio.GetObjectOptions) (*minio.Object, error) {
+	_, encryption, err := s.keys.Stat(ctx, s.minio, s.bucket, blobKey(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat blob: %w", err)
+	}
+
+	opts.ServerSideEncryption = encryption
+	o, err := s.minio.GetObject(ctx, s.bucket, blobKey(hash), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob: %w", err)
+	}
+
+	return o, nil
+}
+
+// adjust changes the reference count of the blob. The caller holds s.mu.
+func (s *Store) adjust(ctx context.Context, hash string, delta int64) error {
+	count, err := s.refCount(ctx, hash)
+	if err != nil {
+		return err
+	}
+
+	count += delta
+	if count < 0 {
+		slog.Error("blob reference count underflow", "hash", hash)
+		return ErrNegativeRefCount
+	}
+
+	data := strconv.FormatInt(count, 10)
+	_, err = s.minio.PutObject(ctx, s.bucket, refKey(hash), strings.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType:          "text/plain",
+		ServerSideEncryption: s.keys.ForWrite(s.bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write blob reference count: %w", err)
+	}
+
+	return nil
+}
+
+// refCount reads the reference count of the blob, a missing count is zero.
+func (s *Store) refCount(ctx context.Context, hash string) (int64, error) {
+	_, encryption, err := s.keys.Stat(ctx, s.minio, s.bucket, refKey(hash))
+	if err != nil {
+		if isNotFound(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to stat blob reference count: %w", err)
+	}
+
+	object, err := s.minio.GetObject(ctx, s.bucket, refKey(hash), minio.GetObjectOptions{ServerSideEncryption: encryption})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read blob reference count: %w", err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read blob reference count: %w", err)
+	}
+
+	count, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid reference count of blob %s: %w", hash, err)
+	}
+
+	return count, nil
+}
+
+func NewStore(minioClient *minio.Client, bucket string, keys *sse.Keys) *Store {
+	return &Store{
+		minio:  minioClient,
+		bucket: bucket,
+		keys:   keys,
+	}
+}
+
+// blobKey spreads the blobs over 256 prefixes by the first byte of the hash.
+func blobKey(hash string) string {
+	return blobsPrefix + hash[:2] + "/" + hash
+}
+
+func refKey(hash string) string {
+	return refsPrefix + hash
+}
+
+func isNotFound(err error) bool {
+	return err != nil && minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
@@ -0,0 +1,170 @@
+package buckets
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/avran02/decoplan/files/internal/config"
+)
+
+// metrics counts lookups answered from the cache, those answered from a
+// cached missing bucket, and those that had to ask MinIO.
+var metrics = expvar.NewMap("bucketCache")
+
+type entry struct {
+	exists  bool
+	expires time.Time
+}
+
+// Cache remembers which buckets exist, so requests don't each ask MinIO
+// first. Buckets are remembered for the TTL, missing ones for the shorter
+// NegativeTTL. Concurrent lookups of the same unknown bucket share one call.
+//
+// At most MaxEntries buckets are remembered. Expired entries are dropped
+// when they're looked up or when the cache is full; if nothing expired, an
+// arbitrary entry makes room.
+type Cache struct {
+	minio *minio.Client
+	conf  config.BucketCache
+
+	mu      sync.RWMutex
+	entries map[string]entry
+	calls   singleflight.Group
+}
+
+// Ensure creates the bucket unless it's known to exist.
+func (c *Cache) Ensure(ctx context.Context, bucket string) error {
+	exists, known := c.lookup(bucket)
+	if known && exists {
+		return nil
+	}
+
+	// the call is shared, so one caller giving up mustn't fail the others
+	_, err, _ := c.calls.Do("ensure/"+bucket, func() (any, error) {
+		if !known {
+			exists, err := c.minio.BucketExists(context.WithoutCancel(ctx), bucket)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check if bucket exists: %w", err)
+			}
+			if exists {
+				c.store(bucket, true)
+				return nil, nil
+			}
+		}
+
+		err := c.minio.MakeBucket(context.WithoutCancel(ctx), bucket, minio.MakeBucketOptions{Region: config.DefaultLocation})
+		if err != nil {
+			// created by someone else in the meantime
+			if code := minio.ToErrorResponse(err).Code; code != "BucketAlreadyOwnedByYou" && code != "BucketAlreadyExists" {
+				return nil, fmt.Errorf("failed to create bucket: %w", err)
+			}
+		}
+		c.store(bucket, true)
+
+		return nil, nil
+	})
+
+	return err
+}
+
+// Exists reports whether the bucket exists, from the cache if it can.
+func (c *Cache) Exists(ctx context.Context, bucket string) (bool, error) {
+	if exists, known := c.lookup(bucket); known {
+		return exists, nil
+	}
+
+	exists, err, _ := c.calls.Do("exists/"+bucket, func() (any, error) {
+		exists, err := c.minio.BucketExists(context.WithoutCancel(ctx), bucket)
+		if err != nil {
+			return false, fmt.Errorf("failed to check if bucket exists: %w", err)
+		}
+		c.store(bucket, exists)
+
+		return exists, nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return exists.(bool), nil
+}
+
+// Forget drops what's known about the bucket, for when it's removed.
+func (c *Cache) Forget(bucket string) {
+	c.mu.Lock()
+	delete(c.entries, bucket)
+	c.mu.Unlock()
+}
+
+func (c *Cache) lookup(bucket string) (exists, known bool) {
+	c.mu.RLock()
+	e, ok := c.entries[bucket]
+	c.mu.RUnlock()
+
+	switch {
+	case !ok:
+		metrics.Add("misses", 1)
+		return false, false
+	case time.Now().After(e.expires):
+		metrics.Add("misses", 1)
+		c.mu.Lock()
+		if e, ok = c.entries[bucket]; ok && time.Now().After(e.expires) {
+			delete(c.entries, bucket)
+		}
+		c.mu.Unlock()
+		return false, false
+	case e.exists:
+		metrics.Add("hits", 1)
+	default:
+		metrics.Add("negativeHits", 1)
+	}
+
+	return e.exists, true
+}
+
+func (c *Cache) store(bucket string, exists bool) {
+	ttl := c.conf.TTL
+	if !exists {
+		ttl = c.conf.NegativeTTL
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	if _, ok := c.entries[bucket]; !ok && len(c.entries) >= c.conf.MaxEntries {
+		c.makeRoom(now)
+	}
+	c.entries[bucket] = entry{exists: exists, expires: now.Add(ttl)}
+	c.mu.Unlock()
+}
+
+// makeRoom drops the expired entries, or one entry if none expired. c.mu
+// must be held.
+func (c *Cache) makeRoom(now time.Time) {
+	for bucket, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, bucket)
+		}
+	}
+
+	for bucket := range c.entries {
+		if len(c.entries) < c.conf.MaxEntries {
+			return
+		}
+		delete(c.entries, bucket)
+		metrics.Add("evictions", 1)
+	}
+}
+
+func NewCache(client *minio.Client, conf config.BucketCache) *Cache {
+	return &Cache{
+		minio:   client,
+		conf:    conf,
+		entries: make(map[string]entry),
+	}
+}
@@ -0,0 +1,241 @@
+package buckets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/avran02/decoplan/files/internal/config"
+)
+
+// fakeMinio answers bucket checks and creations, counting the requests.
+type fakeMinio struct {
+	mu       sync.Mutex
+	buckets  map[string]bool
+	requests map[string]int
+	delay    time.Duration
+}
+
+func (f *fakeMinio) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(f.delay)
+	bucket := strings.Trim(r.URL.Path, "/")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[r.Method+" "+bucket]++
+
+	switch {
+	case r.Method == http.MethodHead && f.buckets[bucket]:
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodPut:
+		f.buckets[bucket] = true
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeMinio) count(method, bucket string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.requests[method+" "+bucket]
+}
+
+func newTestCache(t *testing.T, conf config.BucketCache, buckets ...string) (*Cache, *fakeMinio) {
+	t.Helper()
+
+	fake := &fakeMinio{buckets: make(map[string]bool), requests: make(map[string]int)}
+	for _, bucket := range buckets {
+		fake.buckets[bucket] = true
+	}
+
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client, err := minio.New(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("access", "secret", ""),
+		Region: config.DefaultLocation,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewCache(client, conf), fake
+}
+
+var testConf = config.BucketCache{TTL: time.Minute, NegativeTTL: time.Minute, MaxEntries: 100}
+
+func TestExists(t *testing.T) {
+	tests := []struct {
+		name   string
+		bucket string
+		want   bool
+	}{
+		{name: "existing", bucket: "alice", want: true},
+		{name: "missing", bucket: "bob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newTestCache(t, testConf, "alice")
+
+			for range 3 {
+				got, err := c.Exists(context.Background(), tt.bucket)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("Exists() = %v, want %v", got, tt.want)
+				}
+			}
+
+			if n := fake.count(http.MethodHead, tt.bucket); n != 1 {
+				t.Errorf("asked MinIO %d times, want once", n)
+			}
+		})
+	}
+}
+
+func TestConcurrentLookupsShareOneCall(t *testing.T) {
+	c, fake := newTestCache(t, testConf, "alice")
+	fake.delay = 50 * time.Millisecond
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if exists, err := c.Exists(context.Background(), "alice"); err != nil || !exists {
+				t.Errorf("Exists() = %v, %v", exists, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := fake.count(http.MethodHead, "alice"); n != 1 {
+		t.Errorf("asked MinIO %d times, want once", n)
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		wantPuts int
+	}{
+		{name: "creates missing bucket", wantPuts: 1},
+		{name: "leaves existing bucket", existing: []string{"alice"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newTestCache(t, testConf, tt.existing...)
+
+			for range 3 {
+				if err := c.Ensure(context.Background(), "alice"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if n := fake.count(http.MethodPut, "alice"); n != tt.wantPuts {
+				t.Errorf("created the bucket %d times, want %d", n, tt.wantPuts)
+			}
+			if n := fake.count(http.MethodHead, "alice"); n != 1 {
+				t.Errorf("asked MinIO %d times, want once", n)
+			}
+		})
+	}
+}
+
+func TestEnsureAfterNegativeHit(t *testing.T) {
+	c, fake := newTestCache(t, testConf)
+
+	if exists, err := c.Exists(context.Background(), "alice"); err != nil || exists {
+		t.Fatalf("Exists() = %v, %v", exists, err)
+	}
+	if err := c.Ensure(context.Background(), "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := c.Exists(context.Background(), "alice"); err != nil || !exists {
+		t.Errorf("Exists() after Ensure() = %v, %v", exists, err)
+	}
+
+	if n := fake.count(http.MethodPut, "alice"); n != 1 {
+		t.Errorf("created the bucket %d times, want once", n)
+	}
+}
+
+func TestExpiredEntriesAreDropped(t *testing.T) {
+	c, fake := newTestCache(t, config.BucketCache{TTL: time.Minute, NegativeTTL: 10 * time.Millisecond, MaxEntries: 100})
+
+	if _, err := c.Exists(context.Background(), "ghost"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, known := c.lookup("ghost"); known {
+		t.Error("lookup() trusted an expired entry")
+	}
+	c.mu.RLock()
+	_, kept := c.entries["ghost"]
+	c.mu.RUnlock()
+	if kept {
+		t.Error("the expired entry is still in the cache")
+	}
+
+	if _, err := c.Exists(context.Background(), "ghost"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.count(http.MethodHead, "ghost"); n != 2 {
+		t.Errorf("asked MinIO %d times, want twice", n)
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	c, _ := newTestCache(t, config.BucketCache{TTL: time.Minute, NegativeTTL: time.Minute, MaxEntries: 3})
+
+	for i := range 10 {
+		if _, err := c.Exists(context.Background(), fmt.Sprintf("bucket-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.mu.RLock()
+	n := len(c.entries)
+	_, newest := c.entries["bucket-9"]
+	c.mu.RUnlock()
+
+	if n > 3 {
+		t.Errorf("cache holds %d entries, want at most 3", n)
+	}
+	if !newest {
+		t.Error("the newest entry was evicted")
+	}
+}
+
+func TestForget(t *testing.T) {
+	c, fake := newTestCache(t, testConf, "alice")
+
+	if _, err := c.Exists(context.Background(), "alice"); err != nil {
+		t.Fatal(err)
+	}
+	c.Forget("alice")
+	if _, err := c.Exists(context.Background(), "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := fake.count(http.MethodHead, "alice"); n != 2 {
+		t.Errorf("asked MinIO %d times, want twice", n)
+	}
+}
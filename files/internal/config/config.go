@@ -5,25 +5,162 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Minio  Minio
-	Server Server
+	Minio       Minio
+	Server      Server
+	Webhooks    Webhooks
+	Dedup       Dedup
+	Encryption  Encryption
+	Envelope    Envelope
+	Uploads     Uploads
+	Scan        Scan
+	Thumbnails  Thumbnails
+	Archives    Archives
+	Batch       Batch
+	BucketCache BucketCache
+	Streaming   Streaming
+	Limits      Limits
 }
 
 type Minio struct {
 	Endpoint  string
 	AccessKey string
 	SecretKey string
+	// Secure connects over TLS, which SSE-C requires.
+	Secure bool
 }
 
+// Server serves expvar metrics on MetricsPort, if it's set.
 type Server struct {
-	LogLevel string
-	Port     string
-	Host     string
+	LogLevel    string
+	Port        string
+	Host        string
+	MetricsPort string
+}
+
+type Webhooks struct {
+	Bucket         string
+	PollInterval   time.Duration
+	Timeout        time.Duration
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	LogRetention   time.Duration
+}
+
+// Dedup stores uploads once per content when enabled. Files stored while it
+// was enabled stay readable after disabling it.
+type Dedup struct {
+	Enabled    bool
+	Bucket     string
+	GCInterval time.Duration
+	GCGrace    time.Duration
+}
+
+// Encryption selects server side encryption of user files and the blob
+// store. Master keys are 32 base64 encoded bytes. After rotating the master
+// key, the old one goes to PreviousMasterKeys until ReEncrypt has rewritten
+// every object.
+type Encryption struct {
+	Mode               string
+	MasterKey          string
+	PreviousMasterKeys []string
+	ReEncrypt          bool
+}
+
+// Envelope encrypts files inside the service before they reach MinIO, with a
+// data key per file wrapped by a key from the keyring file. The keyring is
+// also needed to read files stored while it was enabled. Encrypted content
+// never repeats, so it can't be combined with dedup.
+type Envelope struct {
+	Enabled     bool
+	KeyringFile string
+	ChunkSize   int
+}
+
+// Uploads holds the path of the upload policy, see policy.Policy. Without
+// one every type and size is allowed. Uploads of a known size are stored in
+// parts of at least PartSize, Threads at a time. The parts being uploaded
+// across all uploads take at most MemoryBudget bytes.
+type Uploads struct {
+	PolicyFile   string
+	Threads      int
+	PartSize     int
+	MemoryBudget int
+}
+
+// Scan holds uploads in quarantine until the scanner finds them clean. Mode
+// is none, clamd or fake; the fake only detects the EICAR test file.
+type Scan struct {
+	Mode         string
+	ClamdAddress string
+	Timeout      time.Duration
+	Workers      int
+	Interval     time.Duration
+}
+
+// Thumbnails are generated by Workers in the background after uploads, none
+// means only on request. Larger images than the limits get no thumbnails.
+type Thumbnails struct {
+	Workers       int
+	MaxSourceSize int
+	MaxPixels     int
+}
+
+// Archives caps what a single archive may hold, in bytes before compression
+// and in files, for downloads and extracted uploads alike. An uploaded
+// archive may also be no larger than MaxSize itself.
+type Archives struct {
+	MaxSize  int
+	MaxFiles int
+}
+
+// Batch caps the items of a BatchOperation and how many of them run at once.
+type Batch struct {
+	Workers  int
+	MaxItems int
+}
+
+// BucketCache says how long the service trusts what it knows about a
+// bucket existing, and about one missing, and how many buckets it keeps in
+// mind.
+type BucketCache struct {
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	MaxEntries  int
+}
+
+// Streaming sizes the chunks of streamed files. Clients may ask for a chunk
+// size between MinChunkSize and MaxChunkSize, ChunkSize is used otherwise.
+// ReadAhead is how many chunks may queue between storage and the client.
+// Transfers with progress report it every ProgressInterval.
+type Streaming struct {
+	ChunkSize        int
+	MinChunkSize     int
+	MaxChunkSize     int
+	ReadAhead        int
+	ProgressInterval time.Duration
+}
+
+// Limits throttles transfers. Rates are in bytes per second, per user or
+// shared by all users; Streams caps the transfers a user runs at once. Zero
+// lifts a limit. OverridesFile sets other limits for some users, see
+// limits.Overrides. Users over the cap are told to retry after RetryAfter.
+type Limits struct {
+	UploadRate         int
+	DownloadRate       int
+	GlobalUploadRate   int
+	GlobalDownloadRate int
+	Streams            int
+	RetryAfter         time.Duration
+	OverridesFile      string
 }
 
 func New() *Config {
@@ -39,14 +176,195 @@ func New() *Config {
 			Endpoint:  os.Getenv("MINIO_ENDPOINT"),
 			AccessKey: os.Getenv("MINIO_ACCESS_KEY"),
 			SecretKey: os.Getenv("MINIO_SECRET_KEY"),
+			Secure:    getBool("MINIO_USE_SSL", false),
 		},
 		Server: Server{
-			LogLevel: os.Getenv("SERVER_LOG_LEVEL"),
-			Port:     os.Getenv("SERVER_PORT"),
-			Host:     os.Getenv("SERVER_HOST"),
+			LogLevel:    os.Getenv("SERVER_LOG_LEVEL"),
+			Port:        os.Getenv("SERVER_PORT"),
+			Host:        os.Getenv("SERVER_HOST"),
+			MetricsPort: os.Getenv("METRICS_PORT"),
+		},
+		Webhooks: Webhooks{
+			Bucket:         getString("WEBHOOK_BUCKET", defaultWebhookBucket),
+			PollInterval:   getDuration("WEBHOOK_POLL_INTERVAL", defaultWebhookPollInterval),
+			Timeout:        getDuration("WEBHOOK_TIMEOUT", defaultWebhookTimeout),
+			MaxAttempts:    getInt("WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts),
+			RetryBaseDelay: getDuration("WEBHOOK_RETRY_BASE_DELAY", defaultWebhookRetryBaseDelay),
+			RetryMaxDelay:  getDuration("WEBHOOK_RETRY_MAX_DELAY", defaultWebhookRetryMaxDelay),
+			LogRetention:   getDuration("WEBHOOK_LOG_RETENTION", defaultWebhookLogRetention),
+		},
+		Dedup: Dedup{
+			Enabled:    getBool("DEDUP_ENABLED", false),
+			Bucket:     getString("DEDUP_BUCKET", defaultDedupBucket),
+			GCInterval: getDuration("DEDUP_GC_INTERVAL", defaultDedupGCInterval),
+			GCGrace:    getDuration("DEDUP_GC_GRACE", defaultDedupGCGrace),
+		},
+		Encryption: Encryption{
+			Mode:               getString("SSE_MODE", defaultSSEMode),
+			MasterKey:          os.Getenv("SSE_MASTER_KEY"),
+			PreviousMasterKeys: getList("SSE_PREVIOUS_MASTER_KEYS"),
+			ReEncrypt:          getBool("SSE_REENCRYPT", false),
+		},
+		Envelope: Envelope{
+			Enabled:     getBool("ENVELOPE_ENABLED", false),
+			KeyringFile: os.Getenv("ENVELOPE_KEYRING_FILE"),
+			ChunkSize:   getInt("ENVELOPE_CHUNK_SIZE", defaultEnvelopeChunkSize),
+		},
+		Uploads: Uploads{
+			PolicyFile:   os.Getenv("UPLOAD_POLICY_FILE"),
+			Threads:      getInt("UPLOAD_THREADS", defaultUploadThreads),
+			PartSize:     getInt("UPLOAD_PART_SIZE", defaultUploadPartSize),
+			MemoryBudget: getInt("UPLOAD_MEMORY_BUDGET", defaultUploadMemoryBudget),
+		},
+		Scan: Scan{
+			Mode:         getString("SCAN_MODE", defaultScanMode),
+			ClamdAddress: getString("SCAN_CLAMD_ADDRESS", defaultScanClamdAddress),
+			Timeout:      getDuration("SCAN_TIMEOUT", defaultScanTimeout),
+			Workers:      getInt("SCAN_WORKERS", defaultScanWorkers),
+			Interval:     getDuration("SCAN_INTERVAL", defaultScanInterval),
+		},
+		Thumbnails: Thumbnails{
+			Workers:       getInt("THUMBNAIL_WORKERS", defaultThumbnailWorkers),
+			MaxSourceSize: getInt("THUMBNAIL_MAX_SOURCE_SIZE", defaultThumbnailMaxSourceSize),
+			MaxPixels:     getInt("THUMBNAIL_MAX_PIXELS", defaultThumbnailMaxPixels),
+		},
+		Archives: Archives{
+			MaxSize:  getInt("ARCHIVE_MAX_SIZE", defaultArchiveMaxSize),
+			MaxFiles: getInt("ARCHIVE_MAX_FILES", defaultArchiveMaxFiles),
 		},
+		Batch: Batch{
+			Workers:  getInt("BATCH_WORKERS", defaultBatchWorkers),
+			MaxItems: getInt("BATCH_MAX_ITEMS", defaultBatchMaxItems),
+		},
+		BucketCache: BucketCache{
+			TTL:         getDuration("BUCKET_CACHE_TTL", defaultBucketCacheTTL),
+			NegativeTTL: getDuration("BUCKET_CACHE_NEGATIVE_TTL", defaultBucketCacheNegativeTTL),
+			MaxEntries:  getInt("BUCKET_CACHE_MAX_ENTRIES", defaultBucketCacheMaxEntries),
+		},
+		Streaming: Streaming{
+			ChunkSize:        getInt("STREAM_CHUNK_SIZE", defaultStreamChunkSize),
+			MinChunkSize:     getInt("STREAM_MIN_CHUNK_SIZE", defaultStreamMinChunkSize),
+			MaxChunkSize:     getInt("STREAM_MAX_CHUNK_SIZE", defaultStreamMaxChunkSize),
+			ReadAhead:        getInt("STREAM_READ_AHEAD", defaultStreamReadAhead),
+			ProgressInterval: getDuration("STREAM_PROGRESS_INTERVAL", defaultStreamProgressInterval),
+		},
+		Limits: Limits{
+			UploadRate:         getInt("LIMIT_UPLOAD_RATE", 0),
+			DownloadRate:       getInt("LIMIT_DOWNLOAD_RATE", 0),
+			GlobalUploadRate:   getInt("LIMIT_GLOBAL_UPLOAD_RATE", 0),
+			GlobalDownloadRate: getInt("LIMIT_GLOBAL_DOWNLOAD_RATE", 0),
+			Streams:            getInt("LIMIT_STREAMS", defaultLimitStreams),
+			RetryAfter:         getDuration("LIMIT_RETRY_AFTER", defaultLimitRetryAfter),
+			OverridesFile:      os.Getenv("LIMIT_OVERRIDES_FILE"),
+		},
+	}
+	if config.Envelope.Enabled && config.Dedup.Enabled {
+		log.Fatal("DEDUP_ENABLED and ENVELOPE_ENABLED can't both be set")
 	}
-	slog.Debug(fmt.Sprintf("config: %+v", config))
+
+	if config.Envelope.Enabled && config.Envelope.KeyringFile == "" {
+		log.Fatal("ENVELOPE_ENABLED needs ENVELOPE_KEYRING_FILE")
+	}
+
+	if config.Envelope.ChunkSize <= 0 {
+		log.Fatalf("invalid ENVELOPE_CHUNK_SIZE: %d", config.Envelope.ChunkSize)
+	}
+
+	if s := config.Streaming; s.MinChunkSize <= 0 || s.ChunkSize < s.MinChunkSize || s.ChunkSize > s.MaxChunkSize {
+		log.Fatalf("STREAM_CHUNK_SIZE %d must be between STREAM_MIN_CHUNK_SIZE %d and STREAM_MAX_CHUNK_SIZE %d", s.ChunkSize, s.MinChunkSize, s.MaxChunkSize)
+	}
+
+	if u := config.Uploads; u.Threads <= 0 || u.PartSize < minUploadPartSize || u.MemoryBudget < u.PartSize {
+		log.Fatalf("UPLOAD_THREADS %d must be positive, UPLOAD_PART_SIZE %d at least %d and UPLOAD_MEMORY_BUDGET %d at least the part size",
+			u.Threads, u.PartSize, minUploadPartSize, u.MemoryBudget)
+	}
+
+	if config.Streaming.ReadAhead <= 0 {
+		log.Fatalf("invalid STREAM_READ_AHEAD: %d", config.Streaming.ReadAhead)
+	}
+
+	if config.Streaming.ProgressInterval <= 0 {
+		log.Fatalf("invalid STREAM_PROGRESS_INTERVAL: %s", config.Streaming.ProgressInterval)
+	}
+
+	if config.BucketCache.MaxEntries <= 0 {
+		log.Fatalf("invalid BUCKET_CACHE_MAX_ENTRIES: %d", config.BucketCache.MaxEntries)
+	}
+
+	slog.Debug(fmt.Sprintf("config: %+v", config.redacted()))
 
 	return config
 }
+
+// redacted is the config without secrets, for logging.
+func (c Config) redacted() Config {
+	c.Minio.SecretKey = "***"
+	if c.Encryption.MasterKey != "" {
+		c.Encryption.MasterKey = "***"
+	}
+	c.Encryption.PreviousMasterKeys = nil
+
+	return c
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %s", key, err)
+	}
+
+	return d
+}
+
+func getInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %s", key, err)
+	}
+
+	return i
+}
+
+func getBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %s", key, err)
+	}
+
+	return b
+}
+
+// getList splits a comma separated value, ignoring empty items.
+func getList(key string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func getString(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return fallback
+}
@@ -1,7 +1,81 @@
 package config
 
+import "time"
+
 const (
 	DefaultLocation  = "us-east-1"
 	DefaultFilesPath = "tmp/files"
-	StreamChunkSize  = 1024 * 1024 // 1 MB
+	EventsBufferSize = 256
+)
+
+const (
+	defaultWebhookBucket         = "decoplan-webhooks"
+	defaultWebhookPollInterval   = 5 * time.Second
+	defaultWebhookTimeout        = 10 * time.Second
+	defaultWebhookMaxAttempts    = 10
+	defaultWebhookRetryBaseDelay = 10 * time.Second
+	defaultWebhookRetryMaxDelay  = time.Hour
+	defaultWebhookLogRetention   = 7 * 24 * time.Hour
+)
+
+const (
+	defaultDedupBucket     = "decoplan-blobs"
+	defaultDedupGCInterval = time.Hour
+	defaultDedupGCGrace    = 24 * time.Hour
+)
+
+const defaultSSEMode = "none"
+
+const defaultEnvelopeChunkSize = 64 * 1024
+
+const (
+	defaultScanMode         = "none"
+	defaultScanClamdAddress = "clamd:3310"
+	defaultScanTimeout      = 5 * time.Minute
+	defaultScanWorkers      = 4
+	defaultScanInterval     = time.Minute
+)
+
+const (
+	defaultThumbnailWorkers       = 2
+	defaultThumbnailMaxSourceSize = 32 << 20
+	defaultThumbnailMaxPixels     = 40_000_000
+)
+
+const (
+	defaultArchiveMaxSize  = 4 << 30
+	defaultArchiveMaxFiles = 10000
+)
+
+const (
+	defaultBatchWorkers  = 8
+	defaultBatchMaxItems = 1000
+)
+
+const (
+	defaultBucketCacheTTL         = 5 * time.Minute
+	defaultBucketCacheNegativeTTL = 30 * time.Second
+	defaultBucketCacheMaxEntries  = 10000
+)
+
+const (
+	defaultStreamChunkSize    = 1024 * 1024 // 1 MB
+	defaultStreamMinChunkSize = 16 * 1024
+	// stays under the 4 MB gRPC clients accept by default
+	defaultStreamMaxChunkSize     = 3 * 1024 * 1024
+	defaultStreamReadAhead        = 4
+	defaultStreamProgressInterval = time.Second
+)
+
+const (
+	defaultUploadThreads      = 4
+	defaultUploadPartSize     = 16 * 1024 * 1024
+	defaultUploadMemoryBudget = 256 * 1024 * 1024
+	// the smallest part S3 accepts
+	minUploadPartSize = 5 * 1024 * 1024
+)
+
+const (
+	defaultLimitStreams    = 8
+	defaultLimitRetryAfter = 5 * time.Second
 )
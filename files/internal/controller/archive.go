@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/files/internal/archive"
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/pb"
+)
+
+// archiveFormatFromPb returns "" for unknown formats, which the service
+// refuses.
+func archiveFormatFromPb(format pb.ArchiveFormat) archive.Format {
+	switch format {
+	case pb.ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED, pb.ArchiveFormat_ARCHIVE_FORMAT_ZIP:
+		return archive.FormatZip
+	case pb.ArchiveFormat_ARCHIVE_FORMAT_TAR_GZ:
+		return archive.FormatTarGz
+	case pb.ArchiveFormat_ARCHIVE_FORMAT_TAR:
+		return archive.FormatTar
+	default:
+		return ""
+	}
+}
+
+func extractedEntriesToPb(entries []dto.ExtractedEntry) []*pb.ExtractedEntry {
+	res := make([]*pb.ExtractedEntry, 0, len(entries))
+	for _, entry := range entries {
+		extracted := &pb.ExtractedEntry{
+			Path:   entry.Path,
+			Size:   entry.Size,
+			Digest: digestToPb(entry.Digest),
+		}
+		if entry.Err != nil {
+			extracted.Error = entry.Err.Error()
+		}
+		res = append(res, extracted)
+	}
+
+	return res
+}
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/pb"
+)
+
+func batchKindFromPb(kind pb.BatchOperationKind) dto.BatchKind {
+	switch kind {
+	case pb.BatchOperationKind_BATCH_OPERATION_KIND_DELETE:
+		return dto.BatchDelete
+	case pb.BatchOperationKind_BATCH_OPERATION_KIND_COPY:
+		return dto.BatchCopy
+	case pb.BatchOperationKind_BATCH_OPERATION_KIND_MOVE:
+		return dto.BatchMove
+	default:
+		return ""
+	}
+}
+
+func batchResultToPb(result dto.BatchResult) *pb.BatchOperationResult {
+	res := &pb.BatchOperationResult{
+		Index:   int32(result.Index),
+		Success: result.Err == nil && !result.Skipped,
+		Skipped: result.Skipped,
+	}
+	if result.Err != nil {
+		res.Error = result.Err.Error()
+	}
+
+	return res
+}
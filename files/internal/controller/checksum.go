@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/pb"
+)
+
+// checksumFromPb returns nil if the client didn't ask for verification.
+func checksumFromPb(algorithm pb.ChecksumAlgorithm, value string) (*dto.Checksum, error) {
+	switch algorithm {
+	case pb.ChecksumAlgorithm_CHECKSUM_ALGORITHM_UNSPECIFIED:
+		if value != "" {
+			return nil, dto.ErrUnsupportedAlgorithm
+		}
+		return nil, nil //nolint:nilnil
+	case pb.ChecksumAlgorithm_CHECKSUM_ALGORITHM_SHA256:
+		return &dto.Checksum{Algorithm: dto.ChecksumSHA256, Value: value}, nil
+	case pb.ChecksumAlgorithm_CHECKSUM_ALGORITHM_CRC32C:
+		return &dto.Checksum{Algorithm: dto.ChecksumCRC32C, Value: value}, nil
+	default:
+		return nil, dto.ErrUnsupportedAlgorithm
+	}
+}
+
+func digestToPb(d dto.Digest) *pb.Digest {
+	return &pb.Digest{
+		Sha256: d.SHA256,
+		Crc32C: d.CRC32C,
+	}
+}
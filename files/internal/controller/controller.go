@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -9,26 +10,54 @@ import (
 
 	"github.com/avran02/decoplan/files/internal/config"
 	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/internal/events"
+	"github.com/avran02/decoplan/files/internal/limits"
 	"github.com/avran02/decoplan/files/internal/service"
+	"github.com/avran02/decoplan/files/internal/stream"
 	"github.com/avran02/decoplan/files/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type FileServerController interface {
 	ListFiles(ctx context.Context, req *pb.ListFilesRequest) (*pb.ListFilesResponse, error)
 	DownloadFile(req *pb.DownloadFileRequest, stream pb.FileService_DownloadFileServer) error
+	DownloadArchive(req *pb.DownloadArchiveRequest, stream pb.FileService_DownloadArchiveServer) error
 	UploadFile(stream pb.FileService_UploadFileServer) error
 	RemoveFile(ctx context.Context, req *pb.RemoveFileRequest) (*pb.RemoveFileResponse, error)
+	StatFile(ctx context.Context, req *pb.StatFileRequest) (*pb.StatFileResponse, error)
 	RegisterUser(ctx context.Context, req *pb.RegisterUserRequest) (*pb.RegisterUserResponse, error)
+	UnregisterUser(ctx context.Context, req *pb.UnregisterUserRequest) (*pb.UnregisterUserResponse, error)
+	MoveFile(ctx context.Context, req *pb.MoveFileRequest) (*pb.MoveFileResponse, error)
+	BatchOperation(req *pb.BatchOperationRequest, stream pb.FileService_BatchOperationServer) error
+	DownloadFileWithProgress(req *pb.DownloadFileRequest, stream pb.FileService_DownloadFileWithProgressServer) error
+	UploadFileWithProgress(stream pb.FileService_UploadFileWithProgressServer) error
+	GetUsage(ctx context.Context, req *pb.GetUsageRequest) (*pb.GetUsageResponse, error)
+	GetUploadProgress(ctx context.Context, req *pb.GetUploadProgressRequest) (*pb.GetUploadProgressResponse, error)
+	GetThumbnail(ctx context.Context, req *pb.GetThumbnailRequest) (*pb.GetThumbnailResponse, error)
+	WatchFiles(req *pb.WatchFilesRequest, stream pb.FileService_WatchFilesServer) error
+	CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.CreateWebhookResponse, error)
+	ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error)
+	DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error)
+	TestWebhook(ctx context.Context, req *pb.TestWebhookRequest) (*pb.TestWebhookResponse, error)
+	ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error)
+}
+
+// uploadStream is what UploadFile and UploadFileWithProgress receive from.
+type uploadStream interface {
+	Recv() (*pb.UploadFileRequest, error)
+	Context() context.Context
 }
 
 type fileServerController struct {
-	Service service.FilesService
+	Service    service.FilesService
+	streamConf config.Streaming
+	limits     *limits.Limiter
 }
 
 func (c fileServerController) ListFiles(ctx context.Context, req *pb.ListFilesRequest) (*pb.ListFilesResponse, error) {
 	files, err := c.Service.ListFiles(ctx, req.UserID, req.FilePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list files: %w", err)
+		return nil, toStatus(fmt.Errorf("failed to list files: %w", err))
 	}
 
 	return &pb.ListFilesResponse{Files: files}, nil
@@ -38,52 +67,115 @@ func (c fileServerController) DownloadFile(req *pb.DownloadFileRequest, stream p
 	ctx := stream.Context()
 	streamErrChan := make(chan error, 1)
 
-	file, err := c.Service.DownloadFile(ctx, req.UserID, req.FilePath)
+	transfer, err := c.limits.Begin(req.UserID)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer transfer.End()
+
+	file, stat, err := c.Service.DownloadFile(ctx, req.UserID, req.FilePath, req.Offset, req.Length)
 	if err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
+		return toStatus(fmt.Errorf("failed to download file: %w", err))
 	}
 
-	go c.asyncSendFile(stream, file, streamErrChan)
+	chunkSize := c.chunkSize(req.ChunkSize)
+	go c.asyncSendFile(ctx, file, chunkSize, transfer,
+		func(content []byte) error {
+			return stream.Send(&pb.DownloadFileResponse{Content: content, ChunkSize: int32(chunkSize)})
+		},
+		func() error {
+			return stream.Send(&pb.DownloadFileResponse{Success: true, Digest: digestToPb(stat.Digest)})
+		},
+		streamErrChan,
+	)
 
 	if err = <-streamErrChan; err != nil {
 		slog.Error(err.Error())
-		return fmt.Errorf("failed to download file: %w", err)
+		return toStatus(fmt.Errorf("failed to download file: %w", err))
 	}
 
 	return nil
 }
 
-func (c fileServerController) UploadFile(stream pb.FileService_UploadFileServer) error {
-	slog.Info("Upload file")
-
-	streamErrChan := make(chan error, 1)
+func (c fileServerController) DownloadArchive(req *pb.DownloadArchiveRequest, stream pb.FileService_DownloadArchiveServer) error {
 	ctx := stream.Context()
+	streamErrChan := make(chan error, 1)
 
-	r, err := stream.Recv()
+	transfer, err := c.limits.Begin(req.UserID)
 	if err != nil {
-		slog.Error(err.Error())
-		return fmt.Errorf("failed to receive upload file request: %w", err)
+		return toStatus(err)
+	}
+	defer transfer.End()
+
+	file, err := c.Service.DownloadArchive(ctx, dto.ArchiveRequest{
+		UserID: req.UserID,
+		Prefix: req.Prefix,
+		Paths:  req.Paths,
+		Format: archiveFormatFromPb(req.Format),
+	})
+	if err != nil {
+		return toStatus(fmt.Errorf("failed to download archive: %w", err))
 	}
 
-	if len(r.Content) != 0 {
-		slog.Warn("Content should be empty")
-		return ErrNotEmptyFirstChunk
+	chunkSize := c.chunkSize(req.ChunkSize)
+	go c.asyncSendFile(ctx, file, chunkSize, transfer,
+		func(content []byte) error {
+			return stream.Send(&pb.DownloadArchiveResponse{Content: content, ChunkSize: int32(chunkSize)})
+		},
+		func() error {
+			return stream.Send(&pb.DownloadArchiveResponse{Success: true})
+		},
+		streamErrChan,
+	)
+
+	if err = <-streamErrChan; err != nil {
+		slog.Error(err.Error())
+		return toStatus(fmt.Errorf("failed to download archive: %w", err))
 	}
 
-	requestDTO, err := dto.NewUploadFileStreamRequest(r.UserID, r.FilePath)
+	return nil
+}
+
+func (c fileServerController) UploadFile(stream pb.FileService_UploadFileServer) error {
+	slog.Info("Upload file")
+
+	streamErrChan := make(chan error, 1)
+	ctx := stream.Context()
+
+	r, requestDTO, err := c.receiveUploadHeader(stream)
 	if err != nil {
-		slog.Error(err.Error())
-		return fmt.Errorf("failed to get upload file request: %w", err)
+		return toStatus(err)
 	}
 	defer requestDTO.CloseReader()
 
-	go c.asyncGetFileFromGrpcStream(stream, requestDTO, streamErrChan)
-
-	if err = c.Service.UploadFile(ctx, requestDTO); err != nil {
+	transfer, err := c.limits.Begin(r.UserID)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer transfer.End()
+
+	go c.asyncGetFileFromGrpcStream(stream, requestDTO, transfer, streamErrChan)
+
+	res := &pb.UploadFileResponse{Success: true}
+	var digest dto.Digest
+	if r.Extract {
+		var entries []dto.ExtractedEntry
+		digest, entries, err = c.Service.ExtractArchive(ctx, requestDTO, archiveFormatFromPb(r.ArchiveFormat))
+		res.Entries = extractedEntriesToPb(entries)
+		for _, entry := range entries {
+			res.Success = res.Success && entry.Err == nil
+		}
+	} else {
+		var stat dto.FileStat
+		stat, err = c.Service.UploadFile(ctx, requestDTO)
+		digest = stat.Digest
+	}
+	if err != nil {
 		err = fmt.Errorf("failed to upload file: %w", err)
 		slog.Error(err.Error())
-		return err
+		return toStatus(err)
 	}
+	res.Digest = digestToPb(digest)
 
 	if err = <-streamErrChan; err != nil {
 		err = fmt.Errorf("failed while uploading file from stream: %w", err)
@@ -91,7 +183,7 @@ func (c fileServerController) UploadFile(stream pb.FileService_UploadFileServer)
 		return err
 	}
 
-	if err = stream.SendAndClose(&pb.UploadFileResponse{Success: true}); err != nil {
+	if err = stream.SendAndClose(res); err != nil {
 		err = fmt.Errorf("failed to send upload file response: %w", err)
 		slog.Error(err.Error())
 		return err
@@ -105,7 +197,7 @@ func (c fileServerController) RemoveFile(ctx context.Context, req *pb.RemoveFile
 	if err != nil {
 		return &pb.RemoveFileResponse{
 			Success: false,
-		}, err
+		}, toStatus(err)
 	}
 
 	return &pb.RemoveFileResponse{
@@ -113,6 +205,24 @@ func (c fileServerController) RemoveFile(ctx context.Context, req *pb.RemoveFile
 	}, nil
 }
 
+func (c fileServerController) StatFile(ctx context.Context, req *pb.StatFileRequest) (*pb.StatFileResponse, error) {
+	stat, err := c.Service.StatFile(ctx, req.UserID, req.FilePath)
+	if err != nil {
+		return nil, toStatus(fmt.Errorf("failed to stat file: %w", err))
+	}
+
+	return &pb.StatFileResponse{
+		File: &pb.FileInfo{
+			Name:         stat.Name,
+			Size:         stat.Size,
+			LastModified: timestamppb.New(stat.LastModified),
+		},
+		Etag:        stat.ETag,
+		ContentType: stat.ContentType,
+		Digest:      digestToPb(stat.Digest),
+	}, nil
+}
+
 func (c fileServerController) RegisterUser(ctx context.Context, req *pb.RegisterUserRequest) (*pb.RegisterUserResponse, error) {
 	err := c.Service.RegisterUser(ctx, req.UserID)
 	if err != nil {
@@ -126,40 +236,278 @@ func (c fileServerController) RegisterUser(ctx context.Context, req *pb.Register
 	}, nil
 }
 
-func (c fileServerController) asyncSendFile(stream pb.FileService_DownloadFileServer, file io.ReadCloser, streamErrChan chan error) {
-	defer close(streamErrChan)
-	defer file.Close()
-	buf := make([]byte, config.StreamChunkSize)
+func (c fileServerController) UnregisterUser(ctx context.Context, req *pb.UnregisterUserRequest) (*pb.UnregisterUserResponse, error) {
+	err := c.Service.UnregisterUser(ctx, req.UserID)
+	if err != nil {
+		return &pb.UnregisterUserResponse{
+			Success: false,
+		}, err
+	}
+
+	return &pb.UnregisterUserResponse{
+		Success: true,
+	}, nil
+}
+
+func (c fileServerController) MoveFile(ctx context.Context, req *pb.MoveFileRequest) (*pb.MoveFileResponse, error) {
+	err := c.Service.MoveFile(ctx, req.UserID, req.FilePath, req.NewFilePath)
+	if err != nil {
+		return &pb.MoveFileResponse{
+			Success: false,
+		}, toStatus(err)
+	}
+
+	return &pb.MoveFileResponse{
+		Success: true,
+	}, nil
+}
+
+func (c fileServerController) GetThumbnail(ctx context.Context, req *pb.GetThumbnailRequest) (*pb.GetThumbnailResponse, error) {
+	thumb, err := c.Service.GetThumbnail(ctx, req.UserID, req.FilePath, thumbnailSizeFromPb(req.Size))
+	if err != nil {
+		return nil, toStatus(fmt.Errorf("failed to get thumbnail: %w", err))
+	}
+
+	return &pb.GetThumbnailResponse{
+		Content:     thumb.Content,
+		ContentType: thumb.ContentType,
+	}, nil
+}
+
+func (c fileServerController) BatchOperation(req *pb.BatchOperationRequest, stream pb.FileService_BatchOperationServer) error {
+	items := make([]dto.BatchItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, dto.BatchItem{
+			Kind:        batchKindFromPb(item.Kind),
+			FilePath:    item.FilePath,
+			NewFilePath: item.NewFilePath,
+		})
+	}
+
+	err := c.Service.Batch(stream.Context(), dto.BatchRequest{
+		UserID:      req.UserID,
+		Items:       items,
+		DryRun:      req.DryRun,
+		StopOnError: req.StopOnError,
+	}, func(result dto.BatchResult) error {
+		return stream.Send(batchResultToPb(result))
+	})
+	if err != nil {
+		return toStatus(fmt.Errorf("failed to run batch: %w", err))
+	}
+
+	return nil
+}
+
+func (c fileServerController) GetUsage(ctx context.Context, req *pb.GetUsageRequest) (*pb.GetUsageResponse, error) {
+	usage, err := c.Service.GetUsage(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	return &pb.GetUsageResponse{
+		Files:         usage.Files,
+		LogicalBytes:  usage.LogicalBytes,
+		PhysicalBytes: usage.PhysicalBytes,
+	}, nil
+}
+
+func (c fileServerController) GetUploadProgress(ctx context.Context, req *pb.GetUploadProgressRequest) (*pb.GetUploadProgressResponse, error) {
+	progress, err := c.Service.GetUploadProgress(ctx, req.UserID, req.FilePath)
+	if err != nil {
+		return nil, toStatus(fmt.Errorf("failed to get upload progress: %w", err))
+	}
+
+	return &pb.GetUploadProgressResponse{
+		Size:          progress.Size,
+		ReceivedBytes: progress.ReceivedBytes,
+		UploadedBytes: progress.UploadedBytes,
+		Parts:         progress.Parts,
+		PartsDone:     progress.PartsDone,
+	}, nil
+}
+
+func (c fileServerController) WatchFiles(req *pb.WatchFilesRequest, stream pb.FileService_WatchFilesServer) error {
+	sub := c.Service.WatchFiles(events.Filter{
+		Bucket: req.UserID,
+		Prefix: req.Prefix,
+	})
+	defer c.Service.StopWatching(sub)
 
 	for {
-		n, err := file.Read(buf)
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				if n == 0 {
-					break
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Lost() {
+					return ErrWatcherTooSlow
 				}
-			} else {
-				err = fmt.Errorf("failed to read file: %w", err)
+				return nil
+			}
+
+			if err := stream.Send(fileEventToPb(event)); err != nil {
+				err = fmt.Errorf("failed to send file event: %w", err)
 				slog.Error(err.Error())
-				streamErrChan <- err
+				return err
 			}
 		}
+	}
+}
 
-		if err = stream.Send(&pb.DownloadFileResponse{
-			Content: buf[:n],
-		}); err != nil {
-			streamErrChan <- fmt.Errorf("failed to send download file response: %w", err)
-		}
+func (c fileServerController) CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.CreateWebhookResponse, error) {
+	kinds := make([]events.Kind, 0, len(req.Kinds))
+	for _, kind := range req.Kinds {
+		kinds = append(kinds, fileEventKindFromPb(kind))
+	}
+
+	w, err := c.Service.CreateWebhook(ctx, req.UserID, req.Url, req.Prefix, kinds)
+	if err != nil {
+		return nil, toStatus(fmt.Errorf("failed to create webhook: %w", err))
+	}
+
+	return &pb.CreateWebhookResponse{
+		Webhook: webhookToPb(w),
+		Secret:  w.Secret,
+	}, nil
+}
+
+func (c fileServerController) ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	webhooks, err := c.Service.ListWebhooks(ctx, req.UserID)
+	if err != nil {
+		return nil, toStatus(fmt.Errorf("failed to list webhooks: %w", err))
+	}
+
+	res := make([]*pb.Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		res = append(res, webhookToPb(w))
+	}
+
+	return &pb.ListWebhooksResponse{Webhooks: res}, nil
+}
+
+func (c fileServerController) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	err := c.Service.DeleteWebhook(ctx, req.UserID, req.WebhookID)
+	if err != nil {
+		return &pb.DeleteWebhookResponse{
+			Success: false,
+		}, toStatus(err)
 	}
 
-	if err := stream.Send(&pb.DownloadFileResponse{
+	return &pb.DeleteWebhookResponse{
 		Success: true,
-	}); err != nil {
-		streamErrChan <- fmt.Errorf("failed to send download file response: %w", err)
+	}, nil
+}
+
+func (c fileServerController) TestWebhook(ctx context.Context, req *pb.TestWebhookRequest) (*pb.TestWebhookResponse, error) {
+	result, err := c.Service.TestWebhook(ctx, req.UserID, req.WebhookID)
+	if err != nil {
+		return nil, toStatus(fmt.Errorf("failed to test webhook: %w", err))
+	}
+
+	res := &pb.TestWebhookResponse{
+		Delivered:  result.Err == nil,
+		StatusCode: result.StatusCode,
+	}
+	if result.Err != nil {
+		res.Error = result.Err.Error()
+	}
+
+	return res, nil
+}
+
+func (c fileServerController) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	page, err := c.Service.ListWebhookDeliveries(ctx, req.UserID, req.WebhookID, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, toStatus(fmt.Errorf("failed to list webhook deliveries: %w", err))
+	}
+
+	deliveries := make([]*pb.WebhookDelivery, 0, len(page.Deliveries))
+	for _, d := range page.Deliveries {
+		deliveries = append(deliveries, webhookDeliveryToPb(d))
 	}
+
+	return &pb.ListWebhookDeliveriesResponse{
+		Deliveries:    deliveries,
+		NextPageToken: page.NextPageToken,
+	}, nil
 }
 
-func (c fileServerController) asyncGetFileFromGrpcStream(stream pb.FileService_UploadFileServer, requestDTO *dto.UploadFileStreamRequest, streamErrChan chan error) {
+// asyncSendFile sends the file in chunks of chunkSize and then the final
+// message. Chunks are read ahead of the client into pooled buffers. gRPC
+// doesn't promise to be done with a message when Send returns, stats
+// handlers and interceptors may keep it, so each message gets its own copy
+// of the chunk and the pooled buffer is reused. Chunks wait for the
+// transfer's bandwidth. The first error ends it and goes to streamErrChan.
+func (c fileServerController) asyncSendFile(
+	ctx context.Context, file io.ReadCloser, chunkSize int, transfer *limits.Transfer,
+	sendChunk func(content []byte) error, sendLast func() error, streamErrChan chan error,
+) {
+	defer close(streamErrChan)
+	chunks := stream.NewReadAhead(file, chunkSize, c.streamConf.ReadAhead)
+	defer chunks.Close()
+
+	for {
+		chunk, err := chunks.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to read file: %w", err)
+			slog.Error(err.Error())
+			streamErrChan <- err
+			return
+		}
+
+		if err = transfer.Download(ctx, len(chunk)); err != nil {
+			streamErrChan <- fmt.Errorf("failed to wait for download bandwidth: %w", err)
+			return
+		}
+
+		if err = sendChunk(bytes.Clone(chunk)); err != nil {
+			streamErrChan <- fmt.Errorf("failed to send download response: %w", err)
+			return
+		}
+	}
+
+	if err := sendLast(); err != nil {
+		streamErrChan <- fmt.Errorf("failed to send download response: %w", err)
+	}
+}
+
+// receiveUploadHeader reads the first message of an upload, which has the
+// file but no content yet.
+func (c fileServerController) receiveUploadHeader(stream uploadStream) (*pb.UploadFileRequest, *dto.UploadFileStreamRequest, error) {
+	r, err := stream.Recv()
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, nil, fmt.Errorf("failed to receive upload file request: %w", err)
+	}
+
+	if len(r.Content) != 0 {
+		slog.Warn("Content should be empty")
+		return nil, nil, ErrNotEmptyFirstChunk
+	}
+
+	expected, err := checksumFromPb(r.ChecksumAlgorithm, r.Checksum)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requestDTO, err := dto.NewUploadFileStreamRequest(r.UserID, r.FilePath, expected, r.Size, c.streamConf.ReadAhead)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, nil, fmt.Errorf("failed to get upload file request: %w", err)
+	}
+
+	return r, requestDTO, nil
+}
+
+// asyncGetFileFromGrpcStream writes the received content to the request,
+// each message waiting for the transfer's bandwidth first.
+func (c fileServerController) asyncGetFileFromGrpcStream(
+	stream uploadStream, requestDTO *dto.UploadFileStreamRequest, transfer *limits.Transfer, streamErrChan chan error,
+) {
 	defer close(streamErrChan)
 	defer requestDTO.CloseWriter()
 
@@ -172,23 +520,45 @@ func (c fileServerController) asyncGetFileFromGrpcStream(stream pb.FileService_U
 
 			err = fmt.Errorf("failed to receive upload file request: %w", err)
 			slog.Error(err.Error())
+			requestDTO.CloseWriterWithError(err)
 			streamErrChan <- err
 			return
 		}
 
-		_, err = requestDTO.Write(req.Content)
-		if err != nil {
+		if err = transfer.Upload(stream.Context(), len(req.Content)); err != nil {
+			err = fmt.Errorf("failed to wait for upload bandwidth: %w", err)
+			slog.Error(err.Error())
+			requestDTO.CloseWriterWithError(err)
+			streamErrChan <- err
+			return
+		}
+
+		// the message is ours alone, so its content goes on without a copy
+		if err = requestDTO.WriteChunk(req.Content); err != nil {
 			err = fmt.Errorf("failed to write upload file request: %w", err)
 			slog.Error(err.Error())
+			requestDTO.CloseWriterWithError(err)
 			streamErrChan <- err
 			return
 		}
 	}
 }
 
-func New(service service.FilesService) FileServerController {
+// chunkSize is the chunk size for a client asking for requested, 0 taking
+// the default.
+func (c fileServerController) chunkSize(requested int32) int {
+	if requested <= 0 {
+		return c.streamConf.ChunkSize
+	}
+
+	return min(max(int(requested), c.streamConf.MinChunkSize), c.streamConf.MaxChunkSize)
+}
+
+func New(service service.FilesService, streamConf config.Streaming, limiter *limits.Limiter) FileServerController {
 	slog.Info("initializing controller")
 	return fileServerController{
-		Service: service,
+		Service:    service,
+		streamConf: streamConf,
+		limits:     limiter,
 	}
 }
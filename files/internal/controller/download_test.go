@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/avran02/decoplan/files/internal/config"
+	"github.com/avran02/decoplan/files/internal/limits"
+)
+
+func TestAsyncSendFile(t *testing.T) {
+	data := make([]byte, 10*1024+7)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	c := fileServerController{
+		streamConf: config.Streaming{ReadAhead: 2},
+		limits:     limits.New(config.Limits{}, nil),
+	}
+	transfer, err := c.limits.Begin("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transfer.End()
+
+	// the sent messages are kept, like a stats handler may, so a buffer
+	// reused after Send would show up as corrupted content
+	var sent [][]byte
+	last := false
+	streamErrChan := make(chan error, 1)
+	go c.asyncSendFile(context.Background(), io.NopCloser(bytes.NewReader(data)), 1024, transfer,
+		func(content []byte) error {
+			sent = append(sent, content)
+			return nil
+		},
+		func() error {
+			last = true
+			return nil
+		},
+		streamErrChan,
+	)
+
+	if err := <-streamErrChan; err != nil {
+		t.Fatalf("send error = %v", err)
+	}
+	if !last {
+		t.Error("the final message wasn't sent")
+	}
+	if len(sent) != 11 {
+		t.Errorf("sent %d chunks, want 11", len(sent))
+	}
+	if got := bytes.Join(sent, nil); !bytes.Equal(got, data) {
+		t.Error("sent content doesn't match the file")
+	}
+}
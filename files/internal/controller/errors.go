@@ -1,5 +1,69 @@
 package controller
 
-import "errors"
+import (
+	"errors"
+	"time"
 
-var ErrNotEmptyFirstChunk = errors.New("first chunk is not empty")
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/avran02/decoplan/files/internal/archive"
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/internal/limits"
+	"github.com/avran02/decoplan/files/internal/policy"
+	"github.com/avran02/decoplan/files/internal/service"
+	"github.com/avran02/decoplan/files/internal/thumbnail"
+	"github.com/avran02/decoplan/files/internal/webhook"
+)
+
+var (
+	ErrNotEmptyFirstChunk = errors.New("first chunk is not empty")
+	ErrWatcherTooSlow     = errors.New("watcher fell behind, resync and watch again")
+	ErrExtractNoProgress  = errors.New("archives are only extracted by UploadFile")
+)
+
+// toStatus turns errors the client can fix into their gRPC codes, others
+// are returned as they are.
+func toStatus(err error) error {
+	var busy *limits.BusyError
+	switch {
+	case errors.As(err, &busy):
+		return retryStatus(codes.ResourceExhausted, err, busy.RetryAfter)
+	case errors.Is(err, policy.ErrTypeNotAllowed), errors.Is(err, policy.ErrFileTooLarge),
+		errors.Is(err, service.ErrReservedPath), errors.Is(err, service.ErrInvalidThumbnailSize),
+		errors.Is(err, thumbnail.ErrUnsupportedType), errors.Is(err, thumbnail.ErrInvalidImage),
+		errors.Is(err, service.ErrInvalidArchiveSelection), errors.Is(err, archive.ErrUnsupportedFormat),
+		errors.Is(err, archive.ErrInvalidArchive), errors.Is(err, service.ErrBatchTooLarge),
+		errors.Is(err, service.ErrUploadSizeMismatch), errors.Is(err, dto.ErrEmptyUserID),
+		errors.Is(err, dto.ErrEmptyFilePath), errors.Is(err, dto.ErrUnsupportedAlgorithm),
+		errors.Is(err, dto.ErrEmptyChecksum), errors.Is(err, dto.ErrNegativeSize), errors.Is(err, ErrNotEmptyFirstChunk),
+		errors.Is(err, service.ErrInvalidWebhookURL), errors.Is(err, service.ErrInvalidEventKind),
+		errors.Is(err, webhook.ErrInvalidPageToken):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrChecksumMismatch):
+		return status.Error(codes.DataLoss, err.Error())
+	case errors.Is(err, service.ErrFileScanning), errors.Is(err, service.ErrFileInfected),
+		errors.Is(err, thumbnail.ErrTooLarge), errors.Is(err, service.ErrArchiveTooLarge):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrEmptyArchive), errors.Is(err, service.ErrNoUpload),
+		errors.Is(err, webhook.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrArchiveChanged), errors.Is(err, archive.ErrSizeMismatch):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return err
+	}
+}
+
+// retryStatus tells the client to try again after the delay.
+func retryStatus(code codes.Code, err error, after time.Duration) error {
+	st := status.New(code, err.Error())
+	detailed, dErr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(after)})
+	if dErr != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/internal/limits"
+	"github.com/avran02/decoplan/files/internal/service"
+	"github.com/avran02/decoplan/files/internal/webhook"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  codes.Code
+		wantRetry time.Duration
+	}{
+		{name: "busy", err: &limits.BusyError{RetryAfter: 5 * time.Second}, wantCode: codes.ResourceExhausted, wantRetry: 5 * time.Second},
+		{name: "wrapped busy", err: fmt.Errorf("failed to start: %w", &limits.BusyError{RetryAfter: time.Second}), wantCode: codes.ResourceExhausted, wantRetry: time.Second},
+		{name: "reserved path", err: service.ErrReservedPath, wantCode: codes.InvalidArgument},
+		{name: "size mismatch", err: fmt.Errorf("failed to upload: %w", service.ErrUploadSizeMismatch), wantCode: codes.InvalidArgument},
+		{name: "empty path", err: dto.ErrEmptyFilePath, wantCode: codes.InvalidArgument},
+		{name: "unsupported checksum", err: fmt.Errorf("failed to upload: %w", dto.ErrUnsupportedAlgorithm), wantCode: codes.InvalidArgument},
+		{name: "checksum without value", err: dto.ErrEmptyChecksum, wantCode: codes.InvalidArgument},
+		{name: "negative size", err: dto.ErrNegativeSize, wantCode: codes.InvalidArgument},
+		{name: "checksum mismatch", err: fmt.Errorf("failed to upload file: %w", service.ErrChecksumMismatch), wantCode: codes.DataLoss},
+		{name: "invalid webhook url", err: fmt.Errorf("failed to create webhook: %w", service.ErrInvalidWebhookURL), wantCode: codes.InvalidArgument},
+		{name: "invalid event kind", err: service.ErrInvalidEventKind, wantCode: codes.InvalidArgument},
+		{name: "invalid page token", err: webhook.ErrInvalidPageToken, wantCode: codes.InvalidArgument},
+		{name: "webhook not found", err: fmt.Errorf("failed to test webhook: %w", webhook.ErrNotFound), wantCode: codes.NotFound},
+		{name: "infected", err: service.ErrFileInfected, wantCode: codes.FailedPrecondition},
+		{name: "no upload", err: service.ErrNoUpload, wantCode: codes.NotFound},
+		{name: "archive changed", err: service.ErrArchiveChanged, wantCode: codes.Aborted},
+		{name: "unknown", err: errors.New("boom"), wantCode: codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatus(tt.err))
+			if st.Code() != tt.wantCode {
+				t.Errorf("code = %s, want %s", st.Code(), tt.wantCode)
+			}
+
+			var retry time.Duration
+			for _, detail := range st.Details() {
+				if info, ok := detail.(*errdetails.RetryInfo); ok {
+					retry = info.GetRetryDelay().AsDuration()
+				}
+			}
+			if retry != tt.wantRetry {
+				t.Errorf("retry delay = %s, want %s", retry, tt.wantRetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/files/internal/events"
+	"github.com/avran02/decoplan/files/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func fileEventToPb(event events.Event) *pb.FileEvent {
+	return &pb.FileEvent{
+		Kind:        fileEventKindToPb(event.Kind),
+		Bucket:      event.Bucket,
+		FilePath:    event.Key,
+		OldFilePath: event.OldKey,
+		Size:        event.Size,
+		Etag:        event.ETag,
+		Actor:       event.Actor,
+		Time:        timestamppb.New(event.Time),
+	}
+}
+
+func fileEventKindToPb(kind events.Kind) pb.FileEventKind {
+	switch kind {
+	case events.KindUploaded:
+		return pb.FileEventKind_FILE_EVENT_KIND_UPLOADED
+	case events.KindRemoved:
+		return pb.FileEventKind_FILE_EVENT_KIND_REMOVED
+	case events.KindMoved:
+		return pb.FileEventKind_FILE_EVENT_KIND_MOVED
+	default:
+		return pb.FileEventKind_FILE_EVENT_KIND_UNSPECIFIED
+	}
+}
+
+func fileEventKindFromPb(kind pb.FileEventKind) events.Kind {
+	switch kind {
+	case pb.FileEventKind_FILE_EVENT_KIND_UPLOADED:
+		return events.KindUploaded
+	case pb.FileEventKind_FILE_EVENT_KIND_REMOVED:
+		return events.KindRemoved
+	case pb.FileEventKind_FILE_EVENT_KIND_MOVED:
+		return events.KindMoved
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/pb"
+)
+
+// meter turns byte counts into progress messages, with the rate since the
+// previous message.
+type meter struct {
+	total  int64
+	done   int64
+	lastAt time.Time
+}
+
+func (m *meter) progress(done int64) *pb.TransferProgress {
+	now := time.Now()
+	var rate int64
+	if elapsed := now.Sub(m.lastAt); elapsed > 0 {
+		rate = int64(float64(done-m.done) / elapsed.Seconds())
+	}
+	m.done, m.lastAt = done, now
+
+	return &pb.TransferProgress{BytesDone: done, BytesTotal: m.total, BytesPerSecond: rate}
+}
+
+func newMeter(total int64) *meter {
+	return &meter{total: total, lastAt: time.Now()}
+}
+
+// UploadFileWithProgress is UploadFile, reporting what's stored every
+// ProgressInterval while the upload runs and a summary at the end.
+func (c fileServerController) UploadFileWithProgress(stream pb.FileService_UploadFileWithProgressServer) error {
+	slog.Info("Upload file with progress")
+
+	streamErrChan := make(chan error, 1)
+	ctx := stream.Context()
+	started := time.Now()
+
+	r, requestDTO, err := c.receiveUploadHeader(stream)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer requestDTO.CloseReader()
+
+	if r.Extract {
+		return status.Error(codes.InvalidArgument, ErrExtractNoProgress.Error())
+	}
+
+	transfer, err := c.limits.Begin(r.UserID)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer transfer.End()
+
+	go c.asyncGetFileFromGrpcStream(stream, requestDTO, transfer, streamErrChan)
+
+	type result struct {
+		stat dto.FileStat
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		stat, err := c.Service.UploadFile(ctx, requestDTO)
+		done <- result{stat: stat, err: err}
+	}()
+
+	ticker := time.NewTicker(c.streamConf.ProgressInterval)
+	defer ticker.Stop()
+
+	m := newMeter(r.Size)
+	for {
+		select {
+		case <-ticker.C:
+			progress, err := c.Service.GetUploadProgressByID(ctx, requestDTO.UploadID)
+			if err != nil {
+				// not started yet or just done
+				continue
+			}
+
+			msg := &pb.UploadFileWithProgressResponse{Message: &pb.UploadFileWithProgressResponse_Progress{
+				Progress: m.progress(progress.UploadedBytes),
+			}}
+			if err = stream.Send(msg); err != nil {
+				err = fmt.Errorf("failed to send upload progress: %w", err)
+				slog.Error(err.Error())
+				return err
+			}
+		case res := <-done:
+			if res.err != nil {
+				err = fmt.Errorf("failed to upload file: %w", res.err)
+				slog.Error(err.Error())
+				// the stream mustn't be received from after we return, the
+				// closed reader makes the receiving goroutine stop at its
+				// next message
+				requestDTO.CloseReader()
+				<-streamErrChan
+
+				return toStatus(err)
+			}
+
+			if err = <-streamErrChan; err != nil {
+				err = fmt.Errorf("failed while uploading file from stream: %w", err)
+				slog.Error(err.Error())
+				return err
+			}
+
+			msg := &pb.UploadFileWithProgressResponse{Message: &pb.UploadFileWithProgressResponse_Summary{
+				Summary: transferSummaryToPb(res.stat.ETag, res.stat.Size, time.Since(started), res.stat.Digest),
+			}}
+			if err = stream.Send(msg); err != nil {
+				err = fmt.Errorf("failed to send upload summary: %w", err)
+				slog.Error(err.Error())
+				return err
+			}
+
+			return nil
+		}
+	}
+}
+
+// DownloadFileWithProgress is DownloadFile, reporting what's sent every
+// ProgressInterval in between the content and a summary at the end.
+func (c fileServerController) DownloadFileWithProgress(
+	req *pb.DownloadFileRequest, stream pb.FileService_DownloadFileWithProgressServer,
+) error {
+	ctx := stream.Context()
+	streamErrChan := make(chan error, 1)
+	started := time.Now()
+
+	transfer, err := c.limits.Begin(req.UserID)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer transfer.End()
+
+	file, stat, err := c.Service.DownloadFile(ctx, req.UserID, req.FilePath, req.Offset, req.Length)
+	if err != nil {
+		return toStatus(fmt.Errorf("failed to download file: %w", err))
+	}
+
+	m := newMeter(rangeLength(stat.Size, req.Offset, req.Length))
+	var sent int64
+	go c.asyncSendFile(ctx, file, c.chunkSize(req.ChunkSize), transfer,
+		func(content []byte) error {
+			msg := &pb.DownloadFileWithProgressResponse{Message: &pb.DownloadFileWithProgressResponse_Content{Content: content}}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+			sent += int64(len(content))
+
+			if time.Since(m.lastAt) < c.streamConf.ProgressInterval {
+				return nil
+			}
+			return stream.Send(&pb.DownloadFileWithProgressResponse{Message: &pb.DownloadFileWithProgressResponse_Progress{
+				Progress: m.progress(sent),
+			}})
+		},
+		func() error {
+			return stream.Send(&pb.DownloadFileWithProgressResponse{Message: &pb.DownloadFileWithProgressResponse_Summary{
+				Summary: transferSummaryToPb(stat.ETag, sent, time.Since(started), stat.Digest),
+			}})
+		},
+		streamErrChan,
+	)
+
+	if err = <-streamErrChan; err != nil {
+		slog.Error(err.Error())
+		return toStatus(fmt.Errorf("failed to download file: %w", err))
+	}
+
+	return nil
+}
+
+// rangeLength is how many bytes a download of length bytes from offset
+// gets from a file of size, a zero length reading to the end.
+func rangeLength(size, offset, length int64) int64 {
+	if length == 0 || offset+length > size {
+		length = size - offset
+	}
+
+	return max(0, length)
+}
+
+func transferSummaryToPb(etag string, size int64, duration time.Duration, digest dto.Digest) *pb.TransferSummary {
+	return &pb.TransferSummary{
+		Etag:     etag,
+		Size:     size,
+		Duration: durationpb.New(duration),
+		Digest:   digestToPb(digest),
+	}
+}
@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/avran02/decoplan/files/internal/config"
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/internal/limits"
+	"github.com/avran02/decoplan/files/internal/service"
+	"github.com/avran02/decoplan/files/pb"
+)
+
+const (
+	benchFileSize = 16 << 20
+	// storageLatency and networkLatency are paid per read from storage and
+	// per message sent to the client.
+	storageLatency = 200 * time.Microsecond
+	networkLatency = 200 * time.Microsecond
+)
+
+// localStorage stands in for MinIO: it serves every download from a file on
+// disk, sleeping before each read the way a remote object does.
+type localStorage struct {
+	service.FilesService
+	path    string
+	latency time.Duration
+}
+
+func (s localStorage) DownloadFile(context.Context, string, string, int64, int64) (io.ReadCloser, dto.FileStat, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, dto.FileStat{}, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, dto.FileStat{}, err
+	}
+
+	return slowFile{File: f, latency: s.latency}, dto.FileStat{Size: info.Size()}, nil
+}
+
+type slowFile struct {
+	*os.File
+	latency time.Duration
+}
+
+func (f slowFile) Read(p []byte) (int, error) {
+	time.Sleep(f.latency)
+	return f.File.Read(p)
+}
+
+// downloadStream is a client that takes latency to receive every message.
+type downloadStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	latency time.Duration
+	sent    int64
+}
+
+func (s *downloadStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *downloadStream) Send(resp *pb.DownloadFileResponse) error {
+	time.Sleep(s.latency)
+	s.sent += int64(len(resp.Content))
+	return nil
+}
+
+func newLocalStorage(b *testing.B) localStorage {
+	b.Helper()
+
+	data := make([]byte, benchFileSize)
+	if _, err := rand.Read(data); err != nil {
+		b.Fatal(err)
+	}
+
+	path := filepath.Join(b.TempDir(), "file")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		b.Fatal(err)
+	}
+
+	return localStorage{path: path, latency: storageLatency}
+}
+
+// sequentialDownload is the loop downloads used before read-ahead: a fresh
+// buffer per download, and every read waits for the previous send.
+func sequentialDownload(c fileServerController, req *pb.DownloadFileRequest, stream *downloadStream) error {
+	file, _, err := c.Service.DownloadFile(stream.Context(), req.UserID, req.FilePath, req.Offset, req.Length)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	chunkSize := c.chunkSize(req.ChunkSize)
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(file, buf)
+		if n > 0 {
+			if err := stream.Send(&pb.DownloadFileResponse{Content: buf[:n], ChunkSize: int32(chunkSize)}); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return stream.Send(&pb.DownloadFileResponse{Success: true})
+}
+
+func BenchmarkDownloadFile(b *testing.B) {
+	storage := newLocalStorage(b)
+	c := fileServerController{
+		Service: storage,
+		streamConf: config.Streaming{
+			ChunkSize:    256 << 10,
+			MinChunkSize: 16 << 10,
+			MaxChunkSize: 3 << 20,
+			ReadAhead:    4,
+		},
+		limits: limits.New(config.Limits{}, nil),
+	}
+
+	modes := []struct {
+		name     string
+		download func(*pb.DownloadFileRequest, *downloadStream) error
+	}{
+		{
+			name: "sequential",
+			download: func(req *pb.DownloadFileRequest, stream *downloadStream) error {
+				return sequentialDownload(c, req, stream)
+			},
+		},
+		{
+			name: "read-ahead",
+			download: func(req *pb.DownloadFileRequest, stream *downloadStream) error {
+				return c.DownloadFile(req, stream)
+			},
+		},
+	}
+
+	for _, chunkSize := range []int{64 << 10, 256 << 10, 1 << 20, 3 << 20} {
+		for _, mode := range modes {
+			b.Run(fmt.Sprintf("chunk=%dKiB/%s", chunkSize>>10, mode.name), func(b *testing.B) {
+				req := &pb.DownloadFileRequest{UserID: "user", FilePath: "file", ChunkSize: int32(chunkSize)}
+				b.SetBytes(benchFileSize)
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					stream := &downloadStream{ctx: context.Background(), latency: networkLatency}
+					if err := mode.download(req, stream); err != nil {
+						b.Fatal(err)
+					}
+					if stream.sent != benchFileSize {
+						b.Fatalf("sent %d bytes, want %d", stream.sent, benchFileSize)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestChunkSize(t *testing.T) {
+	c := fileServerController{streamConf: config.Streaming{
+		ChunkSize:    256 << 10,
+		MinChunkSize: 16 << 10,
+		MaxChunkSize: 3 << 20,
+	}}
+
+	tests := []struct {
+		name      string
+		requested int32
+		want      int
+	}{
+		{name: "unset", requested: 0, want: 256 << 10},
+		{name: "negative", requested: -1, want: 256 << 10},
+		{name: "below the minimum", requested: 1024, want: 16 << 10},
+		{name: "in range", requested: 1 << 20, want: 1 << 20},
+		{name: "above the maximum", requested: 64 << 20, want: 3 << 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.chunkSize(tt.requested); got != tt.want {
+				t.Errorf("chunkSize(%d) = %d, want %d", tt.requested, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/files/internal/thumbnail"
+	"github.com/avran02/decoplan/files/pb"
+)
+
+// thumbnailSizeFromPb returns 0 for unknown sizes, which the service refuses.
+func thumbnailSizeFromPb(size pb.ThumbnailSize) thumbnail.Size {
+	switch size {
+	case pb.ThumbnailSize_THUMBNAIL_SIZE_UNSPECIFIED, pb.ThumbnailSize_THUMBNAIL_SIZE_SMALL:
+		return thumbnail.Small
+	case pb.ThumbnailSize_THUMBNAIL_SIZE_MEDIUM:
+		return thumbnail.Medium
+	case pb.ThumbnailSize_THUMBNAIL_SIZE_LARGE:
+		return thumbnail.Large
+	default:
+		return 0
+	}
+}
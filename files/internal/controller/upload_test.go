@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/avran02/decoplan/files/internal/config"
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/internal/limits"
+	"github.com/avran02/decoplan/files/pb"
+)
+
+// recvStream replays msgs and then fails with err.
+type recvStream struct {
+	ctx  context.Context
+	msgs []*pb.UploadFileRequest
+	err  error
+}
+
+func (s *recvStream) Recv() (*pb.UploadFileRequest, error) {
+	if len(s.msgs) == 0 {
+		return nil, s.err
+	}
+	msg := s.msgs[0]
+	s.msgs = s.msgs[1:]
+
+	return msg, nil
+}
+
+func (s *recvStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestAsyncGetFileFromGrpcStream(t *testing.T) {
+	disconnected := errors.New("client went away")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		err        error
+		rate       int
+		closeRead  bool
+		wantStream error
+		wantRead   error
+	}{
+		{name: "complete", ctx: context.Background(), err: io.EOF},
+		{name: "client disconnected", ctx: context.Background(), err: disconnected, wantStream: disconnected, wantRead: disconnected},
+		{name: "bandwidth wait cancelled", ctx: cancelled, err: io.EOF, rate: 1, wantStream: context.Canceled, wantRead: context.Canceled},
+		{name: "reader gave up", ctx: context.Background(), err: io.EOF, closeRead: true, wantStream: io.ErrClosedPipe, wantRead: io.ErrClosedPipe},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fileServerController{limits: limits.New(config.Limits{UploadRate: tt.rate}, nil)}
+			transfer, err := c.limits.Begin("user")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer transfer.End()
+
+			req, err := dto.NewUploadFileStreamRequest("user", "file", nil, 0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.closeRead {
+				req.CloseReader()
+			}
+
+			stream := &recvStream{
+				ctx:  tt.ctx,
+				msgs: []*pb.UploadFileRequest{{Content: []byte("hello")}},
+				err:  tt.err,
+			}
+			streamErrChan := make(chan error, 1)
+			// every error path closes the writer and the deferred close runs
+			// again, which mustn't panic
+			go c.asyncGetFileFromGrpcStream(stream, req, transfer, streamErrChan)
+
+			if !tt.closeRead {
+				if _, err := io.ReadAll(req); !errors.Is(err, tt.wantRead) {
+					t.Errorf("reading the upload: %v, want %v", err, tt.wantRead)
+				}
+			}
+			if err := <-streamErrChan; !errors.Is(err, tt.wantStream) {
+				t.Errorf("stream error = %v, want %v", err, tt.wantStream)
+			}
+		})
+	}
+}
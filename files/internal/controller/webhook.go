@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/files/internal/webhook"
+	"github.com/avran02/decoplan/files/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func webhookToPb(w webhook.Webhook) *pb.Webhook {
+	kinds := make([]pb.FileEventKind, 0, len(w.Kinds))
+	for _, kind := range w.Kinds {
+		kinds = append(kinds, fileEventKindToPb(kind))
+	}
+
+	return &pb.Webhook{
+		Id:        w.ID,
+		Url:       w.URL,
+		Kinds:     kinds,
+		Prefix:    w.Prefix,
+		CreatedAt: timestamppb.New(w.CreatedAt),
+	}
+}
+
+func webhookDeliveryToPb(d webhook.Delivery) *pb.WebhookDelivery {
+	return &pb.WebhookDelivery{
+		Id:             d.ID,
+		Kind:           fileEventKindToPb(d.Kind),
+		Status:         deliveryStatusToPb(d.Status),
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		NextAttemptAt:  timestamppb.New(d.NextAttemptAt),
+		CreatedAt:      timestamppb.New(d.CreatedAt),
+		UpdatedAt:      timestamppb.New(d.UpdatedAt),
+	}
+}
+
+func deliveryStatusToPb(status webhook.Status) pb.WebhookDeliveryStatus {
+	switch status {
+	case webhook.StatusPending:
+		return pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_PENDING
+	case webhook.StatusDelivered:
+		return pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_DELIVERED
+	case webhook.StatusDead:
+		return pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_DEAD
+	default:
+		return pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_UNSPECIFIED
+	}
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "github.com/avran02/decoplan/files/internal/archive"
+
+// ArchiveRequest selects the files of an archive, either everything under
+// Prefix or the files at Paths.
+type ArchiveRequest struct {
+	UserID string
+	Prefix string
+	Paths  []string
+	Format archive.Format
+}
+
+// ExtractedEntry is what became of an archive entry. Path is where it was
+// stored, or its name in the archive if it wasn't; Err says why not.
+type ExtractedEntry struct {
+	Path   string
+	Size   int64
+	Digest Digest
+	Err    error
+}
@@ -0,0 +1,36 @@
+package dto
+
+import "errors"
+
+var ErrUnknownBatchKind = errors.New("unknown batch operation")
+
+type BatchKind string
+
+const (
+	BatchDelete BatchKind = "delete"
+	BatchCopy   BatchKind = "copy"
+	BatchMove   BatchKind = "move"
+)
+
+// BatchItem is one operation of a batch, NewFilePath is only used by copies
+// and moves.
+type BatchItem struct {
+	Kind        BatchKind
+	FilePath    string
+	NewFilePath string
+}
+
+type BatchRequest struct {
+	UserID      string
+	Items       []BatchItem
+	DryRun      bool
+	StopOnError bool
+}
+
+// BatchResult is the outcome of the item at Index. A skipped item wasn't
+// run because an earlier one failed.
+type BatchResult struct {
+	Index   int
+	Err     error
+	Skipped bool
+}
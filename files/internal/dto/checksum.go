@@ -0,0 +1,72 @@
+package dto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"strings"
+)
+
+type ChecksumAlgorithm string
+
+const (
+	ChecksumSHA256 ChecksumAlgorithm = "sha256"
+	ChecksumCRC32C ChecksumAlgorithm = "crc32c"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Digest holds the hex encoded checksums of a file. Files uploaded before
+// checksums existed have empty ones.
+type Digest struct {
+	SHA256 string
+	CRC32C string
+}
+
+// Get returns the checksum of the given algorithm.
+func (d Digest) Get(algorithm ChecksumAlgorithm) string {
+	switch algorithm {
+	case ChecksumSHA256:
+		return d.SHA256
+	case ChecksumCRC32C:
+		return d.CRC32C
+	default:
+		return ""
+	}
+}
+
+// Checksum is the checksum a client expects the uploaded file to have.
+type Checksum struct {
+	Algorithm ChecksumAlgorithm
+	Value     string
+}
+
+// Matches compares case-insensitively, hex digits may come in either case.
+func (c Checksum) Matches(d Digest) bool {
+	return strings.EqualFold(c.Value, d.Get(c.Algorithm))
+}
+
+type hasher struct {
+	sha256 hash.Hash
+	crc32c hash.Hash32
+}
+
+func (h *hasher) Write(p []byte) {
+	h.sha256.Write(p)
+	h.crc32c.Write(p)
+}
+
+func (h *hasher) Digest() Digest {
+	return Digest{
+		SHA256: hex.EncodeToString(h.sha256.Sum(nil)),
+		CRC32C: hex.EncodeToString(h.crc32c.Sum(nil)),
+	}
+}
+
+func newHasher() *hasher {
+	return &hasher{
+		sha256: sha256.New(),
+		crc32c: crc32.New(crc32cTable),
+	}
+}
@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+type FileStat struct {
+	Name         string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	ContentType  string
+	Digest       Digest
+}
@@ -2,40 +2,77 @@ package dto
 
 import (
 	"errors"
-	"io"
+
+	"github.com/google/uuid"
+
+	"github.com/avran02/decoplan/files/internal/stream"
 )
 
 var (
-	ErrEmptyUserID   = errors.New("empty user id")
-	ErrEmptyFilePath = errors.New("empty file path")
+	ErrEmptyUserID          = errors.New("empty user id")
+	ErrEmptyFilePath        = errors.New("empty file path")
+	ErrUnsupportedAlgorithm = errors.New("unsupported checksum algorithm")
+	ErrEmptyChecksum        = errors.New("checksum algorithm given without checksum")
+	ErrNegativeSize         = errors.New("upload size must not be negative")
 )
 
 type UploadFileStreamRequest struct {
 	UserID   string
 	FilePath string
-	reader   *io.PipeReader
-	writer   *io.PipeWriter
+	// Expected is nil if the client didn't send a checksum.
+	Expected *Checksum
+	// Size is the length the client declared, zero if it didn't.
+	Size int64
+	// UploadID tells this upload apart from others to the same path.
+	UploadID string
+	pipe     *stream.Pipe
+	hasher   *hasher
 
 	Content []byte
 }
 
 func (r *UploadFileStreamRequest) Read(buf []byte) (int, error) {
-	return r.reader.Read(buf)
+	return r.pipe.Read(buf)
 }
 
+// Write hashes the chunk on its way to the pipe.
 func (r *UploadFileStreamRequest) Write(buf []byte) (int, error) {
-	return (*r.writer).Write(buf)
+	r.hasher.Write(buf)
+	return r.pipe.Write(buf)
+}
+
+// WriteChunk is Write without the copy, for chunks nobody else holds, like
+// the content of a received message.
+func (r *UploadFileStreamRequest) WriteChunk(chunk []byte) error {
+	r.hasher.Write(chunk)
+	return r.pipe.Send(chunk)
 }
 
 func (r *UploadFileStreamRequest) CloseWriter() {
-	r.writer.Close()
+	r.pipe.Close()
+}
+
+// CloseWriterWithError makes the reading side fail with err instead of
+// seeing a clean EOF, so a broken stream never stores a truncated file.
+func (r *UploadFileStreamRequest) CloseWriterWithError(err error) {
+	r.pipe.CloseWithError(err)
 }
 
 func (r *UploadFileStreamRequest) CloseReader() {
-	r.reader.Close()
+	r.pipe.CloseRead()
 }
 
-func NewUploadFileStreamRequest(userID, filePath string) (*UploadFileStreamRequest, error) {
+// Digest is the checksum of everything written so far. It's final once the
+// writer is closed.
+func (r *UploadFileStreamRequest) Digest() Digest {
+	return r.hasher.Digest()
+}
+
+// NewUploadFileStreamRequest buffers up to readAhead chunks between the
+// writer and the upload.
+func NewUploadFileStreamRequest(
+	userID, filePath string, expected *Checksum, size int64, readAhead int,
+) (*UploadFileStreamRequest, error) {
 	if userID == "" {
 		return nil, ErrEmptyUserID
 	}
@@ -44,13 +81,30 @@ func NewUploadFileStreamRequest(userID, filePath string) (*UploadFileStreamReque
 		return nil, ErrEmptyFilePath
 	}
 
-	pr, pw := io.Pipe()
+	if size < 0 {
+		return nil, ErrNegativeSize
+	}
+
+	if expected != nil {
+		switch expected.Algorithm {
+		case ChecksumSHA256, ChecksumCRC32C:
+		default:
+			return nil, ErrUnsupportedAlgorithm
+		}
+
+		if expected.Value == "" {
+			return nil, ErrEmptyChecksum
+		}
+	}
 
 	return &UploadFileStreamRequest{
 		UserID:   userID,
 		FilePath: filePath,
+		Expected: expected,
+		Size:     size,
+		UploadID: uuid.NewString(),
 
-		reader: pr,
-		writer: pw,
+		pipe:   stream.NewPipe(readAhead),
+		hasher: newHasher(),
 	}, nil
 }
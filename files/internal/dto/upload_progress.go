@@ -0,0 +1,11 @@
+package dto
+
+// UploadProgress is how far an upload got. Size is what the client declared,
+// zero if it didn't. Parts are only counted for uploads stored in parts.
+type UploadProgress struct {
+	Size          int64
+	ReceivedBytes int64
+	UploadedBytes int64
+	Parts         int32
+	PartsDone     int32
+}
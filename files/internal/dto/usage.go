@@ -0,0 +1,9 @@
+package dto
+
+// Usage is the storage a user takes. LogicalBytes sums the sizes of the
+// user's files, PhysicalBytes counts shared content only once.
+type Usage struct {
+	Files         int64
+	LogicalBytes  int64
+	PhysicalBytes int64
+}
@@ -0,0 +1,308 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testChunkSize = 64
+
+func writeKeyring(t *testing.T, file keyringFile) string {
+	t.Helper()
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func randomKey(t *testing.T) string {
+	t.Helper()
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func newKeyring(t *testing.T, current string, ids ...string) *Keyring {
+	t.Helper()
+
+	file := keyringFile{Current: current, Keys: map[string]string{current: randomKey(t)}}
+	for _, id := range ids {
+		file.Keys[id] = randomKey(t)
+	}
+
+	k, err := LoadKeyring(writeKeyring(t, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return k
+}
+
+func seal(t *testing.T, k *Keyring, plain []byte) ([]byte, Header) {
+	t.Helper()
+
+	r, h, err := k.Seal(bytes.NewReader(plain), testChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sealed, h
+}
+
+func open(k *Keyring, sealed []byte, h Header, offset, length int64) ([]byte, error) {
+	span, err := h.Span(int64(len(sealed)), offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	if span.Length == 0 {
+		return []byte{}, nil
+	}
+
+	r, err := k.Open(bytes.NewReader(sealed[span.Start:span.End+1]), h, span)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+func TestSealOpen(t *testing.T) {
+	k := newKeyring(t, "k1")
+
+	tests := []struct {
+		name           string
+		size           int
+		offset, length int64
+	}{
+		{name: "empty", size: 0},
+		{name: "one byte", size: 1},
+		{name: "short chunk", size: testChunkSize - 1},
+		{name: "one chunk", size: testChunkSize},
+		{name: "chunk and a byte", size: testChunkSize + 1},
+		{name: "several chunks", size: 3*testChunkSize + 5},
+		{name: "range in one chunk", size: 3*testChunkSize + 5, offset: testChunkSize + 3, length: 10},
+		{name: "range across chunks", size: 3*testChunkSize + 5, offset: testChunkSize - 2, length: testChunkSize + 4},
+		{name: "range to the end", size: 3*testChunkSize + 5, offset: 2*testChunkSize + 1},
+		{name: "range past the end", size: 3*testChunkSize + 5, offset: 3 * testChunkSize, length: 100},
+		{name: "last byte", size: 3*testChunkSize + 5, offset: 3*testChunkSize + 4, length: 1},
+		{name: "at the end", size: 3*testChunkSize + 5, offset: 3*testChunkSize + 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := make([]byte, tt.size)
+			if _, err := rand.Read(plain); err != nil {
+				t.Fatal(err)
+			}
+
+			sealed, h := seal(t, k, plain)
+			if got, want := int64(len(sealed)), h.SealedSize(int64(tt.size)); got != want {
+				t.Errorf("sealed %d bytes, SealedSize() = %d", got, want)
+			}
+			if got := h.PlaintextSize(int64(len(sealed))); got != int64(tt.size) {
+				t.Errorf("PlaintextSize() = %d, want %d", got, tt.size)
+			}
+
+			got, err := open(k, sealed, h, tt.offset, tt.length)
+			if err != nil {
+				t.Fatalf("open() error = %v", err)
+			}
+
+			end := int64(tt.size)
+			if tt.length != 0 {
+				end = min(end, tt.offset+tt.length)
+			}
+			if want := plain[tt.offset:end]; !bytes.Equal(got, want) {
+				t.Errorf("open() = %d bytes, want %d bytes of plaintext", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	k := newKeyring(t, "k1", "k2")
+	plain := bytes.Repeat([]byte("decoplan"), 3*testChunkSize/8+1)
+	sealed, h := seal(t, k, plain)
+	sealedChunk := testChunkSize + tagSize
+
+	tests := []struct {
+		name    string
+		sealed  func() []byte
+		header  func() Header
+		wantErr error
+	}{
+		{name: "flipped bit", wantErr: ErrTampered, sealed: func() []byte {
+			b := bytes.Clone(sealed)
+			b[sealedChunk+5] ^= 1
+			return b
+		}},
+		{name: "swapped chunks", wantErr: ErrTampered, sealed: func() []byte {
+			b := bytes.Clone(sealed)
+			copy(b[:sealedChunk], sealed[sealedChunk:2*sealedChunk])
+			copy(b[sealedChunk:2*sealedChunk], sealed[:sealedChunk])
+			return b
+		}},
+		{name: "final chunk dropped", wantErr: ErrTampered, sealed: func() []byte {
+			return bytes.Clone(sealed[:3*sealedChunk])
+		}},
+		{name: "final chunk cut short", wantErr: ErrTampered, sealed: func() []byte {
+			return bytes.Clone(sealed[:len(sealed)-1])
+		}},
+		{name: "wrapped key moved to another key id", wantErr: ErrTampered, header: func() Header {
+			other := h
+			other.KEK = "k2"
+			return other
+		}},
+		{name: "unknown key id", wantErr: ErrUnknownKEK, header: func() Header {
+			other := h
+			other.KEK = "k3"
+			return other
+		}},
+		{name: "other nonce", wantErr: ErrTampered, header: func() Header {
+			other := h
+			other.Nonce = bytes.Clone(h.Nonce)
+			other.Nonce[0] ^= 1
+			return other
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, header := sealed, h
+			if tt.sealed != nil {
+				b = tt.sealed()
+			}
+			if tt.header != nil {
+				header = tt.header()
+			}
+
+			if _, err := open(k, b, header, 0, 0); !errors.Is(err, tt.wantErr) {
+				t.Errorf("open() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSealUsesFreshKeys(t *testing.T) {
+	k := newKeyring(t, "k1")
+	plain := []byte("same content")
+
+	first, h1 := seal(t, k, plain)
+	second, h2 := seal(t, k, plain)
+	if bytes.Equal(first, second) || bytes.Equal(h1.WrappedKey, h2.WrappedKey) || bytes.Equal(h1.Nonce, h2.Nonce) {
+		t.Error("sealing the same content twice gave the same output")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := randomKey(t)
+	k1, err := LoadKeyring(writeKeyring(t, keyringFile{Current: "k1", Keys: map[string]string{"k1": old}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := []byte("written before the rotation")
+	sealed, h := seal(t, k1, plain)
+
+	rotated := keyringFile{Current: "k2", Keys: map[string]string{"k1": old, "k2": randomKey(t)}}
+	k2, err := LoadKeyring(writeKeyring(t, rotated))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := open(k2, sealed, h, 0, 0)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("open() after rotation = %q, %v", got, err)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    keyringFile
+		wantErr error
+	}{
+		{name: "valid", file: keyringFile{Current: "k1", Keys: map[string]string{"k1": randomKey(t)}}},
+		{name: "current missing", file: keyringFile{Current: "k2", Keys: map[string]string{"k1": randomKey(t)}}, wantErr: ErrInvalidKeyring},
+		{name: "not base64", file: keyringFile{Current: "k1", Keys: map[string]string{"k1": "not base64!"}}, wantErr: ErrInvalidKeyring},
+		{name: "short key", file: keyringFile{Current: "k1", Keys: map[string]string{"k1": base64.StdEncoding.EncodeToString(make([]byte, 16))}}, wantErr: ErrInvalidKeyring},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadKeyring(writeKeyring(t, tt.file)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("LoadKeyring() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	valid := Header{KEK: "k1", WrappedKey: []byte("wrapped"), Nonce: make([]byte, nonceSize), ChunkSize: testChunkSize}
+
+	tests := []struct {
+		name          string
+		meta          map[string]string
+		wantEncrypted bool
+		wantErr       error
+	}{
+		{name: "plain file", meta: map[string]string{}},
+		{name: "round trip", meta: valid.Metadata(), wantEncrypted: true},
+		{name: "bad key", meta: with(valid.Metadata(), metaKey, "%%%"), wantEncrypted: true, wantErr: ErrInvalidHeader},
+		{name: "short nonce", meta: with(valid.Metadata(), metaNonce, "AAAA"), wantEncrypted: true, wantErr: ErrInvalidHeader},
+		{name: "zero chunk size", meta: with(valid.Metadata(), metaChunkSize, "0"), wantEncrypted: true, wantErr: ErrInvalidHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, encrypted, err := ParseHeader(func(key string) string { return tt.meta[key] })
+			if encrypted != tt.wantEncrypted || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseHeader() = %v, %v, want %v, %v", encrypted, err, tt.wantEncrypted, tt.wantErr)
+			}
+			if err == nil && encrypted && (h.KEK != valid.KEK || !bytes.Equal(h.WrappedKey, valid.WrappedKey) || h.ChunkSize != valid.ChunkSize) {
+				t.Errorf("ParseHeader() = %+v, want %+v", h, valid)
+			}
+		})
+	}
+}
+
+func TestSpanInvalid(t *testing.T) {
+	h := Header{ChunkSize: testChunkSize}
+	sealedSize := h.SealedSize(100)
+
+	for _, tt := range []struct{ offset, length int64 }{{-1, 0}, {0, -1}, {101, 0}} {
+		if _, err := h.Span(sealedSize, tt.offset, tt.length); !errors.Is(err, ErrInvalidRange) {
+			t.Errorf("Span(%d, %d) error = %v, want %v", tt.offset, tt.length, err, ErrInvalidRange)
+		}
+	}
+}
+
+func with(meta map[string]string, key, value string) map[string]string {
+	meta[key] = value
+	return meta
+}
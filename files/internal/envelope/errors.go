@@ -0,0 +1,12 @@
+package envelope
+
+import "errors"
+
+var (
+	ErrInvalidKeyring = errors.New("invalid keyring")
+	ErrUnknownKEK     = errors.New("unknown key encryption key")
+	ErrNoKeyring      = errors.New("file is envelope encrypted but no keyring is configured")
+	ErrInvalidHeader  = errors.New("invalid envelope metadata")
+	ErrTampered       = errors.New("encrypted file is corrupted or was tampered with")
+	ErrInvalidRange   = errors.New("invalid range")
+)
@@ -0,0 +1,121 @@
+package envelope
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+// Object metadata keys of an envelope encrypted file, stored as
+// X-Amz-Meta-Envelope-*.
+const (
+	metaKEK       = "Envelope-Kek"
+	metaKey       = "Envelope-Key"
+	metaNonce     = "Envelope-Nonce"
+	metaChunkSize = "Envelope-Chunk-Size"
+)
+
+// Header is what it takes to decrypt a file besides the keyring. It's kept
+// in the object metadata.
+type Header struct {
+	KEK        string
+	WrappedKey []byte
+	Nonce      []byte
+	ChunkSize  int
+}
+
+func (h Header) Metadata() map[string]string {
+	return map[string]string{
+		metaKEK:       h.KEK,
+		metaKey:       base64.StdEncoding.EncodeToString(h.WrappedKey),
+		metaNonce:     base64.StdEncoding.EncodeToString(h.Nonce),
+		metaChunkSize: strconv.Itoa(h.ChunkSize),
+	}
+}
+
+// ParseHeader reads the header from object metadata through get. It
+// returns false for files that aren't envelope encrypted.
+func ParseHeader(get func(key string) string) (Header, bool, error) {
+	kek := get(metaKEK)
+	if kek == "" {
+		return Header{}, false, nil
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(get(metaKey))
+	if err != nil {
+		return Header{}, true, ErrInvalidHeader
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(get(metaNonce))
+	if err != nil || len(nonce) != nonceSize {
+		return Header{}, true, ErrInvalidHeader
+	}
+
+	chunkSize, err := strconv.Atoi(get(metaChunkSize))
+	if err != nil || chunkSize <= 0 {
+		return Header{}, true, ErrInvalidHeader
+	}
+
+	return Header{KEK: kek, WrappedKey: wrapped, Nonce: nonce, ChunkSize: chunkSize}, true, nil
+}
+
+// Span is the part of an encrypted object to fetch for a plaintext range.
+// Start and End are inclusive ciphertext offsets on chunk boundaries; the
+// first Skip bytes of the decrypted chunks come before the range.
+type Span struct {
+	Start, End  int64
+	First, Last int64
+	Total       int64
+	Skip        int64
+	Length      int64
+}
+
+// PlaintextSize is the size of the file before encryption. Every chunk
+// grows by the GCM tag and there's always at least one chunk.
+func (h Header) PlaintextSize(ciphertextSize int64) int64 {
+	return ciphertextSize - h.chunks(ciphertextSize)*tagSize
+}
+
+// SealedSize is the size a file of plaintextSize takes once encrypted.
+func (h Header) SealedSize(plaintextSize int64) int64 {
+	chunkSize := int64(h.ChunkSize)
+	return plaintextSize + max(1, (plaintextSize+chunkSize-1)/chunkSize)*tagSize
+}
+
+// Span maps length bytes from offset of the plaintext to whole chunks. A
+// zero length reads to the end. An empty result has a zero Length and there
+// is nothing to fetch.
+func (h Header) Span(ciphertextSize, offset, length int64) (Span, error) {
+	size := h.PlaintextSize(ciphertextSize)
+	if offset < 0 || length < 0 || offset > size {
+		return Span{}, ErrInvalidRange
+	}
+
+	if length == 0 || offset+length > size {
+		length = size - offset
+	}
+
+	if length == 0 {
+		return Span{}, nil
+	}
+
+	chunkSize := int64(h.ChunkSize)
+	sealedSize := chunkSize + tagSize
+	total := h.chunks(ciphertextSize)
+	first := offset / chunkSize
+	last := (offset + length - 1) / chunkSize
+
+	return Span{
+		Start:  first * sealedSize,
+		End:    min(ciphertextSize, (last+1)*sealedSize) - 1,
+		First:  first,
+		Last:   last,
+		Total:  total,
+		Skip:   offset - first*chunkSize,
+		Length: length,
+	}, nil
+}
+
+func (h Header) chunks(ciphertextSize int64) int64 {
+	sealedSize := int64(h.ChunkSize) + tagSize
+	return (ciphertextSize + sealedSize - 1) / sealedSize
+}
@@ -0,0 +1,106 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+const keySize = 32
+
+// keyringFile is the format of the keyring file. Keys are 32 base64
+// encoded bytes; rotating means adding a key and pointing Current at it,
+// older keys stay to unwrap the data keys of existing files.
+type keyringFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// Keyring holds the key encryption keys that wrap the data key of every
+// file.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var file keyringFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKeyring, err)
+	}
+
+	if _, ok := file.Keys[file.Current]; !ok {
+		return nil, fmt.Errorf("%w: current key %q is missing", ErrInvalidKeyring, file.Current)
+	}
+
+	k := &Keyring{
+		current: file.Current,
+		keys:    make(map[string]cipher.AEAD, len(file.Keys)),
+	}
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("%w: key %q must be %d base64 encoded bytes", ErrInvalidKeyring, id, keySize)
+		}
+		if k.keys[id], err = newAEAD(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// wrap encrypts the data key with the current key encryption key. The key
+// ID is authenticated too, so a wrapped key can't be moved to another ID.
+func (k *Keyring) wrap(dataKey []byte) (string, []byte, error) {
+	aead := k.keys[k.current]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(dataKey)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return k.current, aead.Seal(nonce, nonce, dataKey, []byte(k.current)), nil
+}
+
+func (k *Keyring) unwrap(kekID string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.keys[kekID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKEK, kekID)
+	}
+
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrInvalidHeader
+	}
+
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, []byte(kekID))
+	if err != nil {
+		return nil, ErrTampered
+	}
+
+	return dataKey, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+
+	return aead, nil
+}
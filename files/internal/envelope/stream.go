@@ -0,0 +1,204 @@
+package envelope
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	nonceSize = 12
+	tagSize   = 16
+)
+
+// Seal encrypts r under a fresh data key in chunks of chunkSize, each
+// sealed with AES-GCM on its own, so ranges can be decrypted without the
+// rest of the file. The returned header goes into the object metadata.
+func (k *Keyring) Seal(r io.Reader, chunkSize int) (io.Reader, Header, error) {
+	dataKey := make([]byte, keySize)
+	nonce := make([]byte, nonceSize)
+	for _, b := range [][]byte{dataKey, nonce} {
+		if _, err := io.ReadFull(rand.Reader, b); err != nil {
+			return nil, Header{}, fmt.Errorf("failed to generate data key: %w", err)
+		}
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, Header{}, err
+	}
+
+	kek, wrapped, err := k.wrap(dataKey)
+	if err != nil {
+		return nil, Header{}, err
+	}
+
+	header := Header{
+		KEK:        kek,
+		WrappedKey: wrapped,
+		Nonce:      nonce,
+		ChunkSize:  chunkSize,
+	}
+	sealed := &sealer{
+		src:   bufio.NewReader(r),
+		aead:  aead,
+		nonce: nonce,
+		buf:   make([]byte, chunkSize),
+	}
+
+	return sealed, header, nil
+}
+
+// Open decrypts the chunks First to Last of span from r, which starts at
+// span.Start, and returns span.Length bytes from span.Skip.
+func (k *Keyring) Open(r io.Reader, h Header, span Span) (io.Reader, error) {
+	dataKey, err := k.unwrap(h.KEK, h.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := &opener{
+		src:   r,
+		aead:  aead,
+		nonce: h.Nonce,
+		index: uint64(span.First),
+		last:  uint64(span.Last),
+		final: uint64(span.Total - 1),
+		buf:   make([]byte, h.ChunkSize+tagSize),
+	}
+
+	if _, err = io.CopyN(io.Discard, plain, span.Skip); err != nil {
+		return nil, err
+	}
+
+	return io.LimitReader(plain, span.Length), nil
+}
+
+type sealer struct {
+	src   *bufio.Reader
+	aead  cipher.AEAD
+	nonce []byte
+	index uint64
+	buf   []byte
+	out   []byte
+	done  bool
+}
+
+func (s *sealer) Read(p []byte) (int, error) {
+	if len(s.out) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.seal(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+
+	return n, nil
+}
+
+// seal encrypts the next chunk. The last chunk is marked as such, so a
+// truncated file fails to decrypt; an empty file still gets one chunk.
+func (s *sealer) seal() error {
+	n, err := io.ReadFull(s.src, s.buf)
+	final := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	if err != nil && !final {
+		return err
+	}
+
+	if !final {
+		if _, err = s.src.Peek(1); errors.Is(err, io.EOF) {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	s.out = s.aead.Seal(s.out[:0], chunkNonce(s.nonce, s.index), s.buf[:n], chunkAAD(final))
+	s.index++
+	s.done = final
+
+	return nil
+}
+
+type opener struct {
+	src   io.Reader
+	aead  cipher.AEAD
+	nonce []byte
+	index uint64
+	last  uint64
+	final uint64
+	buf   []byte
+	out   []byte
+}
+
+func (o *opener) Read(p []byte) (int, error) {
+	for len(o.out) == 0 {
+		if o.index > o.last {
+			return 0, io.EOF
+		}
+		if err := o.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, o.out)
+	o.out = o.out[n:]
+
+	return n, nil
+}
+
+func (o *opener) open() error {
+	n, err := io.ReadFull(o.src, o.buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if errors.Is(err, io.EOF) {
+			return ErrTampered
+		}
+		return err
+	}
+
+	// only the final chunk may be short
+	if n < len(o.buf) && o.index != o.final {
+		return ErrTampered
+	}
+
+	plain, err := o.aead.Open(o.buf[:0], chunkNonce(o.nonce, o.index), o.buf[:n], chunkAAD(o.index == o.final))
+	if err != nil {
+		return ErrTampered
+	}
+
+	o.out = plain
+	o.index++
+
+	return nil
+}
+
+// chunkNonce is the file nonce with the chunk index XORed into its last
+// eight bytes, unique per chunk as the data key is unique per file.
+func chunkNonce(base []byte, index uint64) []byte {
+	nonce := make([]byte, nonceSize)
+	copy(nonce, base)
+	binary.BigEndian.PutUint64(nonce[4:], binary.BigEndian.Uint64(nonce[4:])^index)
+
+	return nonce
+}
+
+func chunkAAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+
+	return []byte{0}
+}
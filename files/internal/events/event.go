@@ -0,0 +1,42 @@
+package events
+
+import (
+	"strings"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindUploaded Kind = "uploaded"
+	KindRemoved  Kind = "removed"
+	KindMoved    Kind = "moved"
+)
+
+// Event describes a change of one object. For moves Key is the new key and
+// OldKey the previous one. Actor is the user the request was made for.
+type Event struct {
+	Kind   Kind
+	Bucket string
+	Key    string
+	OldKey string
+	Size   int64
+	ETag   string
+	Actor  string
+	Time   time.Time
+}
+
+// Filter selects events of one bucket, optionally only those under a prefix.
+// An empty bucket matches every bucket.
+type Filter struct {
+	Bucket string
+	Prefix string
+}
+
+func (f Filter) Match(e Event) bool {
+	if f.Bucket != "" && f.Bucket != e.Bucket {
+		return false
+	}
+
+	return strings.HasPrefix(e.Key, f.Prefix) || (e.OldKey != "" && strings.HasPrefix(e.OldKey, f.Prefix))
+}
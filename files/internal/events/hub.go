@@ -0,0 +1,84 @@
+package events
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Hub fans events out to subscribers. Publishing never blocks: a subscriber
+// whose buffer is full is dropped and has to resync with ListFiles.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	buffer      int
+}
+
+type Subscription struct {
+	filter Filter
+	events chan Event
+	lost   bool
+}
+
+// Events is closed when the subscription is dropped or cancelled.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Lost reports whether the hub dropped the subscription because it fell
+// behind. It's only meaningful after Events is closed.
+func (s *Subscription) Lost() bool {
+	return s.lost
+}
+
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		if !sub.filter.Match(event) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			slog.Warn("dropping slow file events subscriber", "bucket", sub.filter.Bucket, "prefix", sub.filter.Prefix)
+			sub.lost = true
+			h.remove(sub)
+		}
+	}
+}
+
+func (h *Hub) Subscribe(filter Filter) *Subscription {
+	sub := &Subscription{
+		filter: filter,
+		events: make(chan Event, h.buffer),
+	}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[sub]; ok {
+		h.remove(sub)
+	}
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	delete(h.subscribers, sub)
+	close(sub.events)
+}
+
+func NewHub(buffer int) *Hub {
+	return &Hub{
+		subscribers: make(map[*Subscription]struct{}),
+		buffer:      buffer,
+	}
+}
@@ -0,0 +1,25 @@
+package limits
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidOverrides = errors.New("invalid limit overrides")
+	ErrTooManyStreams   = errors.New("too many transfers running for this user")
+)
+
+// BusyError is ErrTooManyStreams with the time the client should wait before
+// trying again.
+type BusyError struct {
+	RetryAfter time.Duration
+}
+
+func (e *BusyError) Error() string {
+	return ErrTooManyStreams.Error()
+}
+
+func (e *BusyError) Unwrap() error {
+	return ErrTooManyStreams
+}
@@ -0,0 +1,168 @@
+package limits
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/avran02/decoplan/files/internal/config"
+)
+
+// Limiter throttles the bytes of transfers with token buckets, one per user
+// and direction and one shared by everyone per direction, and caps how many
+// transfers a user runs at once. A nil bucket doesn't limit.
+type Limiter struct {
+	conf      config.Limits
+	overrides *Overrides
+	upload    *rate.Limiter
+	download  *rate.Limiter
+
+	mu        sync.Mutex
+	users     map[string]*user
+	lastSweep time.Time
+}
+
+// sweepInterval is how often Begin looks for users to forget.
+const sweepInterval = time.Second
+
+// user is forgotten once the user has no transfers running and their
+// buckets have filled up again, a fresh user is no different then. Forgetting
+// them any earlier would hand out a new burst.
+type user struct {
+	upload     *rate.Limiter
+	download   *rate.Limiter
+	streams    int
+	maxStreams int
+}
+
+// Transfer is a running transfer of a user, End frees its slot.
+type Transfer struct {
+	limiter *Limiter
+	user    *user
+	once    sync.Once
+}
+
+// Begin takes one of the user's transfer slots. With all taken it fails
+// with a BusyError.
+func (l *Limiter) Begin(userID string) (*Transfer, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now := time.Now(); now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+		l.lastSweep = now
+	}
+
+	u, ok := l.users[userID]
+	if !ok {
+		u = l.newUser(userID)
+		l.users[userID] = u
+	}
+
+	if u.maxStreams > 0 && u.streams >= u.maxStreams {
+		return nil, &BusyError{RetryAfter: l.conf.RetryAfter}
+	}
+	u.streams++
+
+	return &Transfer{limiter: l, user: u}, nil
+}
+
+// Upload waits until n more bytes may be received.
+func (t *Transfer) Upload(ctx context.Context, n int) error {
+	return wait(ctx, n, t.user.upload, t.limiter.upload)
+}
+
+// Download waits until n more bytes may be sent.
+func (t *Transfer) Download(ctx context.Context, n int) error {
+	return wait(ctx, n, t.user.download, t.limiter.download)
+}
+
+func (t *Transfer) End() {
+	t.once.Do(func() {
+		l := t.limiter
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		t.user.streams--
+	})
+}
+
+// sweep forgets the idle users whose buckets are full. l.mu must be held.
+func (l *Limiter) sweep(now time.Time) {
+	for userID, u := range l.users {
+		if u.streams == 0 && full(now, u.upload) && full(now, u.download) {
+			delete(l.users, userID)
+		}
+	}
+}
+
+func full(now time.Time, b *rate.Limiter) bool {
+	return b == nil || b.TokensAt(now) >= float64(b.Burst())
+}
+
+func (l *Limiter) newUser(userID string) *user {
+	uploadRate, downloadRate, streams := l.conf.UploadRate, l.conf.DownloadRate, l.conf.Streams
+	if o, ok := l.overrides.Users[userID]; ok {
+		if o.UploadRate != nil {
+			uploadRate = *o.UploadRate
+		}
+		if o.DownloadRate != nil {
+			downloadRate = *o.DownloadRate
+		}
+		if o.Streams != nil {
+			streams = *o.Streams
+		}
+	}
+
+	return &user{
+		upload:     bucket(uploadRate),
+		download:   bucket(downloadRate),
+		maxStreams: streams,
+	}
+}
+
+// wait takes n tokens from each bucket. A bucket holds a second's worth, so
+// larger chunks are taken in parts.
+func wait(ctx context.Context, n int, buckets ...*rate.Limiter) error {
+	for _, b := range buckets {
+		if b == nil {
+			continue
+		}
+
+		for left := n; left > 0; {
+			take := min(left, b.Burst())
+			if err := b.WaitN(ctx, take); err != nil {
+				return err
+			}
+			left -= take
+		}
+	}
+
+	return nil
+}
+
+// bucket is a token bucket for bytesPerSecond, nil without a limit.
+func bucket(bytesPerSecond int) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), bytesPerSecond)
+}
+
+// New builds the limiter, overrides is nil without an overrides file.
+func New(conf config.Limits, overrides *Overrides) *Limiter {
+	if overrides == nil {
+		overrides = &Overrides{}
+	}
+
+	return &Limiter{
+		conf:      conf,
+		overrides: overrides,
+		upload:    bucket(conf.GlobalUploadRate),
+		download:  bucket(conf.GlobalDownloadRate),
+		users:     make(map[string]*user),
+	}
+}
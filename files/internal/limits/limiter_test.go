@@ -0,0 +1,170 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/avran02/decoplan/files/internal/config"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestBeginStreams(t *testing.T) {
+	overrides := &Overrides{Users: map[string]User{
+		"bot":       {Streams: intPtr(3)},
+		"unlimited": {Streams: intPtr(0)},
+	}}
+
+	tests := []struct {
+		name    string
+		userID  string
+		streams int
+		want    int
+	}{
+		{name: "configured cap", userID: "alice", streams: 5, want: 2},
+		{name: "override", userID: "bot", streams: 5, want: 3},
+		{name: "lifted cap", userID: "unlimited", streams: 5, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(config.Limits{Streams: 2, RetryAfter: 3 * time.Second}, overrides)
+
+			started := 0
+			for range tt.streams {
+				_, err := l.Begin(tt.userID)
+				if err == nil {
+					started++
+					continue
+				}
+
+				var busy *BusyError
+				if !errors.As(err, &busy) || !errors.Is(err, ErrTooManyStreams) {
+					t.Fatalf("Begin() error = %v, want a BusyError", err)
+				}
+				if busy.RetryAfter != 3*time.Second {
+					t.Errorf("RetryAfter = %s, want 3s", busy.RetryAfter)
+				}
+			}
+
+			if started != tt.want {
+				t.Errorf("started %d transfers, want %d", started, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndFreesSlotOnce(t *testing.T) {
+	l := New(config.Limits{Streams: 2}, nil)
+
+	first, err := l.Begin("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = l.Begin("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// ending twice mustn't free the other transfer's slot
+	first.End()
+	first.End()
+
+	if _, err = l.Begin("alice"); err != nil {
+		t.Fatalf("Begin() after End() error = %v", err)
+	}
+	if _, err = l.Begin("alice"); !errors.Is(err, ErrTooManyStreams) {
+		t.Fatalf("Begin() over the cap error = %v, want %v", err, ErrTooManyStreams)
+	}
+}
+
+func TestUsersKeepBucketsUntilRefilled(t *testing.T) {
+	l := New(config.Limits{UploadRate: 1000, DownloadRate: 1000}, nil)
+	ctx := context.Background()
+
+	first, err := l.Begin("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = first.Upload(ctx, 1000); err != nil {
+		t.Fatal(err)
+	}
+	first.End()
+
+	// a new transfer right away has to share what the last one left
+	second, err := l.Begin("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.user != first.user {
+		t.Fatal("the user was forgotten while their transfer's bucket was empty")
+	}
+	if tokens := second.user.upload.TokensAt(time.Now()); tokens > 100 {
+		t.Errorf("new transfer starts with %.0f tokens, want the drained bucket", tokens)
+	}
+	second.End()
+
+	now := time.Now()
+	l.mu.Lock()
+	l.sweep(now)
+	_, kept := l.users["alice"]
+	l.sweep(now.Add(2 * time.Second))
+	_, stillKept := l.users["alice"]
+	l.mu.Unlock()
+
+	if !kept {
+		t.Error("sweep forgot a user whose bucket is still filling up")
+	}
+	if stillKept {
+		t.Error("sweep kept a user whose buckets are full again")
+	}
+}
+
+func TestSweepKeepsRunningTransfers(t *testing.T) {
+	l := New(config.Limits{}, nil)
+
+	if _, err := l.Begin("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	l.mu.Lock()
+	l.sweep(time.Now().Add(time.Hour))
+	_, kept := l.users["alice"]
+	l.mu.Unlock()
+
+	if !kept {
+		t.Error("sweep forgot a user with a transfer running")
+	}
+}
+
+func TestWait(t *testing.T) {
+	l := New(config.Limits{GlobalDownloadRate: 1 << 20}, nil)
+	transfer, err := l.Begin("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transfer.End()
+
+	// more than a burst is taken in parts instead of failing
+	start := time.Now()
+	if err = transfer.Download(context.Background(), 3<<19); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("1.5 seconds worth of bytes took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = transfer.Download(ctx, 1<<20); err == nil {
+		t.Error("Download() on a drained bucket ignored the cancelled context")
+	}
+
+	// no upload limit is configured
+	if err = transfer.Upload(ctx, 1<<30); err != nil {
+		t.Errorf("Upload() without a limit error = %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package limits
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Overrides gives some users other limits than the configured ones. Limits
+// left out keep the configured value, 0 lifts the limit.
+//
+//	{"users": {
+//	    "backup-bot": {"uploadRate": 104857600, "streams": 16},
+//	    "heavy-user": {"downloadRate": 1048576}
+//	}}
+type Overrides struct {
+	Users map[string]User `json:"users"`
+}
+
+// User holds rates in bytes per second and the number of transfers the user
+// may run at once.
+type User struct {
+	UploadRate   *int `json:"uploadRate"`
+	DownloadRate *int `json:"downloadRate"`
+	Streams      *int `json:"streams"`
+}
+
+func LoadOverrides(file string) (*Overrides, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read limit overrides: %w", err)
+	}
+
+	var o Overrides
+	if err = json.Unmarshal(data, &o); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOverrides, err)
+	}
+
+	for id, user := range o.Users {
+		for _, v := range []*int{user.UploadRate, user.DownloadRate, user.Streams} {
+			if v != nil && *v < 0 {
+				return nil, fmt.Errorf("%w: negative limit for %s", ErrInvalidOverrides, id)
+			}
+		}
+	}
+
+	return &o, nil
+}
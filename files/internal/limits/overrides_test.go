@@ -0,0 +1,44 @@
+package limits
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOverrides(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantErr     error
+		wantStreams *int
+	}{
+		{name: "valid", data: `{"users": {"bot": {"streams": 16, "uploadRate": 0}}}`, wantStreams: intPtr(16)},
+		{name: "left out", data: `{"users": {"bot": {"uploadRate": 1024}}}`},
+		{name: "negative", data: `{"users": {"bot": {"downloadRate": -1}}}`, wantErr: ErrInvalidOverrides},
+		{name: "not json", data: `users: bot`, wantErr: ErrInvalidOverrides},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "overrides.json")
+			if err := os.WriteFile(file, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			o, err := LoadOverrides(file)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LoadOverrides() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			got := o.Users["bot"].Streams
+			if (got == nil) != (tt.wantStreams == nil) || (got != nil && *got != *tt.wantStreams) {
+				t.Errorf("streams = %v, want %v", got, tt.wantStreams)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package policy
+
+import "errors"
+
+var (
+	ErrInvalidPolicy  = errors.New("invalid upload policy")
+	ErrTypeNotAllowed = errors.New("file type is not allowed here")
+	ErrFileTooLarge   = errors.New("file is larger than allowed for its type")
+)
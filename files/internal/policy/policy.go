@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// Policy decides which files a space takes. Spaces are buckets, picked by
+// name patterns as in path.Match, so group spaces need names that tell them
+// apart, e.g. "group-*". The first matching space applies; a bucket no
+// space matches takes everything.
+//
+//	{"spaces": [{
+//	    "buckets": ["group-*"],
+//	    "deny": ["application/x-executable", "text/x-shellscript"],
+//	    "maxSizes": [{"type": "video/*", "bytes": 1073741824}]
+//	}]}
+type Policy struct {
+	Spaces []Space `json:"spaces"`
+}
+
+// Space lists MIME type patterns such as "image/*", "*/*" matches any type.
+// Deny wins over allow, and an empty allow list allows everything not
+// denied. The first matching max size applies.
+type Space struct {
+	Buckets  []string  `json:"buckets"`
+	Allow    []string  `json:"allow"`
+	Deny     []string  `json:"deny"`
+	MaxSizes []MaxSize `json:"maxSizes"`
+}
+
+type MaxSize struct {
+	Type  string `json:"type"`
+	Bytes int64  `json:"bytes"`
+}
+
+func Load(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload policy: %w", err)
+	}
+
+	var p Policy
+	if err = json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+
+	// path.Match only reports bad patterns when it gets to them
+	for _, space := range p.Spaces {
+		patterns := append(append(append([]string{}, space.Buckets...), space.Allow...), space.Deny...)
+		for _, limit := range space.MaxSizes {
+			patterns = append(patterns, limit.Type)
+		}
+		for _, pattern := range patterns {
+			if _, err = path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%w: pattern %q: %w", ErrInvalidPolicy, pattern, err)
+			}
+		}
+	}
+
+	return &p, nil
+}
+
+// Check returns an error if the bucket doesn't take files of the type. On
+// success it returns the largest size allowed for the type, 0 for no limit.
+func (p *Policy) Check(bucket, mimeType string) (int64, error) {
+	space, ok := p.space(bucket)
+	if !ok {
+		return 0, nil
+	}
+
+	if matchAny(space.Deny, mimeType) || len(space.Allow) > 0 && !matchAny(space.Allow, mimeType) {
+		return 0, fmt.Errorf("%w: %s", ErrTypeNotAllowed, mimeType)
+	}
+
+	for _, limit := range space.MaxSizes {
+		if match(limit.Type, mimeType) {
+			return limit.Bytes, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func (p *Policy) space(bucket string) (Space, bool) {
+	for _, space := range p.Spaces {
+		if matchAny(space.Buckets, bucket) {
+			return space, true
+		}
+	}
+
+	return Space{}, false
+}
+
+// LimitReader fails with ErrFileTooLarge once r yields more than limit bytes,
+// so the upload is aborted instead of being cut short.
+func LimitReader(r io.Reader, limit int64) io.Reader {
+	return &limitReader{r: r, left: limit}
+}
+
+type limitReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.left < 0 {
+		return 0, ErrFileTooLarge
+	}
+
+	return n, err
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if match(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// match ignores bad patterns, Load already rejected them.
+func match(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
@@ -0,0 +1,54 @@
+package policy
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+)
+
+// SniffLen is how many leading bytes Detect looks at.
+const SniffLen = 512
+
+// magic lists signatures http.DetectContentType doesn't know or reports as
+// plain octet streams. Executables matter most, as they're what policies
+// usually deny.
+var magic = []struct {
+	offset    int
+	signature []byte
+	mimeType  string
+}{
+	{0, []byte("\x7fELF"), "application/x-executable"},
+	{0, []byte("MZ"), "application/vnd.microsoft.portable-executable"},
+	{0, []byte("\xfe\xed\xfa\xce"), "application/x-mach-binary"},
+	{0, []byte("\xfe\xed\xfa\xcf"), "application/x-mach-binary"},
+	{0, []byte("\xce\xfa\xed\xfe"), "application/x-mach-binary"},
+	{0, []byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+	{0, []byte("\xca\xfe\xba\xbe"), "application/x-mach-binary"},
+	{0, []byte("#!"), "text/x-shellscript"},
+	{0, []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), "application/x-ole-storage"},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), "application/x-7z-compressed"},
+	{0, []byte("\xfd7zXZ\x00"), "application/x-xz"},
+	{0, []byte("BZh"), "application/x-bzip2"},
+	{4, []byte("ftypheic"), "image/heic"},
+	{4, []byte("ftypheix"), "image/heic"},
+	{4, []byte("ftypmif1"), "image/heif"},
+	{4, []byte("ftypavif"), "image/avif"},
+}
+
+// Detect returns the MIME type of a file from its first bytes, without
+// parameters such as the charset.
+func Detect(head []byte) string {
+	for _, m := range magic {
+		if len(head) >= m.offset+len(m.signature) && bytes.Equal(head[m.offset:m.offset+len(m.signature)], m.signature) {
+			return m.mimeType
+		}
+	}
+
+	detected := http.DetectContentType(head)
+	mimeType, _, err := mime.ParseMediaType(detected)
+	if err != nil {
+		return detected
+	}
+
+	return mimeType
+}
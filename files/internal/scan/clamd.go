@@ -0,0 +1,114 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+const clamdChunkSize = 64 * 1024
+
+// Clamd scans with a ClamAV daemon over its INSTREAM command: the content
+// goes in length prefixed chunks, a zero length chunk ends it.
+type Clamd struct {
+	address string
+}
+
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return Verdict{}, fmt.Errorf("failed to set clamd deadline: %w", err)
+		}
+	}
+
+	if err = c.stream(conn, r); err != nil {
+		// clamd closes the connection once the stream exceeds its size
+		// limit, the reply then says why
+		if reply, rErr := readReply(conn); rErr == nil {
+			return Verdict{}, fmt.Errorf("%w: %s", ErrScanFailed, reply)
+		}
+		return Verdict{}, err
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	return parseReply(reply)
+}
+
+func (c *Clamd) stream(conn net.Conn, r io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("failed to send clamd command: %w", err)
+	}
+
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, wErr := conn.Write(buf[:4+n]); wErr != nil {
+				return fmt.Errorf("failed to send content to clamd: %w", wErr)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read content: %w", err)
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to end clamd stream: %w", err)
+	}
+
+	return nil
+}
+
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// parseReply reads replies like "stream: OK" and
+// "stream: Win.Test.EICAR_HDB-1 FOUND".
+func parseReply(reply string) (Verdict, error) {
+	result, ok := strings.CutPrefix(reply, "stream: ")
+	if !ok {
+		return Verdict{}, fmt.Errorf("%w: %s", ErrUnexpectedReply, reply)
+	}
+
+	switch {
+	case result == "OK":
+		return Verdict{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Infected: true, Threat: strings.TrimSuffix(result, " FOUND")}, nil
+	case strings.HasSuffix(result, " ERROR"):
+		return Verdict{}, fmt.Errorf("%w: %s", ErrScanFailed, strings.TrimSuffix(result, " ERROR"))
+	default:
+		return Verdict{}, fmt.Errorf("%w: %s", ErrUnexpectedReply, reply)
+	}
+}
+
+func NewClamd(address string) *Clamd {
+	return &Clamd{address: address}
+}
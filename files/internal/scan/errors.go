@@ -0,0 +1,9 @@
+package scan
+
+import "errors"
+
+var (
+	ErrUnknownMode     = errors.New("unknown scan mode")
+	ErrScanFailed      = errors.New("scanner failed")
+	ErrUnexpectedReply = errors.New("unexpected scanner response")
+)
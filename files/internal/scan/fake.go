@@ -0,0 +1,39 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// eicar is the EICAR anti-virus test file, which real scanners detect too.
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// Fake finds its signatures anywhere in the content. It stands in for a
+// real scanner in development and tests.
+type Fake struct {
+	Signatures map[string][]byte
+}
+
+func (f *Fake) Scan(_ context.Context, r io.Reader) (Verdict, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to read content: %w", err)
+	}
+
+	for threat, signature := range f.Signatures {
+		if bytes.Contains(content, signature) {
+			return Verdict{Infected: true, Threat: threat}, nil
+		}
+	}
+
+	return Verdict{}, nil
+}
+
+// NewFake detects the EICAR test file.
+func NewFake() *Fake {
+	return &Fake{Signatures: map[string][]byte{
+		"Eicar-Test-Signature": []byte(eicar),
+	}}
+}
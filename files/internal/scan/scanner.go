@@ -0,0 +1,41 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/avran02/decoplan/files/internal/config"
+)
+
+const (
+	ModeNone  = "none"
+	ModeClamd = "clamd"
+	ModeFake  = "fake"
+)
+
+type Verdict struct {
+	Infected bool
+	// Threat is the signature the scanner matched, empty for clean files.
+	Threat string
+}
+
+// Scanner checks file content for malware. An error means no verdict, the
+// file is scanned again later.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Verdict, error)
+}
+
+// New returns the scanner of the configured mode, nil if scanning is off.
+func New(conf config.Scan) (Scanner, error) {
+	switch conf.Mode {
+	case ModeNone, "":
+		return nil, nil
+	case ModeClamd:
+		return NewClamd(conf.ClamdAddress), nil
+	case ModeFake:
+		return NewFake(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMode, conf.Mode)
+	}
+}
@@ -0,0 +1,5 @@
+package server
+
+import "errors"
+
+var ErrReservedBucket = errors.New("bucket is reserved for the service's own state")
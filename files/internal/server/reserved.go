@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// userRequest is every request that names a user, whose ID is also the
+// name of their bucket.
+type userRequest interface {
+	GetUserID() string
+}
+
+// ReservedBuckets rejects requests naming one of the buckets the service
+// keeps its own state in, such as webhook secrets, as their user. It checks
+// every message of every RPC, so no handler can forget it.
+func ReservedBuckets(buckets ...string) []grpc.ServerOption {
+	check := func(msg any) error {
+		if req, ok := msg.(userRequest); ok && slices.Contains(buckets, req.GetUserID()) {
+			return status.Error(codes.PermissionDenied, ErrReservedBucket.Error())
+		}
+
+		return nil
+	}
+
+	unary := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := check(req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+
+	stream := func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, checkedStream{ServerStream: ss, check: check})
+	}
+
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(unary), grpc.ChainStreamInterceptor(stream)}
+}
+
+type checkedStream struct {
+	grpc.ServerStream
+	check func(msg any) error
+}
+
+func (s checkedStream) RecvMsg(msg any) error {
+	if err := s.ServerStream.RecvMsg(msg); err != nil {
+		return err
+	}
+
+	return s.check(msg)
+}
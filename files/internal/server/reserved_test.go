@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/avran02/decoplan/files/pb"
+)
+
+const webhookBucket = "decoplan-webhooks"
+
+// stubServer implements nothing, so requests that get through end up
+// unimplemented. Uploads read their header first, like the controller does.
+type stubServer struct {
+	pb.UnimplementedFileServiceServer
+}
+
+func (stubServer) UploadFile(stream pb.FileService_UploadFileServer) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+
+	return status.Error(codes.Unimplemented, "method UploadFile not implemented")
+}
+
+func dialReserved(t *testing.T) pb.FileServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(ReservedBuckets(webhookBucket, "decoplan-blobs")...)
+	pb.RegisterFileServiceServer(srv, stubServer{})
+	go srv.Serve(lis) //nolint:errcheck
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewFileServiceClient(conn)
+}
+
+func TestReservedBuckets(t *testing.T) {
+	client := dialReserved(t)
+	ctx := context.Background()
+
+	upload := func(userID string) error {
+		stream, err := client.UploadFile(ctx)
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(&pb.UploadFileRequest{UserID: userID, FilePath: "a.txt"}); err != nil {
+			return err
+		}
+		_, err = stream.CloseAndRecv()
+		return err
+	}
+
+	download := func(userID string) error {
+		stream, err := client.DownloadFile(ctx, &pb.DownloadFileRequest{UserID: userID, FilePath: "a.txt"})
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+
+	tests := []struct {
+		name   string
+		call   func() error
+		wantOK bool
+	}{
+		{name: "list webhook bucket", call: func() error {
+			_, err := client.ListFiles(ctx, &pb.ListFilesRequest{UserID: webhookBucket})
+			return err
+		}},
+		{name: "unregister blob bucket", call: func() error {
+			_, err := client.UnregisterUser(ctx, &pb.UnregisterUserRequest{UserID: "decoplan-blobs"})
+			return err
+		}},
+		{name: "remove from webhook bucket", call: func() error {
+			_, err := client.RemoveFile(ctx, &pb.RemoveFileRequest{UserID: webhookBucket, FilePath: "webhooks/x"})
+			return err
+		}},
+		{name: "download from blob bucket", call: func() error { return download("decoplan-blobs") }},
+		{name: "upload to webhook bucket", call: func() error { return upload(webhookBucket) }},
+		{name: "list user bucket", wantOK: true, call: func() error {
+			_, err := client.ListFiles(ctx, &pb.ListFilesRequest{UserID: "alice"})
+			return err
+		}},
+		{name: "download from user bucket", wantOK: true, call: func() error { return download("alice") }},
+		{name: "upload to user bucket", wantOK: true, call: func() error { return upload("alice") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := codes.PermissionDenied
+			if tt.wantOK {
+				want = codes.Unimplemented
+			}
+
+			if got := status.Code(tt.call()); got != want {
+				t.Errorf("code = %s, want %s", got, want)
+			}
+		})
+	}
+}
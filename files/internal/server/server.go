@@ -21,6 +21,10 @@ func (s FileServer) DownloadFile(req *pb.DownloadFileRequest, stream pb.FileServ
 	return s.FileServerController.DownloadFile(req, stream)
 }
 
+func (s FileServer) DownloadArchive(req *pb.DownloadArchiveRequest, stream pb.FileService_DownloadArchiveServer) error {
+	return s.FileServerController.DownloadArchive(req, stream)
+}
+
 func (s FileServer) UploadFile(stream pb.FileService_UploadFileServer) error {
 	return s.FileServerController.UploadFile(stream)
 }
@@ -29,10 +33,70 @@ func (s FileServer) RemoveFile(ctx context.Context, req *pb.RemoveFileRequest) (
 	return s.FileServerController.RemoveFile(ctx, req)
 }
 
+func (s FileServer) StatFile(ctx context.Context, req *pb.StatFileRequest) (*pb.StatFileResponse, error) {
+	return s.FileServerController.StatFile(ctx, req)
+}
+
 func (s FileServer) RegisterUser(ctx context.Context, req *pb.RegisterUserRequest) (*pb.RegisterUserResponse, error) {
 	return s.FileServerController.RegisterUser(ctx, req)
 }
 
+func (s FileServer) UnregisterUser(ctx context.Context, req *pb.UnregisterUserRequest) (*pb.UnregisterUserResponse, error) {
+	return s.FileServerController.UnregisterUser(ctx, req)
+}
+
+func (s FileServer) MoveFile(ctx context.Context, req *pb.MoveFileRequest) (*pb.MoveFileResponse, error) {
+	return s.FileServerController.MoveFile(ctx, req)
+}
+
+func (s FileServer) GetThumbnail(ctx context.Context, req *pb.GetThumbnailRequest) (*pb.GetThumbnailResponse, error) {
+	return s.FileServerController.GetThumbnail(ctx, req)
+}
+
+func (s FileServer) DownloadFileWithProgress(req *pb.DownloadFileRequest, stream pb.FileService_DownloadFileWithProgressServer) error {
+	return s.FileServerController.DownloadFileWithProgress(req, stream)
+}
+
+func (s FileServer) UploadFileWithProgress(stream pb.FileService_UploadFileWithProgressServer) error {
+	return s.FileServerController.UploadFileWithProgress(stream)
+}
+
+func (s FileServer) GetUploadProgress(ctx context.Context, req *pb.GetUploadProgressRequest) (*pb.GetUploadProgressResponse, error) {
+	return s.FileServerController.GetUploadProgress(ctx, req)
+}
+
+func (s FileServer) BatchOperation(req *pb.BatchOperationRequest, stream pb.FileService_BatchOperationServer) error {
+	return s.FileServerController.BatchOperation(req, stream)
+}
+
+func (s FileServer) GetUsage(ctx context.Context, req *pb.GetUsageRequest) (*pb.GetUsageResponse, error) {
+	return s.FileServerController.GetUsage(ctx, req)
+}
+
+func (s FileServer) WatchFiles(req *pb.WatchFilesRequest, stream pb.FileService_WatchFilesServer) error {
+	return s.FileServerController.WatchFiles(req, stream)
+}
+
+func (s FileServer) CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.CreateWebhookResponse, error) {
+	return s.FileServerController.CreateWebhook(ctx, req)
+}
+
+func (s FileServer) ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	return s.FileServerController.ListWebhooks(ctx, req)
+}
+
+func (s FileServer) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	return s.FileServerController.DeleteWebhook(ctx, req)
+}
+
+func (s FileServer) TestWebhook(ctx context.Context, req *pb.TestWebhookRequest) (*pb.TestWebhookResponse, error) {
+	return s.FileServerController.TestWebhook(ctx, req)
+}
+
+func (s FileServer) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	return s.FileServerController.ListWebhookDeliveries(ctx, req)
+}
+
 func New(controller controller.FileServerController) FileServer {
 	slog.Info("initializing server")
 	return FileServer{
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/avran02/decoplan/files/internal/archive"
+	"github.com/avran02/decoplan/files/internal/dto"
+)
+
+// archiveEntry is a file as it was when DownloadArchive listed it.
+type archiveEntry struct {
+	key      string
+	name     string
+	size     int64
+	etag     string
+	modified time.Time
+}
+
+// DownloadArchive streams the selected files as an archive that's built
+// while it's read. The files are listed up front and the caps are checked
+// against that listing; a file that changes afterwards fails the download
+// instead of ending up in the archive. Quarantined files and derivatives are
+// left out.
+func (s *filesService) DownloadArchive(ctx context.Context, req dto.ArchiveRequest) (io.ReadCloser, error) {
+	if req.Prefix != "" && len(req.Paths) > 0 {
+		return nil, ErrInvalidArchiveSelection
+	}
+
+	pr, pw := io.Pipe()
+	writer, err := archive.NewWriter(req.Format, pw)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.createBucketIfNotExists(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	var entries []archiveEntry
+	if len(req.Paths) > 0 {
+		entries, err = s.statArchiveEntries(ctx, req.UserID, req.Paths)
+	} else {
+		entries, err = s.listArchiveEntries(ctx, req.UserID, req.Prefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrEmptyArchive
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.size
+	}
+	if len(entries) > s.archiveConf.MaxFiles || total > int64(s.archiveConf.MaxSize) {
+		return nil, ErrArchiveTooLarge
+	}
+
+	go func() {
+		pw.CloseWithError(s.writeArchive(ctx, req.UserID, writer, entries))
+	}()
+
+	return pr, nil
+}
+
+// listArchiveEntries lists everything under prefix, stopping once there
+// are more files than an archive may have.
+func (s *filesService) listArchiveEntries(ctx context.Context, bucketName, prefix string) ([]archiveEntry, error) {
+	if err := checkPath(prefix); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	entries := make([]archiveEntry, 0)
+	opts := minio.ListObjectsOptions{Prefix: prefix, Recursive: true, WithMetadata: true}
+	for object := range s.minio.ListObjects(ctx, bucketName, opts) {
+		if object.Err != nil {
+			err := fmt.Errorf("failed to list objects: %w", object.Err)
+			slog.Error(err.Error())
+			return nil, err
+		}
+
+		if checkPath(object.Key) != nil || strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+
+		entries = append(entries, newArchiveEntry(object, archiveName(prefix, object.Key)))
+		if len(entries) > s.archiveConf.MaxFiles {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// statArchiveEntries looks up the files at paths, each one once.
+func (s *filesService) statArchiveEntries(ctx context.Context, bucketName string, paths []string) ([]archiveEntry, error) {
+	entries := make([]archiveEntry, 0, len(paths))
+	seen := make(map[string]struct{}, len(paths))
+	for _, filePath := range paths {
+		if err := checkPath(filePath); err != nil {
+			return nil, err
+		}
+
+		if _, ok := seen[filePath]; ok {
+			continue
+		}
+		seen[filePath] = struct{}{}
+
+		info, _, err := s.sse.Stat(ctx, s.minio, bucketName, filePath)
+		if err != nil {
+			err = fmt.Errorf("failed to stat object %s: %w", filePath, err)
+			slog.Error(err.Error())
+			return nil, s.quarantineError(ctx, bucketName, filePath, err)
+		}
+
+		entries = append(entries, newArchiveEntry(info, filePath))
+	}
+
+	return entries, nil
+}
+
+func (s *filesService) writeArchive(ctx context.Context, bucketName string, writer archive.Writer, entries []archiveEntry) error {
+	for _, entry := range entries {
+		if err := s.addToArchive(ctx, bucketName, writer, entry); err != nil {
+			slog.Error("failed to build archive", "bucket", bucketName, "file", entry.key, "error", err)
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+func (s *filesService) addToArchive(ctx context.Context, bucketName string, writer archive.Writer, entry archiveEntry) error {
+	info, encryption, err := s.sse.Stat(ctx, s.minio, bucketName, entry.key)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return ErrArchiveChanged
+		}
+		return fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	if info.ETag != entry.etag {
+		return ErrArchiveChanged
+	}
+
+	content, err := s.openObject(ctx, bucketName, info, encryption, 0, 0)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	return writer.Add(entry.name, entry.size, entry.modified, content)
+}
+
+func newArchiveEntry(info minio.ObjectInfo, name string) archiveEntry {
+	return archiveEntry{
+		key:      info.Key,
+		name:     name,
+		size:     logicalSize(info),
+		etag:     info.ETag,
+		modified: info.LastModified,
+	}
+}
+
+// archiveName is the key from the folder the prefix selects, so that
+// folder is the top level of the archive.
+func archiveName(prefix, key string) string {
+	parent := path.Dir(strings.TrimSuffix(prefix, "/"))
+	if parent == "." {
+		return key
+	}
+
+	return strings.TrimPrefix(key, parent+"/")
+}
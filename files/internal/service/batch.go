@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/internal/events"
+)
+
+// Batch runs the request's items and reports each outcome as it's known;
+// report is never called concurrently. Consecutive deletes are removed
+// together, other consecutive items run at once with up to Workers at a
+// time, so items should only depend on items of an earlier run. With
+// StopOnError the items run one by one and those after a failure are
+// skipped. A dry run only checks what would fail: invalid paths and missing
+// sources.
+func (s *filesService) Batch(ctx context.Context, req dto.BatchRequest, report func(dto.BatchResult) error) error {
+	if len(req.Items) > s.batchConf.MaxItems {
+		return ErrBatchTooLarge
+	}
+
+	if err := s.createBucketIfNotExists(ctx, req.UserID); err != nil {
+		return err
+	}
+
+	var (
+		mu        sync.Mutex
+		failed    bool
+		reportErr error
+	)
+	send := func(result dto.BatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = failed || result.Err != nil
+		if reportErr == nil {
+			reportErr = report(result)
+		}
+	}
+
+	for start := 0; start < len(req.Items) && reportErr == nil; {
+		end := start + 1
+		for !req.StopOnError && end < len(req.Items) && isDelete(req.Items[end]) == isDelete(req.Items[start]) {
+			end++
+		}
+		run := req.Items[start:end]
+
+		switch {
+		case failed && req.StopOnError:
+			for i := range run {
+				send(dto.BatchResult{Index: start + i, Skipped: true})
+			}
+		case req.DryRun:
+			s.parallel(len(run), func(i int) {
+				send(dto.BatchResult{Index: start + i, Err: s.checkBatchItem(ctx, req.UserID, run[i])})
+			})
+		case isDelete(run[0]):
+			paths := make([]string, len(run))
+			for i, item := range run {
+				paths[i] = item.FilePath
+			}
+			for i, err := range s.removeFiles(ctx, req.UserID, paths) {
+				send(dto.BatchResult{Index: start + i, Err: err})
+			}
+		default:
+			s.parallel(len(run), func(i int) {
+				send(dto.BatchResult{Index: start + i, Err: s.runBatchItem(ctx, req.UserID, run[i])})
+			})
+		}
+
+		start = end
+	}
+
+	if reportErr != nil {
+		return fmt.Errorf("failed to report batch result: %w", reportErr)
+	}
+
+	slog.Info("Ran batch", "user", req.UserID, "items", len(req.Items), "dryRun", req.DryRun)
+
+	return nil
+}
+
+func isDelete(item dto.BatchItem) bool {
+	return item.Kind == dto.BatchDelete
+}
+
+func (s *filesService) runBatchItem(ctx context.Context, bucketName string, item dto.BatchItem) error {
+	switch item.Kind {
+	case dto.BatchCopy:
+		return s.copyFile(ctx, bucketName, item.FilePath, item.NewFilePath)
+	case dto.BatchMove:
+		return s.MoveFile(ctx, bucketName, item.FilePath, item.NewFilePath)
+	default:
+		return dto.ErrUnknownBatchKind
+	}
+}
+
+// checkBatchItem fails the way running the item would fail before changing
+// anything. Removing a missing file succeeds, like RemoveFile does.
+func (s *filesService) checkBatchItem(ctx context.Context, bucketName string, item dto.BatchItem) error {
+	switch item.Kind {
+	case dto.BatchDelete:
+		if item.FilePath == "" {
+			return dto.ErrEmptyFilePath
+		}
+		return checkPath(item.FilePath)
+	case dto.BatchCopy, dto.BatchMove:
+		if err := checkTransfer(item.FilePath, item.NewFilePath); err != nil {
+			return err
+		}
+		if _, _, err := s.sse.Stat(ctx, s.minio, bucketName, item.FilePath); err != nil {
+			return s.quarantineError(ctx, bucketName, item.FilePath, fmt.Errorf("failed to stat object: %w", err))
+		}
+		return nil
+	default:
+		return dto.ErrUnknownBatchKind
+	}
+}
+
+// parallel calls fn for 0 to n-1 with up to Workers calls at a time.
+func (s *filesService) parallel(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, max(1, s.batchConf.Workers))
+	for i := range n {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			fn(i)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// removeFiles removes the files like RemoveFile, all with one
+// RemoveObjects, and returns the error of each path.
+func (s *filesService) removeFiles(ctx context.Context, bucketName string, paths []string) []error {
+	errs := make([]error, len(paths))
+
+	// the keys of each valid path and the path of each key
+	keys := make([][]string, len(paths))
+	owners := make(map[string]int)
+	for i, filePath := range paths {
+		if filePath == "" {
+			errs[i] = dto.ErrEmptyFilePath
+			continue
+		}
+		if errs[i] = checkPath(filePath); errs[i] != nil {
+			continue
+		}
+
+		keys[i] = []string{pendingPrefix + filePath, infectedPrefix + filePath, filePath}
+		for _, key := range keys[i] {
+			owners[key] = i
+		}
+	}
+
+	// references are swapped under s.refs, see replaceObject
+	s.refs.Lock()
+	var blobsMu sync.Mutex
+	blobs := make(map[string]string)
+	s.parallel(len(paths), func(i int) {
+		for _, key := range keys[i] {
+			blob, err := s.referencedBlob(ctx, bucketName, key)
+			blobsMu.Lock()
+			if err != nil && errs[i] == nil {
+				errs[i] = err
+			}
+			if blob != "" {
+				blobs[key] = blob
+			}
+			blobsMu.Unlock()
+		}
+	})
+
+	objects := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objects)
+		for i := range paths {
+			if errs[i] != nil {
+				continue
+			}
+			for _, key := range keys[i] {
+				objects <- minio.ObjectInfo{Key: key}
+			}
+		}
+	}()
+
+	for rErr := range s.minio.RemoveObjects(ctx, bucketName, objects, minio.RemoveObjectsOptions{}) {
+		i := owners[rErr.ObjectName]
+		delete(blobs, rErr.ObjectName)
+		if errs[i] == nil {
+			errs[i] = fmt.Errorf("failed to remove object %s: %w", rErr.ObjectName, rErr.Err)
+		}
+	}
+	s.refs.Unlock()
+
+	for key, blob := range blobs {
+		if errs[owners[key]] == nil {
+			s.release(ctx, blob)
+		}
+	}
+
+	s.parallel(len(paths), func(i int) {
+		if keys[i] == nil || errs[i] != nil {
+			return
+		}
+
+		s.dropDerivatives(ctx, bucketName, paths[i])
+		s.events.Publish(events.Event{
+			Kind:   events.KindRemoved,
+			Bucket: bucketName,
+			Key:    paths[i],
+			Actor:  bucketName,
+			Time:   time.Now().UTC(),
+		})
+	})
+
+	return errs
+}
+
+// copyFile copies the file to newFilePath, which counts as an upload of the
+// copy.
+func (s *filesService) copyFile(ctx context.Context, bucketName, filePath, newFilePath string) error {
+	if err := checkTransfer(filePath, newFilePath); err != nil {
+		return err
+	}
+
+	info, err := s.copyObject(ctx, bucketName, filePath, newFilePath)
+	if err != nil {
+		slog.Error(err.Error())
+		return s.quarantineError(ctx, bucketName, filePath, err)
+	}
+
+	slog.Info("Copied file: " + filePath + " -> " + newFilePath)
+	s.uploaded(ctx, bucketName, newFilePath, logicalSize(info), info.ETag)
+
+	return nil
+}
+
+// copyObject copies the object, a blob reference taking another reference
+// before it's copied.
+func (s *filesService) copyObject(ctx context.Context, bucketName, from, to string) (minio.ObjectInfo, error) {
+	var info minio.ObjectInfo
+	err := s.replaceObject(ctx, bucketName, to, func() error {
+		source, sourceKey, err := s.sse.Stat(ctx, s.minio, bucketName, from)
+		if err != nil {
+			return fmt.Errorf("failed to stat object: %w", err)
+		}
+
+		blob := metaValue(source.UserMetadata, metaBlob)
+		if blob != "" {
+			if err = s.blobs.Acquire(ctx, blob); err != nil {
+				return err
+			}
+		}
+
+		if info, err = s.duplicate(ctx, bucketName, source, sourceKey, to, nil); err != nil && blob != "" {
+			s.release(ctx, blob)
+		}
+
+		return err
+	})
+
+	return info, err
+}
@@ -0,0 +1,24 @@
+package service
+
+import "github.com/avran02/decoplan/files/internal/dto"
+
+// Object metadata keys of the file digest, stored as X-Amz-Meta-Sha256 and
+// X-Amz-Meta-Crc32c.
+const (
+	metaSHA256 = "Sha256"
+	metaCRC32C = "Crc32c"
+)
+
+func digestMetadata(d dto.Digest) map[string]string {
+	return map[string]string{
+		metaSHA256: d.SHA256,
+		metaCRC32C: d.CRC32C,
+	}
+}
+
+func digestFromMetadata(meta map[string]string) dto.Digest {
+	return dto.Digest{
+		SHA256: meta[metaSHA256],
+		CRC32C: meta[metaCRC32C],
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/avran02/decoplan/files/internal/dto"
+)
+
+// Object metadata keys of a reference to a deduplicated blob. The object
+// itself is empty; Blob is the SHA-256 of the content and Size its length.
+const (
+	metaBlob = "Blob"
+	metaSize = "Size"
+)
+
+// uploadDeduplicated stores the content in the blob store and the file as a
+// reference to it at key. The blob reference is taken before the file is
+// written, so a crash in between leaks a reference rather than losing content.
+func (s *filesService) uploadDeduplicated(
+	ctx context.Context, req *dto.UploadFileStreamRequest, key string, content io.Reader, contentType string,
+) (dto.FileStat, error) {
+	staged, size, err := s.blobs.Stage(ctx, content)
+	if err != nil {
+		slog.Error(err.Error())
+		return dto.FileStat{}, fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	digest := req.Digest()
+	if req.Expected != nil && !req.Expected.Matches(digest) {
+		slog.Warn("checksum mismatch", "file", req.FilePath, "algorithm", req.Expected.Algorithm,
+			"expected", req.Expected.Value, "actual", digest.Get(req.Expected.Algorithm))
+		if err = s.blobs.Discard(ctx, staged); err != nil {
+			slog.Error("failed to remove corrupted upload", "file", req.FilePath, "error", err)
+		}
+		return dto.FileStat{}, ErrChecksumMismatch
+	}
+
+	if err = s.blobs.Commit(ctx, staged, digest.SHA256); err != nil {
+		slog.Error(err.Error())
+		return dto.FileStat{}, fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	meta := digestMetadata(digest)
+	meta[metaBlob] = digest.SHA256
+	meta[metaSize] = strconv.FormatInt(size, 10)
+
+	var info minio.UploadInfo
+	err = s.replaceObject(ctx, req.UserID, key, func() error {
+		info, err = s.minio.PutObject(ctx, req.UserID, key, strings.NewReader(""), 0, minio.PutObjectOptions{
+			ContentType:          contentType,
+			UserMetadata:         meta,
+			ServerSideEncryption: s.sse.ForWrite(req.UserID),
+		})
+		return err
+	})
+	if err != nil {
+		s.release(ctx, digest.SHA256)
+		err = fmt.Errorf("failed to write file reference: %w", err)
+		slog.Error(err.Error())
+		return dto.FileStat{}, err
+	}
+
+	s.uploaded(ctx, req.UserID, key, size, info.ETag)
+
+	return dto.FileStat{
+		Name:         req.FilePath,
+		Size:         size,
+		LastModified: info.LastModified,
+		ETag:         info.ETag,
+		ContentType:  contentType,
+		Digest:       digest,
+	}, nil
+}
+
+// replaceObject runs write, which replaces or removes the object at key, and
+// then releases the blob the old object referenced. References are swapped
+// under s.refs, so concurrent writes to one path can't release a blob twice.
+func (s *filesService) replaceObject(ctx context.Context, bucketName, key string, write func() error) error {
+	s.refs.Lock()
+	previous, err := s.referencedBlob(ctx, bucketName, key)
+	if err == nil {
+		err = write()
+	}
+	s.refs.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if previous != "" {
+		s.release(ctx, previous)
+	}
+
+	return nil
+}
+
+// referencedBlob returns the blob the object references, or "" if it's a
+// plain object or doesn't exist.
+func (s *filesService) referencedBlob(ctx context.Context, bucketName, key string) (string, error) {
+	info, _, err := s.sse.Stat(ctx, s.minio, bucketName, key)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	return metaValue(info.UserMetadata, metaBlob), nil
+}
+
+// release drops a blob reference. A failure only leaks the blob, so it's
+// logged rather than failing the request.
+func (s *filesService) release(ctx context.Context, hash string) {
+	if err := s.blobs.Release(ctx, hash); err != nil {
+		slog.Error("failed to release blob", "hash", hash, "error", err)
+	}
+}
+
+// GetUsage sums the user's files. A blob the user references several times
+// counts once towards the physical bytes, whether or not other users
+// reference it too.
+func (s *filesService) GetUsage(ctx context.Context, bucketName string) (dto.Usage, error) {
+	var usage dto.Usage
+	exists, err := s.buckets.Exists(ctx, bucketName)
+	if err != nil {
+		slog.Error(err.Error())
+		return dto.Usage{}, err
+	}
+	if !exists {
+		return usage, nil
+	}
+
+	blobs := make(map[string]struct{})
+	objects := s.minio.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Recursive: true, WithMetadata: true})
+	for object := range objects {
+		if object.Err != nil {
+			if minio.ToErrorResponse(object.Err).Code == "NoSuchBucket" {
+				return dto.Usage{}, nil
+			}
+			err := fmt.Errorf("failed to list objects: %w", object.Err)
+			slog.Error(err.Error())
+			return dto.Usage{}, err
+		}
+
+		// derivatives and staged uploads take up space, but aren't the
+		// user's files
+		if strings.HasPrefix(object.Key, derivativesPrefix) || strings.HasPrefix(object.Key, uploadsPrefix) {
+			usage.PhysicalBytes += object.Size
+			continue
+		}
+
+		size := logicalSize(object)
+		usage.Files++
+		usage.LogicalBytes += size
+
+		blob := metaValue(object.UserMetadata, metaBlob)
+		if blob == "" {
+			usage.PhysicalBytes += object.Size
+			continue
+		}
+
+		if _, ok := blobs[blob]; !ok {
+			blobs[blob] = struct{}{}
+			usage.PhysicalBytes += size
+		}
+	}
+
+	return usage, nil
+}
+
+// logicalSize is the size of the file the object stands for, which for a
+// blob reference is the size of the blob and for an envelope encrypted
+// object the size before encryption.
+func logicalSize(info minio.ObjectInfo) int64 {
+	if header, ok, err := envelopeHeader(info.UserMetadata); ok && err == nil {
+		return header.PlaintextSize(info.Size)
+	}
+
+	if metaValue(info.UserMetadata, metaBlob) == "" {
+		return info.Size
+	}
+
+	size, err := strconv.ParseInt(metaValue(info.UserMetadata, metaSize), 10, 64)
+	if err != nil {
+		slog.Warn("invalid blob reference size", "object", info.Key, "error", err)
+		return info.Size
+	}
+
+	return size
+}
+
+// metaValue looks up user metadata. Stat strips the X-Amz-Meta- prefix from
+// the keys, while listings with metadata keep it.
+func metaValue(meta map[string]string, key string) string {
+	if v, ok := meta[key]; ok {
+		return v
+	}
+
+	return meta["X-Amz-Meta-"+key]
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+
+	"github.com/avran02/decoplan/files/internal/envelope"
+)
+
+// readCloser reads the decrypted content and closes the object beneath.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// openEnvelope fetches only the chunks covering the range and decrypts
+// them.
+func (s *filesService) openEnvelope(
+	ctx context.Context, bucketName, filePath string, info minio.ObjectInfo, header envelope.Header, encryption encrypt.ServerSide, offset, length int64,
+) (io.ReadCloser, error) {
+	if s.keyring == nil {
+		return nil, envelope.ErrNoKeyring
+	}
+
+	span, err := header.Span(info.Size, offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	if span.Length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	opts := minio.GetObjectOptions{ServerSideEncryption: encryption}
+	if err = opts.SetRange(span.Start, span.End); err != nil {
+		return nil, fmt.Errorf("failed to set range: %w", err)
+	}
+	if err = opts.SetMatchETag(info.ETag); err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	o, err := s.minio.GetObject(ctx, bucketName, filePath, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	plain, err := s.keyring.Open(o, header, span)
+	if err != nil {
+		o.Close()
+		return nil, fmt.Errorf("failed to decrypt file: %w", err)
+	}
+
+	return readCloser{Reader: plain, Closer: o}, nil
+}
+
+// envelopeHeader reads the envelope header from object metadata, see
+// envelope.ParseHeader.
+func envelopeHeader(meta map[string]string) (envelope.Header, bool, error) {
+	return envelope.ParseHeader(func(key string) string {
+		return metaValue(meta, key)
+	})
+}
+
+// rangeOptions requests length bytes from offset, a zero length meaning to
+// the end.
+func rangeOptions(offset, length int64) (minio.GetObjectOptions, error) {
+	var (
+		opts minio.GetObjectOptions
+		err  error
+	)
+	switch {
+	case offset < 0 || length < 0:
+		return opts, ErrInvalidRange
+	case length > 0:
+		err = opts.SetRange(offset, offset+length-1)
+	case offset > 0:
+		err = opts.SetRange(offset, 0)
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		return opts, ErrInvalidRange
+	}
+
+	return opts, nil
+}
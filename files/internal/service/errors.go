@@ -2,4 +2,29 @@ package service
 
 import "errors"
 
-var ErrorBucketExists = errors.New("bucket already exists")
+var (
+	ErrorBucketExists = errors.New("bucket already exists")
+	ErrSameFilePath   = errors.New("source and destination paths are the same")
+	ErrInvalidRange   = errors.New("offset and length must not be negative")
+
+	ErrInvalidThumbnailSize = errors.New("invalid thumbnail size")
+
+	ErrInvalidArchiveSelection = errors.New("archive files are selected by either prefix or paths")
+	ErrEmptyArchive            = errors.New("no files to archive")
+	ErrArchiveTooLarge         = errors.New("archive exceeds the size or file count limit")
+	ErrArchiveChanged          = errors.New("files changed while the archive was built")
+	ErrNotRegularFile          = errors.New("only regular files are extracted")
+
+	ErrBatchTooLarge = errors.New("batch has too many items")
+
+	ErrChecksumMismatch   = errors.New("uploaded file doesn't match its checksum")
+	ErrUploadSizeMismatch = errors.New("uploaded file doesn't match its declared size")
+	ErrNoUpload           = errors.New("no upload to this path is running")
+
+	ErrReservedPath = errors.New("path is reserved for the service's own files")
+	ErrFileScanning = errors.New("file is still being scanned for malware")
+	ErrFileInfected = errors.New("file is infected")
+
+	ErrInvalidWebhookURL = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidEventKind  = errors.New("invalid file event kind")
+)
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/avran02/decoplan/files/internal/archive"
+	"github.com/avran02/decoplan/files/internal/dto"
+)
+
+// ExtractArchive stores the files of the uploaded archive under the folder
+// at the request's path, "/" being the top level. The archive is spooled to
+// a temporary file and checked against the expected checksum before anything
+// is extracted. Each file goes through UploadFile, so it's checked, scanned
+// and encrypted like any other upload.
+//
+// Entries with unsafe paths, links and files UploadFile refuses are skipped
+// with their error. The files and bytes extracted are capped like archive
+// downloads, by what's actually read rather than what the archive claims;
+// extraction stops at the entry that hits the cap.
+func (s *filesService) ExtractArchive(
+	ctx context.Context, req *dto.UploadFileStreamRequest, format archive.Format,
+) (dto.Digest, []dto.ExtractedEntry, error) {
+	dest := strings.Trim(req.FilePath, "/")
+	if dest != "" {
+		dest += "/"
+	}
+
+	if err := checkPath(dest); err != nil {
+		return dto.Digest{}, nil, err
+	}
+
+	spool, size, err := s.spoolArchive(req)
+	if err != nil {
+		return dto.Digest{}, nil, err
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+
+	digest := req.Digest()
+	if req.Expected != nil && !req.Expected.Matches(digest) {
+		slog.Warn("checksum mismatch", "file", req.FilePath, "algorithm", req.Expected.Algorithm,
+			"expected", req.Expected.Value, "actual", digest.Get(req.Expected.Algorithm))
+		return dto.Digest{}, nil, ErrChecksumMismatch
+	}
+
+	reader, err := archive.NewReader(format, spool, size)
+	if err != nil {
+		return dto.Digest{}, nil, err
+	}
+	defer reader.Close()
+
+	budget := int64(s.archiveConf.MaxSize)
+	results := make([]dto.ExtractedEntry, 0)
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			slog.Error(err.Error())
+			return dto.Digest{}, nil, err
+		}
+
+		if entry.Mode.IsDir() {
+			continue
+		}
+
+		result := dto.ExtractedEntry{Path: entry.Name}
+		name, err := archive.CleanName(entry.Name)
+		switch {
+		case err != nil:
+			result.Err = err
+		case !entry.Mode.IsRegular():
+			result.Err = ErrNotRegularFile
+		case len(results) == s.archiveConf.MaxFiles:
+			result.Err = ErrArchiveTooLarge
+		default:
+			result.Path = dest + name
+			result.Size, result.Digest, result.Err = s.extractEntry(ctx, req.UserID, result.Path, entry, &budget)
+		}
+
+		if result.Err != nil {
+			slog.Warn("failed to extract archive entry", "archive", req.FilePath, "entry", entry.Name, "error", result.Err)
+		}
+		results = append(results, result)
+
+		if errors.Is(result.Err, ErrArchiveTooLarge) {
+			break
+		}
+	}
+
+	slog.Info("Extracted archive: " + req.FilePath)
+
+	return digest, results, nil
+}
+
+// spoolArchive writes the upload to a temporary file, refusing archives
+// over the extraction cap or of another size than declared.
+func (s *filesService) spoolArchive(req *dto.UploadFileStreamRequest) (*os.File, int64, error) {
+	spool, err := os.CreateTemp("", "extract-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create spool file: %w", err)
+	}
+
+	size, err := io.Copy(spool, io.LimitReader(req, int64(s.archiveConf.MaxSize)+1))
+	if err == nil && size > int64(s.archiveConf.MaxSize) {
+		err = ErrArchiveTooLarge
+	}
+	if err == nil && req.Size > 0 && size != req.Size {
+		err = ErrUploadSizeMismatch
+	}
+	if err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		if !errors.Is(err, ErrArchiveTooLarge) && !errors.Is(err, ErrUploadSizeMismatch) {
+			err = fmt.Errorf("failed to spool archive: %w", err)
+		}
+		return nil, 0, err
+	}
+
+	return spool, size, nil
+}
+
+// extractEntry uploads the entry to filePath, taking what it reads from the
+// budget. An entry over the budget isn't stored.
+func (s *filesService) extractEntry(
+	ctx context.Context, userID, filePath string, entry archive.Entry, budget *int64,
+) (int64, dto.Digest, error) {
+	if entry.Size > *budget {
+		return 0, dto.Digest{}, ErrArchiveTooLarge
+	}
+
+	req, err := dto.NewUploadFileStreamRequest(userID, filePath, nil, entry.Size, s.streamConf.ReadAhead)
+	if err != nil {
+		return 0, dto.Digest{}, err
+	}
+
+	var size int64
+	copyErrChan := make(chan error, 1)
+	go func() {
+		defer close(copyErrChan)
+		n, err := io.Copy(req, io.LimitReader(entry.Content, *budget+1))
+		size = n
+		if err == nil && n > *budget {
+			err = ErrArchiveTooLarge
+		}
+		if err != nil {
+			req.CloseWriterWithError(err)
+			copyErrChan <- err
+			return
+		}
+		req.CloseWriter()
+	}()
+
+	stat, uploadErr := s.UploadFile(ctx, req)
+	// unblocks the copy if the upload gave up early
+	req.CloseReader()
+	copyErr := <-copyErrChan
+	*budget -= size
+
+	switch {
+	case errors.Is(copyErr, ErrArchiveTooLarge):
+		return size, dto.Digest{}, copyErr
+	case uploadErr != nil:
+		return size, dto.Digest{}, uploadErr
+	case copyErr != nil:
+		return size, dto.Digest{}, fmt.Errorf("%w: %w", archive.ErrInvalidArchive, copyErr)
+	}
+
+	return size, stat.Digest, nil
+}
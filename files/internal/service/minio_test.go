@@ -0,0 +1,285 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/avran02/decoplan/files/internal/config"
+)
+
+type fakeObject struct {
+	data []byte
+	meta http.Header
+	etag string
+}
+
+// fakeS3 keeps objects and multipart uploads in memory, enough of S3 for
+// the uploads and copies the service makes.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	uploads map[string]*fakeUpload
+	nextID  int
+}
+
+// fakeUpload is a multipart upload, which takes the metadata it was started
+// with.
+type fakeUpload struct {
+	parts  map[int][]byte
+	header http.Header
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *minio.Client) {
+	t.Helper()
+
+	fake := &fakeS3{objects: make(map[string]fakeObject), uploads: make(map[string]*fakeUpload)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client, err := minio.New(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("access", "secret", ""),
+		Region: config.DefaultLocation,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fake, client
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.uploads[id] = &fakeUpload{parts: make(map[int][]byte), header: r.Header}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			UploadID string   `xml:"UploadId"`
+		}{UploadID: id})
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		upload, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			http.Error(w, "no such upload", http.StatusNotFound)
+			return
+		}
+		parts := upload.parts
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if r.Header.Get("X-Amz-Copy-Source") == "" {
+			parts[number] = readBody(r)
+			w.Header().Set("ETag", etag(parts[number]))
+			return
+		}
+
+		src, ok := f.copySource(w, r)
+		if !ok {
+			return
+		}
+		data := src.data
+		if first, last, ok := parseRange(r.Header.Get("X-Amz-Copy-Source-Range")); ok {
+			data = data[first : last+1]
+		}
+		parts[number] = data
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyPartResult"`
+			ETag         string
+			LastModified string
+		}{ETag: etag(data), LastModified: time.Now().UTC().Format(time.RFC3339)})
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		upload, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			http.Error(w, "no such upload", http.StatusNotFound)
+			return
+		}
+		parts := upload.parts
+		delete(f.uploads, query.Get("uploadId"))
+		numbers := make([]int, 0, len(parts))
+		for number := range parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		var data []byte
+		for _, number := range numbers {
+			data = append(data, parts[number]...)
+		}
+		obj := f.store(key, data, upload.header)
+		bucket, object, _ := strings.Cut(key, "/")
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: bucket, Key: object, ETag: obj.etag})
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		src, ok := f.copySource(w, r)
+		if !ok {
+			return
+		}
+		header := src.meta
+		if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+			header = r.Header
+		}
+		obj := f.store(key, src.data, header)
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			ETag         string
+			LastModified string
+		}{ETag: obj.etag, LastModified: time.Now().UTC().Format(time.RFC3339)})
+	case r.Method == http.MethodPut:
+		obj := f.store(key, readBody(r), r.Header)
+		w.Header().Set("ETag", obj.etag)
+	case r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for k, v := range obj.meta {
+			w.Header()[k] = v
+		}
+		w.Header().Set("ETag", obj.etag)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// copySource looks up the source of a copy, failing the request if it's
+// missing or its ETag doesn't match.
+func (f *fakeS3) copySource(w http.ResponseWriter, r *http.Request) (fakeObject, bool) {
+	source, _ := url.PathUnescape(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"))
+	src, ok := f.objects[source]
+	if !ok {
+		http.Error(w, "no such key", http.StatusNotFound)
+		return fakeObject{}, false
+	}
+
+	if match := r.Header.Get("X-Amz-Copy-Source-If-Match"); match != "" && strings.Trim(match, `"`) != strings.Trim(src.etag, `"`) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return fakeObject{}, false
+	}
+
+	return src, true
+}
+
+// parseRange parses "bytes=first-last".
+func parseRange(header string) (int, int, bool) {
+	first, last, ok := strings.Cut(strings.TrimPrefix(header, "bytes="), "-")
+	if !ok {
+		return 0, 0, false
+	}
+
+	a, errA := strconv.Atoi(first)
+	b, errB := strconv.Atoi(last)
+	return a, b, errA == nil && errB == nil
+}
+
+// store keeps the content type and user metadata of header with data.
+func (f *fakeS3) store(key string, data []byte, header http.Header) fakeObject {
+	meta := make(http.Header)
+	for k, v := range header {
+		if strings.HasPrefix(k, "X-Amz-Meta-") || k == "Content-Type" {
+			meta[k] = v
+		}
+	}
+
+	obj := fakeObject{data: data, meta: meta, etag: etag(data)}
+	f.objects[key] = obj
+
+	return obj
+}
+
+func (f *fakeS3) object(key string) (fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj, ok := f.objects[key]
+	return obj, ok
+}
+
+// keys lists the stored objects and the multipart uploads still open.
+func (f *fakeS3) keys() ([]string, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, len(f.uploads)
+}
+
+// readBody undoes the aws-chunked encoding MinIO signs streamed bodies with
+// on plain HTTP.
+func readBody(r *http.Request) []byte {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		data, _ := io.ReadAll(r.Body)
+		return data
+	}
+
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return data
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil || size == 0 {
+			return data
+		}
+		chunk := make([]byte, size)
+		if _, err = io.ReadFull(br, chunk); err != nil {
+			return data
+		}
+		data = append(data, chunk...)
+		br.Discard(2)
+	}
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	var buf bytes.Buffer
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		panic(fmt.Sprint("encoding fake S3 response: ", err))
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(buf.Bytes())
+}
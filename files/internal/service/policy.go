@@ -0,0 +1,44 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/internal/policy"
+)
+
+// admit sniffs the type of the upload from its first bytes and checks it
+// against the policy of the space, before anything is stored. It returns
+// the whole content again, cut off with an error past the size limit of the
+// type. A declared size over the limit is refused right away.
+func (s *filesService) admit(req *dto.UploadFileStreamRequest) (io.Reader, string, error) {
+	head := make([]byte, policy.SniffLen)
+	n, err := io.ReadFull(req, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	head = head[:n]
+
+	contentType := policy.Detect(head)
+	limit, err := s.policy.Check(req.UserID, contentType)
+	if err != nil {
+		slog.Warn("upload rejected", "bucket", req.UserID, "file", req.FilePath, "type", contentType, "error", err)
+		return nil, "", err
+	}
+
+	if limit > 0 && req.Size > limit {
+		slog.Warn("upload rejected", "bucket", req.UserID, "file", req.FilePath, "type", contentType, "size", req.Size)
+		return nil, "", policy.ErrFileTooLarge
+	}
+
+	var body io.Reader = io.MultiReader(bytes.NewReader(head), req)
+	if limit > 0 {
+		body = policy.LimitReader(body, limit)
+	}
+
+	return body, contentType, nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/avran02/decoplan/files/internal/events"
+	"github.com/avran02/decoplan/files/pb"
+)
+
+// While scanning is on, uploads land under pendingPrefix of the user's
+// bucket with their path appended. A clean file is moved to its path, an
+// infected one under infectedPrefix, where it stays until removed.
+const (
+	quarantinePrefix = ".quarantine/"
+	pendingPrefix    = quarantinePrefix + "pending/"
+	infectedPrefix   = quarantinePrefix + "infected/"
+
+	// metaThreat is the object metadata key of what the scanner found.
+	metaThreat = "Scan-Threat"
+)
+
+// uploadKey is where an upload to filePath is written.
+func (s *filesService) uploadKey(filePath string) string {
+	if s.scanner == nil {
+		return filePath
+	}
+
+	return pendingPrefix + filePath
+}
+
+// uploaded announces a stored upload. A quarantined upload is announced
+// once the scanner promoted it.
+func (s *filesService) uploaded(ctx context.Context, bucketName, key string, size int64, etag string) {
+	if strings.HasPrefix(key, pendingPrefix) {
+		slog.Info("Quarantined upload for scanning: " + key)
+		select {
+		case s.scanKick <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	slog.Info("Uploaded file: " + key)
+	s.dropDerivatives(ctx, bucketName, key)
+	s.events.Publish(events.Event{
+		Kind:   events.KindUploaded,
+		Bucket: bucketName,
+		Key:    key,
+		Size:   size,
+		ETag:   etag,
+		Actor:  bucketName,
+		Time:   time.Now().UTC(),
+	})
+}
+
+// RunScans scans quarantined uploads of every bucket, after each upload and
+// every scan interval. A file the scanner fails on stays pending and is
+// tried again with the next round.
+func (s *filesService) RunScans(ctx context.Context) {
+	if s.scanner == nil {
+		return
+	}
+
+	slog.Info("starting upload scanner")
+
+	ticker := time.NewTicker(s.scanConf.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.scanPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.scanKick:
+		}
+	}
+}
+
+// scanPending scans everything pending with up to Workers scans at once
+// and returns when all are done, so no file is scanned twice at a time.
+func (s *filesService) scanPending(ctx context.Context) {
+	all, err := s.minio.ListBuckets(ctx)
+	if err != nil {
+		slog.Error("failed to list buckets for scanning", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, max(1, s.scanConf.Workers))
+	for _, bucket := range all {
+		opts := minio.ListObjectsOptions{Prefix: pendingPrefix, Recursive: true}
+		for object := range s.minio.ListObjects(ctx, bucket.Name, opts) {
+			if object.Err != nil {
+				slog.Error("failed to list quarantine", "bucket", bucket.Name, "error", object.Err)
+				break
+			}
+
+			workers <- struct{}{}
+			wg.Add(1)
+			go func(bucketName, key string) {
+				defer wg.Done()
+				defer func() { <-workers }()
+				s.scan(ctx, bucketName, key)
+			}(bucket.Name, object.Key)
+		}
+	}
+
+	wg.Wait()
+}
+
+func (s *filesService) scan(ctx context.Context, bucketName, key string) {
+	ctx, cancel := context.WithTimeout(ctx, s.scanConf.Timeout)
+	defer cancel()
+
+	content, _, err := s.openFile(ctx, bucketName, key, 0, 0)
+	if err != nil {
+		slog.Warn("failed to open quarantined file", "bucket", bucketName, "key", key, "error", err)
+		return
+	}
+
+	verdict, err := s.scanner.Scan(ctx, content)
+	content.Close()
+	if err != nil {
+		slog.Warn("failed to scan file", "bucket", bucketName, "key", key, "error", err)
+		return
+	}
+
+	filePath := strings.TrimPrefix(key, pendingPrefix)
+	if verdict.Infected {
+		slog.Warn("infected upload", "bucket", bucketName, "file", filePath, "threat", verdict.Threat)
+		_, err = s.moveObject(ctx, bucketName, key, infectedPrefix+filePath, map[string]string{metaThreat: verdict.Threat})
+		if err != nil {
+			slog.Error("failed to move infected file", "bucket", bucketName, "file", filePath, "error", err)
+		}
+		return
+	}
+
+	info, err := s.moveObject(ctx, bucketName, key, filePath, nil)
+	if err != nil {
+		slog.Error("failed to promote scanned file", "bucket", bucketName, "file", filePath, "error", err)
+		return
+	}
+
+	s.uploaded(ctx, bucketName, filePath, logicalSize(info), info.ETag)
+}
+
+// quarantineError explains a missing file that's still quarantined, other
+// errors are returned as they are.
+func (s *filesService) quarantineError(ctx context.Context, bucketName, filePath string, err error) error {
+	var resp minio.ErrorResponse
+	if !errors.As(err, &resp) || resp.Code != "NoSuchKey" {
+		return err
+	}
+
+	if _, _, sErr := s.sse.Stat(ctx, s.minio, bucketName, pendingPrefix+filePath); sErr == nil {
+		return ErrFileScanning
+	}
+
+	if _, _, sErr := s.sse.Stat(ctx, s.minio, bucketName, infectedPrefix+filePath); sErr == nil {
+		return ErrFileInfected
+	}
+
+	return err
+}
+
+// listQuarantine lists the files of dir quarantined under prefix by their
+// own paths. Directories have no status.
+func (s *filesService) listQuarantine(ctx context.Context, bucketName, dir, prefix string) ([]*pb.FileInfo, error) {
+	status := pb.FileStatus_FILE_STATUS_SCANNING
+	if prefix == infectedPrefix {
+		status = pb.FileStatus_FILE_STATUS_INFECTED
+	}
+
+	files := make([]*pb.FileInfo, 0)
+	opts := minio.ListObjectsOptions{Prefix: prefix + dir, WithMetadata: true}
+	for object := range s.minio.ListObjects(ctx, bucketName, opts) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list quarantine: %w", object.Err)
+		}
+
+		file := &pb.FileInfo{
+			Name:         strings.TrimPrefix(object.Key, prefix),
+			Size:         logicalSize(object),
+			LastModified: timestamppb.New(object.LastModified),
+		}
+		if !strings.HasSuffix(file.Name, "/") {
+			file.Status = status
+			file.Threat = metaValue(object.UserMetadata, metaThreat)
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
@@ -7,45 +7,104 @@ import (
 	"io"
 	"log"
 	"log/slog"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/avran02/decoplan/files/internal/archive"
+	"github.com/avran02/decoplan/files/internal/blobs"
+	"github.com/avran02/decoplan/files/internal/buckets"
 	"github.com/avran02/decoplan/files/internal/config"
 	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/internal/envelope"
+	"github.com/avran02/decoplan/files/internal/events"
+	"github.com/avran02/decoplan/files/internal/policy"
+	"github.com/avran02/decoplan/files/internal/scan"
+	"github.com/avran02/decoplan/files/internal/sse"
+	"github.com/avran02/decoplan/files/internal/thumbnail"
+	"github.com/avran02/decoplan/files/internal/webhook"
 	"github.com/avran02/decoplan/files/pb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
-)
-
-var (
-	getObjectOptions  = minio.GetObjectOptions{}
-	makeBucketOptions = minio.MakeBucketOptions{
-		Region: config.DefaultLocation,
-	}
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"golang.org/x/sync/semaphore"
 )
 
 type FilesService interface {
 	RegisterUser(ctx context.Context, bucketName string) error
+	UnregisterUser(ctx context.Context, bucketName string) error
 	ListFiles(ctx context.Context, bucketName, dir string) ([]*pb.FileInfo, error)
-	UploadFile(ctx context.Context, req *dto.UploadFileStreamRequest) error
-	DownloadFile(ctx context.Context, bucketName, filePath string) (io.ReadCloser, error)
+	UploadFile(ctx context.Context, req *dto.UploadFileStreamRequest) (dto.FileStat, error)
+	ExtractArchive(ctx context.Context, req *dto.UploadFileStreamRequest, format archive.Format) (dto.Digest, []dto.ExtractedEntry, error)
+	DownloadFile(ctx context.Context, bucketName, filePath string, offset, length int64) (io.ReadCloser, dto.FileStat, error)
+	DownloadArchive(ctx context.Context, req dto.ArchiveRequest) (io.ReadCloser, error)
+	StatFile(ctx context.Context, bucketName, filePath string) (dto.FileStat, error)
 	RemoveFile(ctx context.Context, bucketName, filePath string) error
+	MoveFile(ctx context.Context, bucketName, filePath, newFilePath string) error
+	Batch(ctx context.Context, req dto.BatchRequest, report func(dto.BatchResult) error) error
+	RunScans(ctx context.Context)
+	GetThumbnail(ctx context.Context, bucketName, filePath string, size thumbnail.Size) (thumbnail.Thumbnail, error)
+	RunThumbnails(ctx context.Context)
+	GetUploadProgress(ctx context.Context, bucketName, filePath string) (dto.UploadProgress, error)
+	GetUploadProgressByID(ctx context.Context, uploadID string) (dto.UploadProgress, error)
+	GetUsage(ctx context.Context, bucketName string) (dto.Usage, error)
+	WatchFiles(filter events.Filter) *events.Subscription
+	StopWatching(sub *events.Subscription)
+
+	CreateWebhook(ctx context.Context, userID, url, prefix string, kinds []events.Kind) (webhook.Webhook, error)
+	ListWebhooks(ctx context.Context, userID string) ([]webhook.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID, webhookID string) error
+	TestWebhook(ctx context.Context, userID, webhookID string) (webhook.Result, error)
+	ListWebhookDeliveries(ctx context.Context, userID, webhookID string, pageSize int, pageToken string) (webhook.DeliveryPage, error)
 }
 
 type filesService struct {
-	minio *minio.Client
+	minio         *minio.Client
+	buckets       *buckets.Cache
+	events        *events.Hub
+	webhooks      *webhook.Store
+	sender        *webhook.Sender
+	webhooksConf  config.Webhooks
+	blobs         *blobs.Store
+	dedup         bool
+	sse           *sse.Keys
+	keyring       *envelope.Keyring
+	envelopeConf  config.Envelope
+	policy        *policy.Policy
+	uploadsConf   config.Uploads
+	uploadMemory  *semaphore.Weighted
+	progress      *uploadTracker
+	scanner       scan.Scanner
+	scanConf      config.Scan
+	scanKick      chan struct{}
+	thumbnailConf config.Thumbnails
+	archiveConf   config.Archives
+	batchConf     config.Batch
+	streamConf    config.Streaming
+
+	// refs serializes swapping blob references, see replaceObject
+	refs sync.Mutex
 }
 
+// ListFiles lists dir, including quarantined uploads with their scan status.
+// A file replaced by an upload that's still being scanned is listed twice.
 func (s *filesService) ListFiles(ctx context.Context, bucketName string, dir string) ([]*pb.FileInfo, error) {
 	slog.Info("List files in " + dir)
+	if err := checkPath(dir); err != nil {
+		return nil, err
+	}
+
 	err := s.createBucketIfNotExists(ctx, bucketName)
 	if err != nil && !errors.Is(err, ErrorBucketExists) {
 		return nil, err
 	}
 
 	objChan := s.minio.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
-		Prefix:    dir,
-		Recursive: false,
+		Prefix:       dir,
+		Recursive:    false,
+		WithMetadata: true,
 	})
 
 	files := make([]*pb.FileInfo, 0, len(objChan))
@@ -56,105 +115,530 @@ func (s *filesService) ListFiles(ctx context.Context, bucketName string, dir str
 			return nil, fmt.Errorf("failed to list objects:\n%w", object.Err)
 		}
 
+		if checkPath(object.Key) != nil {
+			continue
+		}
+
 		files = append(files, &pb.FileInfo{
 			Name:         object.Key,
-			Size:         object.Size,
+			Size:         logicalSize(object),
 			LastModified: timestamppb.New(object.LastModified),
 		})
 	}
 
+	dirs := make(map[string]struct{})
+	for _, file := range files {
+		if strings.HasSuffix(file.Name, "/") {
+			dirs[file.Name] = struct{}{}
+		}
+	}
+
+	for _, prefix := range []string{pendingPrefix, infectedPrefix} {
+		quarantined, err := s.listQuarantine(ctx, bucketName, dir, prefix)
+		if err != nil {
+			slog.Error(err.Error())
+			return nil, err
+		}
+
+		for _, file := range quarantined {
+			if _, ok := dirs[file.Name]; ok {
+				continue
+			}
+			if strings.HasSuffix(file.Name, "/") {
+				dirs[file.Name] = struct{}{}
+			}
+			files = append(files, file)
+		}
+	}
+
 	return files, nil
 }
 
 func (s *filesService) RegisterUser(ctx context.Context, bucketName string) error {
-	err := s.createBucketIfNotExists(ctx, bucketName)
-	if err != nil && !errors.Is(err, ErrorBucketExists) {
+	return s.createBucketIfNotExists(ctx, bucketName)
+}
+
+// UnregisterUser removes all objects of the bucket and the bucket itself,
+// releasing the blobs the removed objects referenced. A missing bucket counts
+// as already removed. It asks MinIO rather than the bucket cache, since a
+// bucket left behind would be worse than the extra call.
+func (s *filesService) UnregisterUser(ctx context.Context, bucketName string) error {
+	exists, err := s.minio.BucketExists(ctx, bucketName)
+	if err != nil {
+		err = fmt.Errorf("failed to check if bucket exists: %w", err)
+		slog.Error(err.Error())
 		return err
 	}
 
-	err = s.minio.MakeBucket(ctx, bucketName, makeBucketOptions)
-	if err != nil {
+	if !exists {
+		return nil
+	}
+
+	// written by the listing goroutine, read once RemoveObjects is done
+	references := make(map[string]string)
+	listErrChan := make(chan error, 1)
+	objectsChan := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objectsChan)
+		defer close(listErrChan)
+		opts := minio.ListObjectsOptions{Recursive: true, WithMetadata: true}
+		for object := range s.minio.ListObjects(ctx, bucketName, opts) {
+			if object.Err != nil {
+				listErrChan <- object.Err
+				return
+			}
+			if blob := metaValue(object.UserMetadata, metaBlob); blob != "" {
+				references[object.Key] = blob
+			}
+			select {
+			case objectsChan <- object:
+			case <-ctx.Done():
+				listErrChan <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	var removeErr error
+	failed := make(map[string]struct{})
+	for rErr := range s.minio.RemoveObjects(ctx, bucketName, objectsChan, minio.RemoveObjectsOptions{}) {
+		failed[rErr.ObjectName] = struct{}{}
+		if removeErr == nil {
+			removeErr = fmt.Errorf("failed to remove object %s: %w", rErr.ObjectName, rErr.Err)
+		}
+	}
+
+	listErr := <-listErrChan
+	for key, blob := range references {
+		if _, ok := failed[key]; !ok {
+			s.release(ctx, blob)
+		}
+	}
+
+	if err = errors.Join(listErr, removeErr); err != nil {
+		err = fmt.Errorf("failed to purge bucket: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	if err = s.minio.RemoveBucket(ctx, bucketName); err != nil {
+		err = fmt.Errorf("failed to remove bucket: %w", err)
 		slog.Error(err.Error())
-		return fmt.Errorf("failed to create bucket: %w", err)
+		return err
 	}
+	s.buckets.Forget(bucketName)
+
+	slog.Info("Unregistered user: " + bucketName)
 
 	return nil
 }
 
-func (s *filesService) UploadFile(ctx context.Context, req *dto.UploadFileStreamRequest) error {
+// UploadFile checks the sniffed type against the upload policy, stores the
+// file, then checks it against the checksum the client sent, if any, and
+// records the digest as object metadata. A mismatching file is removed
+// again. With dedup enabled the content goes to the blob store instead, see
+// uploadDeduplicated. With scanning enabled the file is quarantined until
+// the scanner clears it. Uploads of a declared size must match it and may
+// be stored in parallel parts, see putParts.
+func (s *filesService) UploadFile(ctx context.Context, req *dto.UploadFileStreamRequest) (dto.FileStat, error) {
+	if err := checkPath(req.FilePath); err != nil {
+		return dto.FileStat{}, err
+	}
+
 	if err := s.createBucketIfNotExists(ctx, req.UserID); err != nil {
-		return err
+		return dto.FileStat{}, err
+	}
+
+	progress := s.progress.start(req.UserID, req.FilePath, req.UploadID, req.Size)
+	defer s.progress.finish(req.UserID, req.FilePath, progress)
+
+	content, contentType, err := s.admit(req)
+	if err != nil {
+		return dto.FileStat{}, err
 	}
+	content = receivedReader{r: content, progress: progress}
 
-	_, err := s.minio.PutObject(ctx, req.UserID, req.FilePath, req, -1, minio.PutObjectOptions{})
+	key := s.uploadKey(req.FilePath)
+	if s.dedup {
+		return s.uploadDeduplicated(ctx, req, key, content, contentType)
+	}
+
+	// the file may still be a reference from when dedup was enabled
+	previous, err := s.referencedBlob(ctx, req.UserID, key)
 	if err != nil {
-		if errors.Is(err, io.EOF) {
-			slog.Info("closed in EOF block")
-			return nil
+		slog.Error(err.Error())
+		return dto.FileStat{}, err
+	}
+
+	var (
+		body     = content
+		bodySize = int64(-1)
+		meta     = make(map[string]string)
+		header   envelope.Header
+	)
+	if req.Size > 0 {
+		bodySize = req.Size
+	}
+	if s.envelopeConf.Enabled {
+		if body, header, err = s.keyring.Seal(content, s.envelopeConf.ChunkSize); err != nil {
+			slog.Error(err.Error())
+			return dto.FileStat{}, err
+		}
+		meta = header.Metadata()
+		if bodySize > 0 {
+			bodySize = header.SealedSize(bodySize)
 		}
+	}
+
+	// finish runs once the body is read to its end, so every chunk is hashed
+	// by then. A mismatch drops the upload before it replaces the file.
+	var digest dto.Digest
+	finish := func() (map[string]string, error) {
+		digest = req.Digest()
+		if req.Expected != nil && !req.Expected.Matches(digest) {
+			slog.Warn("checksum mismatch", "file", req.FilePath, "algorithm", req.Expected.Algorithm,
+				"expected", req.Expected.Value, "actual", digest.Get(req.Expected.Algorithm))
+			return nil, ErrChecksumMismatch
+		}
+
+		return digestMetadata(digest), nil
+	}
+
+	info, err := s.putObject(ctx, req.UserID, key, body, bodySize, progress, minio.PutObjectOptions{
+		ContentType:          contentType,
+		UserMetadata:         meta,
+		ServerSideEncryption: s.sse.ForWrite(req.UserID),
+	}, finish)
+	if err != nil {
 		slog.Error(err.Error())
-		return fmt.Errorf("failed to upload file: %w", err)
+		return dto.FileStat{}, fmt.Errorf("failed to upload file: %w", err)
 	}
 
-	slog.Info("Uploaded file: " + req.FilePath)
+	if previous != "" {
+		s.release(ctx, previous)
+	}
 
-	return nil
+	size := info.Size
+	if s.envelopeConf.Enabled {
+		size = header.PlaintextSize(size)
+	}
+
+	s.uploaded(ctx, req.UserID, key, size, info.ETag)
+
+	return dto.FileStat{
+		Name:         req.FilePath,
+		Size:         size,
+		LastModified: info.LastModified,
+		ETag:         info.ETag,
+		ContentType:  contentType,
+		Digest:       digest,
+	}, nil
 }
 
-func (s *filesService) DownloadFile(ctx context.Context, bucketName, filePath string) (io.ReadCloser, error) {
+// DownloadFile returns length bytes of the file from offset, all of it for
+// zero values. Envelope encrypted files are decrypted on the way. The digest
+// is always the one of the whole file. Quarantined files can't be downloaded.
+func (s *filesService) DownloadFile(ctx context.Context, bucketName, filePath string, offset, length int64) (io.ReadCloser, dto.FileStat, error) {
+	if err := checkPath(filePath); err != nil {
+		return nil, dto.FileStat{}, err
+	}
+
 	if err := s.createBucketIfNotExists(ctx, bucketName); err != nil {
-		return nil, err
+		return nil, dto.FileStat{}, err
+	}
+
+	content, stat, err := s.openFile(ctx, bucketName, filePath, offset, length)
+	if err != nil {
+		return nil, dto.FileStat{}, s.quarantineError(ctx, bucketName, filePath, err)
 	}
 
-	o, err := s.minio.GetObject(ctx, bucketName, filePath, getObjectOptions)
+	return content, stat, nil
+}
+
+// openFile opens the object at key the way DownloadFile describes. The stat
+// is of the whole file.
+func (s *filesService) openFile(ctx context.Context, bucketName, key string, offset, length int64) (io.ReadCloser, dto.FileStat, error) {
+	info, encryption, err := s.sse.Stat(ctx, s.minio, bucketName, key)
 	if err != nil {
-		err = fmt.Errorf("failed to get object: %w", err)
+		err = fmt.Errorf("failed to stat object: %w", err)
 		slog.Error(err.Error())
+		return nil, dto.FileStat{}, err
+	}
+
+	content, err := s.openObject(ctx, bucketName, info, encryption, offset, length)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, dto.FileStat{}, err
+	}
+
+	return content, fileStat(info), nil
+}
+
+// openObject opens the object version info was stat'd from.
+func (s *filesService) openObject(
+	ctx context.Context, bucketName string, info minio.ObjectInfo, encryption encrypt.ServerSide, offset, length int64,
+) (io.ReadCloser, error) {
+	header, enveloped, err := envelopeHeader(info.UserMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if enveloped {
+		return s.openEnvelope(ctx, bucketName, info.Key, info, header, encryption, offset, length)
+	}
+
+	opts, err := rangeOptions(offset, length)
+	if err != nil {
 		return nil, err
 	}
 
+	if blob := metaValue(info.UserMetadata, metaBlob); blob != "" {
+		return s.blobs.Open(ctx, blob, opts)
+	}
+
+	opts.ServerSideEncryption = encryption
+	if err = opts.SetMatchETag(info.ETag); err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	o, err := s.minio.GetObject(ctx, bucketName, info.Key, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
 	return o, nil
 }
 
+func (s *filesService) StatFile(ctx context.Context, bucketName, filePath string) (dto.FileStat, error) {
+	if err := checkPath(filePath); err != nil {
+		return dto.FileStat{}, err
+	}
+
+	info, _, err := s.sse.Stat(ctx, s.minio, bucketName, filePath)
+	if err != nil {
+		err = fmt.Errorf("failed to stat object: %w", err)
+		slog.Error(err.Error())
+		return dto.FileStat{}, s.quarantineError(ctx, bucketName, filePath, err)
+	}
+
+	return fileStat(info), nil
+}
+
+func fileStat(info minio.ObjectInfo) dto.FileStat {
+	return dto.FileStat{
+		Name:         info.Key,
+		Size:         logicalSize(info),
+		LastModified: info.LastModified,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		Digest:       digestFromMetadata(info.UserMetadata),
+	}
+}
+
+// RemoveFile removes the file along with its quarantined uploads and
+// derivatives, if any.
 func (s *filesService) RemoveFile(ctx context.Context, bucketName, filePath string) error {
+	if err := checkPath(filePath); err != nil {
+		return err
+	}
+
 	if err := s.createBucketIfNotExists(ctx, bucketName); err != nil {
 		return err
 	}
 
-	return s.minio.RemoveObject(ctx, bucketName, filePath, minio.RemoveObjectOptions{})
+	return s.removeFiles(ctx, bucketName, []string{filePath})[0]
 }
 
-func (s *filesService) createBucketIfNotExists(ctx context.Context, bucketName string) error {
-	exists, err := s.minio.BucketExists(ctx, bucketName)
+// MoveFile copies the object to the new path and removes the old one. MinIO
+// has no rename, so a failed removal leaves both copies behind. A blob
+// reference moves along with its metadata and keeps its count; the blob a
+// replaced destination referenced is released.
+func (s *filesService) MoveFile(ctx context.Context, bucketName, filePath, newFilePath string) error {
+	if err := checkTransfer(filePath, newFilePath); err != nil {
+		return err
+	}
+
+	info, err := s.moveObject(ctx, bucketName, filePath, newFilePath, nil)
 	if err != nil {
-		err = fmt.Errorf("failed to check if bucket exists: %w", err)
 		slog.Error(err.Error())
-		return err
+		return s.quarantineError(ctx, bucketName, filePath, err)
 	}
 
-	if !exists {
-		err = s.minio.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: config.DefaultLocation})
+	// the moved file gets its derivatives again from the event
+	s.dropDerivatives(ctx, bucketName, filePath)
+	s.dropDerivatives(ctx, bucketName, newFilePath)
+
+	slog.Info("Moved file: " + filePath + " -> " + newFilePath)
+	s.events.Publish(events.Event{
+		Kind:   events.KindMoved,
+		Bucket: bucketName,
+		Key:    newFilePath,
+		OldKey: filePath,
+		Size:   logicalSize(info),
+		ETag:   info.ETag,
+		Actor:  bucketName,
+		Time:   time.Now().UTC(),
+	})
+
+	return nil
+}
+
+// moveObject does the copying and removing for MoveFile, adding meta to the
+// metadata the object has.
+func (s *filesService) moveObject(ctx context.Context, bucketName, from, to string, meta map[string]string) (minio.ObjectInfo, error) {
+	var info minio.ObjectInfo
+	err := s.replaceObject(ctx, bucketName, to, func() error {
+		source, sourceKey, err := s.sse.Stat(ctx, s.minio, bucketName, from)
 		if err != nil {
-			err = fmt.Errorf("failed to create bucket: %w", err)
-			slog.Error(err.Error())
+			return fmt.Errorf("failed to stat object: %w", err)
+		}
+
+		if info, err = s.duplicate(ctx, bucketName, source, sourceKey, to, meta); err != nil {
 			return err
 		}
+
+		// the source reference now lives on at the destination, so it's
+		// removed without releasing its blob. If it stays, both copies need
+		// a reference.
+		if err = s.minio.RemoveObject(ctx, bucketName, from, minio.RemoveObjectOptions{}); err != nil {
+			if blob := metaValue(info.UserMetadata, metaBlob); blob != "" {
+				if aErr := s.blobs.Acquire(ctx, blob); aErr != nil {
+					slog.Error("failed to reference blob of copied file", "hash", blob, "error", aErr)
+				}
+			}
+			return fmt.Errorf("failed to remove moved object: %w", err)
+		}
+
+		return nil
+	})
+
+	return info, err
+}
+
+// duplicate copies the stat'd source to key to, adding meta to its metadata,
+// and returns the copy's info. Blob references are left to the caller.
+func (s *filesService) duplicate(
+	ctx context.Context, bucketName string, source minio.ObjectInfo, sourceKey encrypt.ServerSide, to string, meta map[string]string,
+) (minio.ObjectInfo, error) {
+	// the copy is written with the current key, whatever the source has. The
+	// metadata is always given, a compose of several parts doesn't keep the
+	// content type otherwise.
+	encryption := s.sse.ForWrite(bucketName)
+	dest := minio.CopyDestOptions{
+		Bucket:          bucketName,
+		Object:          to,
+		UserMetadata:    withMetadata(source.UserMetadata, meta),
+		ReplaceMetadata: true,
+		Encryption:      encryption,
+	}
+	dest.UserMetadata["Content-Type"] = source.ContentType
+
+	// compose copies server side and, unlike a plain copy, past 5 GiB
+	_, err := s.minio.ComposeObject(ctx, dest,
+		minio.CopySrcOptions{Bucket: bucketName, Object: source.Key, MatchETag: source.ETag, Encryption: sse.CopySource(sourceKey)},
+	)
+	if err != nil {
+		return minio.ObjectInfo{}, fmt.Errorf("failed to copy object: %w", err)
+	}
+
+	info, err := s.minio.StatObject(ctx, bucketName, to, minio.StatObjectOptions{ServerSideEncryption: encryption})
+	if err != nil {
+		return minio.ObjectInfo{}, fmt.Errorf("failed to stat copied object: %w", err)
+	}
+
+	return info, nil
+}
+
+func (s *filesService) WatchFiles(filter events.Filter) *events.Subscription {
+	return s.events.Subscribe(filter)
+}
+
+func (s *filesService) StopWatching(sub *events.Subscription) {
+	s.events.Unsubscribe(sub)
+}
+
+// checkTransfer checks the paths of a move or copy.
+func checkTransfer(from, to string) error {
+	if from == "" || to == "" {
+		return dto.ErrEmptyFilePath
+	}
+
+	if from == to {
+		return ErrSameFilePath
+	}
+
+	if checkPath(from) != nil || checkPath(to) != nil {
+		return ErrReservedPath
 	}
 
 	return nil
 }
 
-func New(conf config.Minio) FilesService {
-	slog.Info("initializing service")
+// checkPath rejects paths the service keeps its own objects under. Users
+// only see those through the status of their files and GetThumbnail.
+func checkPath(filePath string) error {
+	for _, prefix := range []string{quarantinePrefix, derivativesPrefix, uploadsPrefix} {
+		if strings.HasPrefix(filePath, prefix) {
+			return ErrReservedPath
+		}
+	}
+
+	return nil
+}
+
+func (s *filesService) createBucketIfNotExists(ctx context.Context, bucketName string) error {
+	if err := s.buckets.Ensure(ctx, bucketName); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// NewMinioClient connects to MinIO, the client is shared by the service and
+// the webhook store.
+func NewMinioClient(conf config.Minio) *minio.Client {
 	minioClient, err := minio.New(conf.Endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(conf.AccessKey, conf.SecretKey, ""),
 		Region: config.DefaultLocation,
-		Secure: false,
+		Secure: conf.Secure,
 	})
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+
+	return minioClient
+}
+
+// New builds the service, keyring is nil if no keyring file is configured
+// and scanner is nil if scanning is off.
+func New(
+	minioClient *minio.Client, hub *events.Hub, webhooks *webhook.Store, sender *webhook.Sender, blobStore *blobs.Store, keys *sse.Keys,
+	keyring *envelope.Keyring, uploads *policy.Policy, scanner scan.Scanner, conf *config.Config,
+) FilesService {
+	slog.Info("initializing service")
 	return &filesService{
-		minio: minioClient,
+		minio:         minioClient,
+		buckets:       buckets.NewCache(minioClient, conf.BucketCache),
+		events:        hub,
+		webhooks:      webhooks,
+		sender:        sender,
+		webhooksConf:  conf.Webhooks,
+		blobs:         blobStore,
+		dedup:         conf.Dedup.Enabled,
+		sse:           keys,
+		keyring:       keyring,
+		envelopeConf:  conf.Envelope,
+		policy:        uploads,
+		uploadsConf:   conf.Uploads,
+		uploadMemory:  semaphore.NewWeighted(int64(conf.Uploads.MemoryBudget)),
+		progress:      &uploadTracker{uploads: make(map[string]*uploadProgress), byID: make(map[string]*uploadProgress)},
+		scanner:       scanner,
+		scanConf:      conf.Scan,
+		scanKick:      make(chan struct{}, 1),
+		thumbnailConf: conf.Thumbnails,
+		archiveConf:   conf.Archives,
+		batchConf:     conf.Batch,
+		streamConf:    conf.Streaming,
 	}
 }
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/avran02/decoplan/files/internal/config"
+	"github.com/avran02/decoplan/files/internal/sse"
+)
+
+func TestDuplicate(t *testing.T) {
+	tests := []struct {
+		name string
+		meta map[string]string
+		want map[string]string
+	}{
+		{
+			name: "as it is",
+			want: map[string]string{"Content-Type": "text/plain", "X-Amz-Meta-Sha256": "digest"},
+		},
+		{
+			name: "with metadata",
+			meta: map[string]string{metaThreat: "Eicar"},
+			want: map[string]string{"Content-Type": "text/plain", "X-Amz-Meta-Sha256": "digest", "X-Amz-Meta-Scan-Threat": "Eicar"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, client := newFakeS3(t)
+			keys, err := sse.New(config.Encryption{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := &filesService{minio: client, sse: keys}
+			ctx := context.Background()
+
+			_, err = client.PutObject(ctx, "user", "a.txt", bytes.NewReader([]byte("content")), 7, minio.PutObjectOptions{
+				ContentType:  "text/plain",
+				UserMetadata: map[string]string{metaSHA256: "digest"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			source, sourceKey, err := keys.Stat(ctx, client, "user", "a.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = s.duplicate(ctx, "user", source, sourceKey, "b.txt", tt.meta); err != nil {
+				t.Fatal(err)
+			}
+
+			obj, ok := fake.object("user/b.txt")
+			if !ok || string(obj.data) != "content" {
+				t.Fatalf("copy = %q, %v", obj.data, ok)
+			}
+			for k, v := range tt.want {
+				if got := obj.meta.Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: "a.txt"},
+		{path: "docs/.uploads/a.txt"},
+		{path: ".quarantine/pending/a.txt", wantErr: true},
+		{path: ".derivatives/a.txt/thumbnail-small", wantErr: true},
+		{path: ".uploads/0f6e", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := checkPath(tt.path); (err != nil) != tt.wantErr {
+			t.Errorf("checkPath(%q) = %v, want error %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/avran02/decoplan/files/internal/events"
+	"github.com/avran02/decoplan/files/internal/thumbnail"
+)
+
+// Files derived from a user's file, thumbnails for now, are kept under
+// derivativesPrefix followed by the file's path. Each one records the ETag of
+// the file it was made from, so one that outlived its file is never served.
+const (
+	derivativesPrefix = ".derivatives/"
+	metaSourceETag    = "Source-Etag"
+)
+
+// RunThumbnails generates the thumbnails of uploaded and moved images ahead
+// of GetThumbnail, with the configured number of workers.
+func (s *filesService) RunThumbnails(ctx context.Context) {
+	if s.thumbnailConf.Workers <= 0 {
+		return
+	}
+
+	slog.Info("starting thumbnail workers")
+
+	jobs := make(chan events.Event)
+	defer close(jobs)
+	for range s.thumbnailConf.Workers {
+		go func() {
+			for event := range jobs {
+				s.pregenerate(ctx, event.Bucket, event.Key)
+			}
+		}()
+	}
+
+	// events missed after the hub dropped us get their thumbnails on request
+	for ctx.Err() == nil {
+		sub := s.events.Subscribe(events.Filter{})
+		for event := range sub.Events() {
+			if event.Kind == events.KindUploaded || event.Kind == events.KindMoved {
+				jobs <- event
+			}
+		}
+
+		if sub.Lost() {
+			slog.Warn("thumbnail workers fell behind file events, some thumbnails are left to requests")
+		}
+	}
+}
+
+func (s *filesService) pregenerate(ctx context.Context, bucketName, filePath string) {
+	source, err := s.thumbnailSource(ctx, bucketName, filePath)
+	if err != nil {
+		if !errors.Is(err, thumbnail.ErrUnsupportedType) {
+			slog.Warn("failed to stat file for thumbnails", "bucket", bucketName, "file", filePath, "error", err)
+		}
+		return
+	}
+
+	if _, err = s.generateThumbnails(ctx, bucketName, filePath, source); err != nil {
+		slog.Warn("failed to generate thumbnails", "bucket", bucketName, "file", filePath, "error", err)
+	}
+}
+
+// GetThumbnail returns the file's thumbnail of the given size. If it's
+// missing or older than the file, all sizes are generated on the spot.
+func (s *filesService) GetThumbnail(ctx context.Context, bucketName, filePath string, size thumbnail.Size) (thumbnail.Thumbnail, error) {
+	if err := checkPath(filePath); err != nil {
+		return thumbnail.Thumbnail{}, err
+	}
+
+	if !slices.Contains(thumbnail.Sizes, size) {
+		return thumbnail.Thumbnail{}, ErrInvalidThumbnailSize
+	}
+
+	source, err := s.thumbnailSource(ctx, bucketName, filePath)
+	if err != nil {
+		return thumbnail.Thumbnail{}, s.quarantineError(ctx, bucketName, filePath, err)
+	}
+
+	if cached, ok := s.readThumbnail(ctx, bucketName, filePath, size, source.ETag); ok {
+		return cached, nil
+	}
+
+	thumbnails, err := s.generateThumbnails(ctx, bucketName, filePath, source)
+	if err != nil {
+		slog.Error(err.Error())
+		return thumbnail.Thumbnail{}, err
+	}
+
+	i := slices.IndexFunc(thumbnails, func(t thumbnail.Thumbnail) bool { return t.Size == size })
+	return thumbnails[i], nil
+}
+
+// thumbnailSource stats the file, refusing types without thumbnails.
+func (s *filesService) thumbnailSource(ctx context.Context, bucketName, filePath string) (minio.ObjectInfo, error) {
+	info, _, err := s.sse.Stat(ctx, s.minio, bucketName, filePath)
+	if err != nil {
+		return minio.ObjectInfo{}, fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	if !thumbnail.Supported(info.ContentType) {
+		return minio.ObjectInfo{}, thumbnail.ErrUnsupportedType
+	}
+
+	return info, nil
+}
+
+func (s *filesService) generateThumbnails(ctx context.Context, bucketName, filePath string, source minio.ObjectInfo) ([]thumbnail.Thumbnail, error) {
+	content, _, err := s.openFile(ctx, bucketName, filePath, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	thumbnails, err := thumbnail.Generate(content, int64(s.thumbnailConf.MaxSourceSize), s.thumbnailConf.MaxPixels)
+	if err != nil {
+		return nil, err
+	}
+
+	// a thumbnail that failed to be stored is generated again next time
+	for _, t := range thumbnails {
+		if err = s.writeThumbnail(ctx, bucketName, filePath, t, source.ETag); err != nil {
+			slog.Warn("failed to store thumbnail", "bucket", bucketName, "file", filePath, "size", t.Size, "error", err)
+		}
+	}
+
+	return thumbnails, nil
+}
+
+// readThumbnail returns the stored thumbnail if it was made from the file
+// version with etag.
+func (s *filesService) readThumbnail(ctx context.Context, bucketName, filePath string, size thumbnail.Size, etag string) (thumbnail.Thumbnail, bool) {
+	key := thumbnailKey(filePath, size)
+	info, _, err := s.sse.Stat(ctx, s.minio, bucketName, key)
+	if err != nil || metaValue(info.UserMetadata, metaSourceETag) != etag {
+		return thumbnail.Thumbnail{}, false
+	}
+
+	content, _, err := s.openFile(ctx, bucketName, key, 0, 0)
+	if err != nil {
+		slog.Warn("failed to open thumbnail", "bucket", bucketName, "file", filePath, "error", err)
+		return thumbnail.Thumbnail{}, false
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		slog.Warn("failed to read thumbnail", "bucket", bucketName, "file", filePath, "error", err)
+		return thumbnail.Thumbnail{}, false
+	}
+
+	return thumbnail.Thumbnail{Size: size, ContentType: info.ContentType, Content: data}, true
+}
+
+// writeThumbnail stores the thumbnail the way the file itself would be, so
+// it's no less protected than its file.
+func (s *filesService) writeThumbnail(ctx context.Context, bucketName, filePath string, t thumbnail.Thumbnail, etag string) error {
+	var (
+		body io.Reader = bytes.NewReader(t.Content)
+		size           = int64(len(t.Content))
+		meta           = make(map[string]string)
+	)
+	if s.envelopeConf.Enabled {
+		sealed, header, err := s.keyring.Seal(body, s.envelopeConf.ChunkSize)
+		if err != nil {
+			return err
+		}
+		body, size, meta = sealed, -1, header.Metadata()
+	}
+	meta[metaSourceETag] = etag
+
+	_, err := s.minio.PutObject(ctx, bucketName, thumbnailKey(filePath, t.Size), body, size, minio.PutObjectOptions{
+		ContentType:          t.ContentType,
+		UserMetadata:         meta,
+		ServerSideEncryption: s.sse.ForWrite(bucketName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload thumbnail: %w", err)
+	}
+
+	return nil
+}
+
+// dropDerivatives removes what was derived from the file. One left behind
+// isn't served anyway, so failures are only logged.
+func (s *filesService) dropDerivatives(ctx context.Context, bucketName, filePath string) {
+	opts := minio.ListObjectsOptions{Prefix: derivativesPrefix + filePath + "/", Recursive: true}
+	objects := s.minio.ListObjects(ctx, bucketName, opts)
+	for rErr := range s.minio.RemoveObjects(ctx, bucketName, objects, minio.RemoveObjectsOptions{}) {
+		slog.Warn("failed to remove derivative", "bucket", bucketName, "key", rErr.ObjectName, "error", rErr.Err)
+	}
+}
+
+func thumbnailKey(filePath string, size thumbnail.Size) string {
+	return derivativesPrefix + filePath + "/thumbnail-" + size.String()
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/internal/sse"
+	"github.com/avran02/decoplan/files/internal/stream"
+)
+
+// uploadsPrefix holds uploads too large to buffer until they're checked and
+// moved to their path. A crash in between leaves them behind.
+const uploadsPrefix = ".uploads/"
+
+// maxParts is the most parts S3 takes for one object.
+const maxParts = 10000
+
+// uploadProgress counts the bytes of an upload as they're received and as
+// they're stored.
+type uploadProgress struct {
+	id        string
+	size      int64
+	parts     atomic.Int32
+	partsDone atomic.Int32
+	received  atomic.Int64
+	uploaded  atomic.Int64
+}
+
+// Read is handed to MinIO as PutObjectOptions.Progress for uploads that
+// aren't stored in parts, MinIO reads what it stored from it.
+func (p *uploadProgress) Read(b []byte) (int, error) {
+	p.uploaded.Add(int64(len(b)))
+	return len(b), nil
+}
+
+// uploadTracker keeps the progress of the running uploads by their path,
+// the latest upload to a path winning, and by their upload ID.
+type uploadTracker struct {
+	mu      sync.Mutex
+	uploads map[string]*uploadProgress
+	byID    map[string]*uploadProgress
+}
+
+func (t *uploadTracker) start(bucketName, filePath, uploadID string, size int64) *uploadProgress {
+	progress := &uploadProgress{id: uploadID, size: size}
+
+	t.mu.Lock()
+	t.uploads[bucketName+"/"+filePath] = progress
+	t.byID[uploadID] = progress
+	t.mu.Unlock()
+
+	return progress
+}
+
+// finish forgets the upload. Its path stays tracked if another upload to it
+// has started since.
+func (t *uploadTracker) finish(bucketName, filePath string, progress *uploadProgress) {
+	key := bucketName + "/" + filePath
+
+	t.mu.Lock()
+	if t.uploads[key] == progress {
+		delete(t.uploads, key)
+	}
+	delete(t.byID, progress.id)
+	t.mu.Unlock()
+}
+
+func (t *uploadTracker) get(bucketName, filePath string) (*uploadProgress, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	progress, ok := t.uploads[bucketName+"/"+filePath]
+	return progress, ok
+}
+
+func (t *uploadTracker) getByID(uploadID string) (*uploadProgress, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	progress, ok := t.byID[uploadID]
+	return progress, ok
+}
+
+// receivedReader counts what's read from r as received. With a declared
+// size it fails once r turns out longer or shorter.
+type receivedReader struct {
+	r        io.Reader
+	progress *uploadProgress
+}
+
+func (r receivedReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	received := r.progress.received.Add(int64(n))
+
+	if size := r.progress.size; size > 0 {
+		if received > size || (errors.Is(err, io.EOF) && received < size) {
+			return n, ErrUploadSizeMismatch
+		}
+	}
+
+	return n, err
+}
+
+// GetUploadProgress reports how far the latest running upload to filePath
+// got.
+func (s *filesService) GetUploadProgress(_ context.Context, bucketName, filePath string) (dto.UploadProgress, error) {
+	progress, ok := s.progress.get(bucketName, filePath)
+	if !ok {
+		return dto.UploadProgress{}, ErrNoUpload
+	}
+
+	return progressToDTO(progress), nil
+}
+
+// GetUploadProgressByID reports how far the running upload with the ID got,
+// whatever else is uploaded to its path at the same time.
+func (s *filesService) GetUploadProgressByID(_ context.Context, uploadID string) (dto.UploadProgress, error) {
+	progress, ok := s.progress.getByID(uploadID)
+	if !ok {
+		return dto.UploadProgress{}, ErrNoUpload
+	}
+
+	return progressToDTO(progress), nil
+}
+
+func progressToDTO(progress *uploadProgress) dto.UploadProgress {
+	return dto.UploadProgress{
+		Size:          progress.size,
+		ReceivedBytes: progress.received.Load(),
+		UploadedBytes: progress.uploaded.Load(),
+		Parts:         progress.parts.Load(),
+		PartsDone:     progress.partsDone.Load(),
+	}
+}
+
+// putObject stores body at key. Once body is read to its end, and before
+// anything shows up at key, finish returns the metadata only known by then,
+// like the digest, or an error that drops the upload. A body that fits in a
+// part is written to key with it. A larger one is staged under uploadsPrefix
+// and composed into place, which unlike a copy works past 5 GiB. A body of
+// unknown size, -1, is staged by MinIO as it comes.
+func (s *filesService) putObject(
+	ctx context.Context, bucketName, key string, body io.Reader, size int64, progress *uploadProgress,
+	opts minio.PutObjectOptions, finish func() (map[string]string, error),
+) (minio.UploadInfo, error) {
+	partSize := int64(s.uploadsConf.PartSize)
+	bufSize := partSize
+	if size >= 0 {
+		partSize = s.partSize(size)
+		// one byte more than a body that fits in a part, so it's read to its end
+		bufSize = min(partSize, size+1)
+		progress.parts.Store(int32((size + partSize - 1) / partSize))
+	}
+
+	first, err := s.takeBuffer(ctx, int(bufSize))
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	n, err := io.ReadFull(body, *first)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		defer s.giveBuffer(first)
+
+		meta, err := finish()
+		if err != nil {
+			return minio.UploadInfo{}, err
+		}
+		opts.UserMetadata = withMetadata(opts.UserMetadata, meta)
+		progress.parts.Store(1)
+
+		return s.putPart(ctx, bucketName, key, (*first)[:n], progress, opts)
+	}
+	if err != nil {
+		s.giveBuffer(first)
+		return minio.UploadInfo{}, err
+	}
+
+	staging := uploadsPrefix + uuid.NewString()
+	var info minio.UploadInfo
+	if size < 0 {
+		opts.Progress = progress
+		info, err = s.minio.PutObject(ctx, bucketName, staging, io.MultiReader(bytes.NewReader(*first), body), -1, opts)
+		s.giveBuffer(first)
+	} else {
+		info, err = s.putParts(ctx, bucketName, staging, first, body, partSize, progress, opts)
+	}
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	defer func() {
+		if err := s.minio.RemoveObject(context.WithoutCancel(ctx), bucketName, staging, minio.RemoveObjectOptions{}); err != nil {
+			slog.Error("failed to remove staged upload", "bucket", bucketName, "key", staging, "error", err)
+		}
+	}()
+
+	meta, err := finish()
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	// replacing the metadata also replaces the content type
+	meta = withMetadata(opts.UserMetadata, meta)
+	meta["Content-Type"] = opts.ContentType
+	composed, err := s.minio.ComposeObject(ctx,
+		minio.CopyDestOptions{
+			Bucket:          bucketName,
+			Object:          key,
+			UserMetadata:    meta,
+			ReplaceMetadata: true,
+			Encryption:      opts.ServerSideEncryption,
+		},
+		minio.CopySrcOptions{Bucket: bucketName, Object: staging, MatchETag: info.ETag, Encryption: sse.CopySource(opts.ServerSideEncryption)},
+	)
+	if err != nil {
+		return minio.UploadInfo{}, fmt.Errorf("failed to store staged upload: %w", err)
+	}
+	composed.Size = info.Size
+
+	return composed, nil
+}
+
+// putParts uploads first, already read, and the rest of body in parts of
+// partSize, Threads at a time. Each part's buffer is taken from the memory
+// budget all uploads share, so the parts in flight never take more than it.
+func (s *filesService) putParts(
+	ctx context.Context, bucketName, key string, first *[]byte, body io.Reader, partSize int64, progress *uploadProgress,
+	opts minio.PutObjectOptions,
+) (minio.UploadInfo, error) {
+	core := minio.Core{Client: s.minio}
+	uploadID, err := core.NewMultipartUpload(ctx, bucketName, key, opts)
+	if err != nil {
+		s.giveBuffer(first)
+		return minio.UploadInfo{}, fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	var (
+		total   int64
+		mu      sync.Mutex
+		parts   []minio.CompletePart
+		readErr error
+	)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.uploadsConf.Threads)
+
+	for number := 1; ; number++ {
+		buf, n, last := first, len(*first), false
+		if number > 1 {
+			if buf, err = s.takeBuffer(gctx, int(partSize)); err != nil {
+				readErr = err
+				break
+			}
+
+			n, err = io.ReadFull(body, *buf)
+			last = errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+			if err != nil && !last {
+				s.giveBuffer(buf)
+				readErr = err
+				break
+			}
+		}
+
+		// the body ended on a part boundary
+		if n == 0 {
+			s.giveBuffer(buf)
+			break
+		}
+
+		total += int64(n)
+		g.Go(func() error {
+			defer s.giveBuffer(buf)
+
+			part, err := core.PutObjectPart(gctx, bucketName, key, uploadID, number, bytes.NewReader((*buf)[:n]), int64(n),
+				minio.PutObjectPartOptions{SSE: opts.ServerSideEncryption})
+			if err != nil {
+				return fmt.Errorf("failed to upload part %d: %w", number, err)
+			}
+
+			mu.Lock()
+			parts = append(parts, minio.CompletePart{PartNumber: number, ETag: part.ETag})
+			mu.Unlock()
+			progress.uploaded.Add(int64(n))
+			progress.partsDone.Add(1)
+
+			return nil
+		})
+
+		if last {
+			break
+		}
+	}
+
+	// a failed part cancels the reading too, it's the error that matters
+	if err := g.Wait(); err != nil {
+		readErr = err
+	}
+
+	var info minio.UploadInfo
+	if readErr == nil {
+		slices.SortFunc(parts, func(a, b minio.CompletePart) int { return a.PartNumber - b.PartNumber })
+		info, readErr = core.CompleteMultipartUpload(ctx, bucketName, key, uploadID, parts, opts)
+	}
+	if readErr != nil {
+		// the parts would take space until MinIO cleans up stale uploads
+		if err := core.AbortMultipartUpload(context.WithoutCancel(ctx), bucketName, key, uploadID); err != nil {
+			slog.Error("failed to abort multipart upload", "bucket", bucketName, "key", key, "error", err)
+		}
+		return minio.UploadInfo{}, readErr
+	}
+	info.Size = total
+
+	return info, nil
+}
+
+// putPart stores a body that fit in a single part.
+func (s *filesService) putPart(
+	ctx context.Context, bucketName, key string, body []byte, progress *uploadProgress, opts minio.PutObjectOptions,
+) (minio.UploadInfo, error) {
+	info, err := s.minio.PutObject(ctx, bucketName, key, bytes.NewReader(body), int64(len(body)), opts)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	progress.uploaded.Add(int64(len(body)))
+	progress.partsDone.Add(1)
+
+	return info, nil
+}
+
+// partSize is the configured part size, or the smallest that stores size
+// bytes in maxParts parts, rounded up to a MiB.
+func (s *filesService) partSize(size int64) int64 {
+	const mib = 1024 * 1024
+	fit := (size + maxParts - 1) / maxParts
+	fit = (fit + mib - 1) / mib * mib
+
+	return max(int64(s.uploadsConf.PartSize), fit)
+}
+
+// takeBuffer waits until the memory budget has room for a buffer of size
+// and returns one. The pool rounds buffers up, the budget is charged for
+// what the buffer really takes.
+func (s *filesService) takeBuffer(ctx context.Context, size int) (*[]byte, error) {
+	if err := s.uploadMemory.Acquire(ctx, s.bufferWeight(stream.BufferCap(size))); err != nil {
+		return nil, err
+	}
+
+	return stream.GetBuffer(size), nil
+}
+
+func (s *filesService) giveBuffer(buf *[]byte) {
+	s.uploadMemory.Release(s.bufferWeight(cap(*buf)))
+	stream.PutBuffer(buf)
+}
+
+// bufferWeight is what a buffer of capacity c takes from the budget. Parts
+// of huge files may be larger than the whole budget, they take all of it.
+func (s *filesService) bufferWeight(c int) int64 {
+	return int64(min(c, s.uploadsConf.MemoryBudget))
+}
+
+// withMetadata returns meta with extra added, leaving meta as it is.
+func withMetadata(meta, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(meta)+len(extra))
+	maps.Copy(merged, meta)
+	maps.Copy(merged, extra)
+
+	return merged
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/avran02/decoplan/files/internal/config"
+)
+
+const mib = 1024 * 1024
+
+func TestPartSize(t *testing.T) {
+	s := &filesService{uploadsConf: config.Uploads{PartSize: 16 * mib}}
+
+	tests := []struct {
+		name string
+		size int64
+		want int64
+	}{
+		{name: "empty", size: 0, want: 16 * mib},
+		{name: "one part", size: 10 * mib, want: 16 * mib},
+		{name: "fits in the configured size", size: maxParts * 16 * mib, want: 16 * mib},
+		{name: "one byte too many", size: maxParts*16*mib + 1, want: 17 * mib},
+		{name: "huge", size: 5 << 40, want: 525 * mib},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.partSize(tt.size)
+			if got != tt.want {
+				t.Errorf("partSize(%d) = %d, want %d", tt.size, got, tt.want)
+			}
+			if got%mib != 0 {
+				t.Errorf("partSize(%d) = %d isn't whole MiB", tt.size, got)
+			}
+			if parts := (tt.size + got - 1) / got; parts > maxParts {
+				t.Errorf("partSize(%d) = %d takes %d parts", tt.size, got, parts)
+			}
+		})
+	}
+}
+
+func TestReceivedReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		declared int64
+		sent     int
+		wantErr  error
+	}{
+		{name: "undeclared", declared: 0, sent: 100},
+		{name: "as declared", declared: 100, sent: 100},
+		{name: "shorter", declared: 100, sent: 99, wantErr: ErrUploadSizeMismatch},
+		{name: "longer", declared: 100, sent: 101, wantErr: ErrUploadSizeMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := &uploadProgress{size: tt.declared}
+			r := receivedReader{r: bytes.NewReader(make([]byte, tt.sent)), progress: progress}
+
+			_, err := io.Copy(io.Discard, r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := progress.received.Load(); got != int64(tt.sent) {
+				t.Errorf("received %d bytes, want %d", got, tt.sent)
+			}
+		})
+	}
+}
+
+func TestUploadTracker(t *testing.T) {
+	tracker := &uploadTracker{uploads: map[string]*uploadProgress{}, byID: map[string]*uploadProgress{}}
+
+	first := tracker.start("user", "a.txt", "first", 10)
+	second := tracker.start("user", "a.txt", "second", 20)
+
+	// uploads to the same path keep their own progress
+	if got, ok := tracker.getByID("first"); !ok || got != first {
+		t.Errorf("getByID(first) = %v, %v, want the first upload", got, ok)
+	}
+	if got, ok := tracker.getByID("second"); !ok || got != second {
+		t.Errorf("getByID(second) = %v, %v, want the second upload", got, ok)
+	}
+
+	// the first upload ending must not hide the one that replaced it
+	tracker.finish("user", "a.txt", first)
+	if got, ok := tracker.get("user", "a.txt"); !ok || got != second {
+		t.Fatalf("get() = %v, %v, want the second upload", got, ok)
+	}
+	if _, ok := tracker.getByID("first"); ok {
+		t.Error("the first upload is still tracked after it finished")
+	}
+
+	tracker.finish("user", "a.txt", second)
+	if _, ok := tracker.get("user", "a.txt"); ok {
+		t.Error("the upload is still tracked after it finished")
+	}
+	if _, ok := tracker.getByID("second"); ok {
+		t.Error("the second upload is still tracked after it finished")
+	}
+}
+
+func TestTakeBuffer(t *testing.T) {
+	s := &filesService{
+		uploadsConf:  config.Uploads{PartSize: 8, MemoryBudget: 16},
+		uploadMemory: semaphore.NewWeighted(16),
+	}
+	ctx := context.Background()
+
+	// a part larger than the budget takes all of it rather than never fitting
+	huge, err := s.takeBuffer(ctx, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*huge) != 64 {
+		t.Errorf("buffer has %d bytes, want 64", len(*huge))
+	}
+
+	full, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := s.takeBuffer(full, 8); !errors.Is(err, context.Canceled) {
+		t.Errorf("takeBuffer() on a spent budget = %v, want %v", err, context.Canceled)
+	}
+
+	s.giveBuffer(huge)
+	for range 2 {
+		if _, err := s.takeBuffer(ctx, 8); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a buffer is charged for its capacity, not the size asked for
+	s = &filesService{
+		uploadsConf:  config.Uploads{PartSize: 8, MemoryBudget: 16},
+		uploadMemory: semaphore.NewWeighted(16),
+	}
+	odd, err := s.takeBuffer(ctx, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.uploadMemory.TryAcquire(1) {
+		t.Errorf("a %d byte buffer with capacity %d left room in a budget of 16", len(*odd), cap(*odd))
+	}
+	s.giveBuffer(odd)
+	if !s.uploadMemory.TryAcquire(16) {
+		t.Error("giving the buffer back didn't free the budget")
+	}
+}
+
+func TestPutObject(t *testing.T) {
+	mismatch := func() (map[string]string, error) { return nil, ErrChecksumMismatch }
+	digest := func() (map[string]string, error) { return map[string]string{metaSHA256: "digest"}, nil }
+
+	tests := []struct {
+		name     string
+		size     int
+		declared int64
+		finish   func() (map[string]string, error)
+		wantErr  error
+	}{
+		{name: "one part", size: 100, declared: 100, finish: digest},
+		{name: "one part of unknown size", size: 100, declared: -1, finish: digest},
+		{name: "parts", size: 2*mib + 100, declared: 2*mib + 100, finish: digest},
+		{name: "parts ending on a boundary", size: 2 * mib, declared: 2 * mib, finish: digest},
+		{name: "one part mismatched", size: 100, declared: 100, finish: mismatch, wantErr: ErrChecksumMismatch},
+		{name: "parts mismatched", size: 2*mib + 100, declared: 2*mib + 100, finish: mismatch, wantErr: ErrChecksumMismatch},
+		{name: "parts cut short", size: 2 * mib, declared: 2*mib + 100, finish: digest, wantErr: ErrUploadSizeMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, client := newFakeS3(t)
+			s := &filesService{
+				minio:        client,
+				uploadsConf:  config.Uploads{Threads: 2, PartSize: mib, MemoryBudget: 4 * mib},
+				uploadMemory: semaphore.NewWeighted(4 * mib),
+			}
+			ctx := context.Background()
+
+			// the file the upload replaces
+			if _, err := client.PutObject(ctx, "user", "a.txt", bytes.NewReader([]byte("previous")), 8, minio.PutObjectOptions{}); err != nil {
+				t.Fatal(err)
+			}
+
+			data := make([]byte, tt.size)
+			if _, err := rand.Read(data); err != nil {
+				t.Fatal(err)
+			}
+			progress := &uploadProgress{size: max(tt.declared, 0)}
+			body := receivedReader{r: bytes.NewReader(data), progress: progress}
+
+			info, err := s.putObject(ctx, "user", "a.txt", body, tt.declared, progress, minio.PutObjectOptions{
+				ContentType:  "text/plain",
+				UserMetadata: map[string]string{"Envelope": "header"},
+			}, tt.finish)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("putObject() error = %v, want %v", err, tt.wantErr)
+			}
+
+			keys, uploads := fake.keys()
+			if len(keys) != 1 || uploads != 0 {
+				t.Errorf("left objects %v and %d multipart uploads, want only the file", keys, uploads)
+			}
+
+			obj, _ := fake.object("user/a.txt")
+			if tt.wantErr != nil {
+				if string(obj.data) != "previous" {
+					t.Errorf("the failed upload replaced the file with %d bytes", len(obj.data))
+				}
+				return
+			}
+
+			if !bytes.Equal(obj.data, data) || info.Size != int64(tt.size) {
+				t.Errorf("stored %d bytes, reported %d, want %d", len(obj.data), info.Size, tt.size)
+			}
+			wantMeta := map[string]string{"X-Amz-Meta-Sha256": "digest", "X-Amz-Meta-Envelope": "header", "Content-Type": "text/plain"}
+			for k, v := range wantMeta {
+				if got := obj.meta.Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/avran02/decoplan/files/internal/dto"
+	"github.com/avran02/decoplan/files/internal/events"
+	"github.com/avran02/decoplan/files/internal/webhook"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookSecretBytes        = 32
+	defaultDeliveriesPageSize = 50
+	maxDeliveriesPageSize     = 200
+)
+
+// CreateWebhook subscribes the URL to the user's file events. The returned
+// webhook is the only place the signing secret is ever shown.
+func (s *filesService) CreateWebhook(ctx context.Context, userID, rawURL, prefix string, kinds []events.Kind) (webhook.Webhook, error) {
+	if userID == "" {
+		return webhook.Webhook{}, dto.ErrEmptyUserID
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return webhook.Webhook{}, ErrInvalidWebhookURL
+	}
+
+	for _, kind := range kinds {
+		switch kind {
+		case events.KindUploaded, events.KindRemoved, events.KindMoved:
+		default:
+			return webhook.Webhook{}, ErrInvalidEventKind
+		}
+	}
+
+	kinds = slices.Clone(kinds)
+	slices.Sort(kinds)
+
+	secret := make([]byte, webhookSecretBytes)
+	if _, err = rand.Read(secret); err != nil {
+		return webhook.Webhook{}, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	w := webhook.Webhook{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		URL:       u.String(),
+		Secret:    hex.EncodeToString(secret),
+		Kinds:     slices.Compact(kinds),
+		Prefix:    prefix,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err = s.webhooks.CreateWebhook(ctx, w); err != nil {
+		return webhook.Webhook{}, err
+	}
+
+	return w, nil
+}
+
+func (s *filesService) ListWebhooks(ctx context.Context, userID string) ([]webhook.Webhook, error) {
+	return s.webhooks.ListWebhooks(ctx, userID)
+}
+
+func (s *filesService) DeleteWebhook(ctx context.Context, userID, webhookID string) error {
+	return s.webhooks.DeleteWebhook(ctx, userID, webhookID)
+}
+
+// TestWebhook sends a signed test event right away and reports what the
+// receiver answered. Test deliveries aren't retried or logged.
+func (s *filesService) TestWebhook(ctx context.Context, userID, webhookID string) (webhook.Result, error) {
+	w, err := s.webhooks.GetWebhook(ctx, userID, webhookID)
+	if err != nil {
+		return webhook.Result{}, err
+	}
+
+	event := events.Event{
+		Kind:   webhook.KindTest,
+		Bucket: userID,
+		Actor:  userID,
+		Time:   time.Now().UTC(),
+	}
+
+	id := uuid.NewString()
+	body, err := webhook.Marshal(id, event)
+	if err != nil {
+		return webhook.Result{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.webhooksConf.Timeout)
+	defer cancel()
+
+	return s.sender.Send(ctx, w, id, event.Kind, body), nil
+}
+
+func (s *filesService) ListWebhookDeliveries(ctx context.Context, userID, webhookID string, pageSize int, pageToken string) (webhook.DeliveryPage, error) {
+	if _, err := s.webhooks.GetWebhook(ctx, userID, webhookID); err != nil {
+		return webhook.DeliveryPage{}, err
+	}
+
+	switch {
+	case pageSize <= 0:
+		pageSize = defaultDeliveriesPageSize
+	case pageSize > maxDeliveriesPageSize:
+		pageSize = maxDeliveriesPageSize
+	}
+
+	return s.webhooks.ListDeliveries(ctx, webhookID, pageSize, pageToken)
+}
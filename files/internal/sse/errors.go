@@ -0,0 +1,9 @@
+package sse
+
+import "errors"
+
+var (
+	ErrUnknownMode      = errors.New("unknown server side encryption mode")
+	ErrInvalidMasterKey = errors.New("master key must be 32 base64 encoded bytes")
+	ErrMissingMasterKey = errors.New("sse-c needs a master key")
+)
@@ -0,0 +1,147 @@
+package sse
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+
+	"github.com/avran02/decoplan/files/internal/config"
+)
+
+type Mode string
+
+const (
+	ModeNone Mode = "none"
+	// ModeS3 lets MinIO encrypt with its KMS, which must be configured.
+	ModeS3 Mode = "sse-s3"
+	// ModeC encrypts every bucket with its own key derived from the master
+	// key. MinIO only accepts such keys over TLS.
+	ModeC Mode = "sse-c"
+)
+
+// Keys picks the server side encryption of every object. Objects are
+// written with the current mode and key; reads also try the keys of
+// previous master keys and no key at all, so objects written before a
+// rotation or before encryption was enabled stay readable until the
+// re-encryption job rewrote them.
+type Keys struct {
+	mode Mode
+	// masters holds the current master key first, then the previous ones
+	masters [][]byte
+}
+
+// ForWrite returns the encryption new objects in the bucket get.
+func (k *Keys) ForWrite(bucket string) encrypt.ServerSide {
+	switch k.mode {
+	case ModeS3:
+		return encrypt.NewSSE()
+	case ModeC:
+		return k.customerKey(k.masters[0], bucket)
+	default:
+		return nil
+	}
+}
+
+// ForRead returns the keys an object in the bucket may have been written
+// with, the most likely first. A nil key reads both unencrypted and SSE-S3
+// objects.
+func (k *Keys) ForRead(bucket string) []encrypt.ServerSide {
+	keys := make([]encrypt.ServerSide, 0, len(k.masters)+1)
+	if k.mode != ModeC {
+		keys = append(keys, nil)
+	}
+	for _, master := range k.masters {
+		keys = append(keys, k.customerKey(master, bucket))
+	}
+	if k.mode == ModeC {
+		keys = append(keys, nil)
+	}
+
+	return keys
+}
+
+// Stat stats the object with the key it was written with and returns that
+// key for reading or copying it.
+func (k *Keys) Stat(ctx context.Context, client *minio.Client, bucket, object string) (minio.ObjectInfo, encrypt.ServerSide, error) {
+	info, i, err := k.stat(ctx, client, bucket, object)
+	if err != nil {
+		return minio.ObjectInfo{}, nil, err
+	}
+
+	return info, k.ForRead(bucket)[i], nil
+}
+
+// stat returns the index of the matching key in ForRead. A missing object
+// ends the search right away, any other error is taken as a wrong key.
+func (k *Keys) stat(ctx context.Context, client *minio.Client, bucket, object string) (minio.ObjectInfo, int, error) {
+	var err error
+	for i, key := range k.ForRead(bucket) {
+		var info minio.ObjectInfo
+		info, err = client.StatObject(ctx, bucket, object, minio.StatObjectOptions{ServerSideEncryption: key})
+		if err == nil {
+			return info, i, nil
+		}
+
+		switch minio.ToErrorResponse(err).Code {
+		case "NoSuchKey", "NoSuchBucket":
+			return minio.ObjectInfo{}, 0, err
+		}
+	}
+
+	return minio.ObjectInfo{}, 0, err
+}
+
+// customerKey derives the SSE-C key of the bucket, so a key leaked with one
+// user's object opens nothing of other users.
+func (k *Keys) customerKey(master []byte, bucket string) encrypt.ServerSide {
+	mac := hmac.New(sha256.New, master)
+	mac.Write([]byte(bucket))
+
+	// a SHA-256 HMAC is always 32 bytes, the only length NewSSEC rejects
+	key, _ := encrypt.NewSSEC(mac.Sum(nil))
+	return key
+}
+
+// CopySource returns the key to pass as the source of a copy. Only SSE-C
+// keys are sent for the source, MinIO decrypts the other modes itself.
+func CopySource(key encrypt.ServerSide) encrypt.ServerSide {
+	if key == nil || key.Type() != encrypt.SSEC {
+		return nil
+	}
+
+	return key
+}
+
+func New(conf config.Encryption) (*Keys, error) {
+	k := &Keys{mode: Mode(conf.Mode)}
+	switch k.mode {
+	case "":
+		k.mode = ModeNone
+	case ModeNone, ModeS3, ModeC:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMode, conf.Mode)
+	}
+
+	for _, encoded := range append([]string{conf.MasterKey}, conf.PreviousMasterKeys...) {
+		if encoded == "" {
+			continue
+		}
+
+		master, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(master) != 32 {
+			return nil, ErrInvalidMasterKey
+		}
+		k.masters = append(k.masters, master)
+	}
+
+	if k.mode == ModeC && conf.MasterKey == "" {
+		return nil, ErrMissingMasterKey
+	}
+
+	return k, nil
+}
@@ -0,0 +1,108 @@
+package sse
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Rotator rewrites every object that isn't encrypted the current way, which
+// is how a master key rotation or a mode change reaches existing files. An
+// object is copied onto itself only if its ETag didn't change since it was
+// checked, so concurrent writes win over the rewrite.
+type Rotator struct {
+	minio *minio.Client
+	keys  *Keys
+	skip  map[string]struct{}
+}
+
+// Run makes one pass over all buckets except the skipped ones.
+func (r *Rotator) Run(ctx context.Context) {
+	slog.Info("starting re-encryption", "mode", r.keys.mode)
+
+	buckets, err := r.minio.ListBuckets(ctx)
+	if err != nil {
+		slog.Error("failed to list buckets for re-encryption", "error", err)
+		return
+	}
+
+	var rewritten, failed int
+	for _, bucket := range buckets {
+		if _, ok := r.skip[bucket.Name]; ok {
+			continue
+		}
+
+		for object := range r.minio.ListObjects(ctx, bucket.Name, minio.ListObjectsOptions{Recursive: true}) {
+			if object.Err != nil {
+				slog.Error("failed to list objects for re-encryption", "bucket", bucket.Name, "error", object.Err)
+				failed++
+				break
+			}
+
+			ok, err := r.rewrite(ctx, bucket.Name, object.Key)
+			switch {
+			case err != nil:
+				slog.Warn("failed to re-encrypt object", "bucket", bucket.Name, "object", object.Key, "error", err)
+				failed++
+			case ok:
+				rewritten++
+			}
+		}
+	}
+
+	slog.Info("finished re-encryption", "rewritten", rewritten, "failed", failed)
+}
+
+// rewrite re-encrypts the object if needed and reports whether it did.
+func (r *Rotator) rewrite(ctx context.Context, bucket, object string) (bool, error) {
+	info, i, err := r.keys.stat(ctx, r.minio, bucket, object)
+	if err != nil {
+		return false, err
+	}
+
+	if r.isCurrent(i, info) {
+		return false, nil
+	}
+
+	current := r.keys.ForWrite(bucket)
+	_, err = r.minio.ComposeObject(ctx,
+		minio.CopyDestOptions{Bucket: bucket, Object: object, Encryption: current},
+		minio.CopySrcOptions{Bucket: bucket, Object: object, MatchETag: info.ETag, Encryption: CopySource(r.keys.ForRead(bucket)[i])},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// isCurrent reports whether the object read with the i-th key of ForRead is
+// encrypted the way new objects are. Without encryption, SSE-S3 objects are
+// left as they are.
+func (r *Rotator) isCurrent(i int, info minio.ObjectInfo) bool {
+	if i != 0 {
+		return false
+	}
+
+	if r.keys.mode == ModeS3 {
+		return info.Metadata.Get("X-Amz-Server-Side-Encryption") != ""
+	}
+
+	return true
+}
+
+// NewRotator skips the given system buckets, which the service doesn't
+// encrypt.
+func NewRotator(minioClient *minio.Client, keys *Keys, skip ...string) *Rotator {
+	r := &Rotator{
+		minio: minioClient,
+		keys:  keys,
+		skip:  make(map[string]struct{}, len(skip)),
+	}
+	for _, bucket := range skip {
+		r.skip[bucket] = struct{}{}
+	}
+
+	return r
+}
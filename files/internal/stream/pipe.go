@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Pipe is an io.Pipe that buffers up to depth chunks, so the writer only
+// waits once the reader falls that far behind. Chunks handed over with Send
+// aren't copied.
+//
+// Only one goroutine writes. The writing side may be closed more than once,
+// like io.Pipe's, the first close wins.
+type Pipe struct {
+	chunks chan []byte
+	werr   error
+	wonce  sync.Once
+
+	done chan struct{}
+	once sync.Once
+
+	cur []byte
+}
+
+// Send queues the chunk without copying it, so the caller mustn't touch it
+// afterwards.
+func (p *Pipe) Send(chunk []byte) error {
+	if len(chunk) == 0 {
+		return nil
+	}
+
+	select {
+	case <-p.done:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	select {
+	case p.chunks <- chunk:
+		return nil
+	case <-p.done:
+		return io.ErrClosedPipe
+	}
+}
+
+// Write queues a copy of b.
+func (p *Pipe) Write(b []byte) (int, error) {
+	if err := p.Send(bytes.Clone(b)); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// CloseWithError makes the reader fail with err once it has read what's
+// queued, or see io.EOF if err is nil. Later calls are no-ops.
+func (p *Pipe) CloseWithError(err error) {
+	p.wonce.Do(func() {
+		if err == nil {
+			err = io.EOF
+		}
+		p.werr = err
+		close(p.chunks)
+	})
+}
+
+func (p *Pipe) Close() error {
+	p.CloseWithError(nil)
+	return nil
+}
+
+func (p *Pipe) Read(b []byte) (int, error) {
+	select {
+	case <-p.done:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	if len(p.cur) == 0 {
+		chunk, ok := <-p.chunks
+		if !ok {
+			return 0, p.werr
+		}
+		p.cur = chunk
+	}
+
+	n := copy(b, p.cur)
+	p.cur = p.cur[n:]
+
+	return n, nil
+}
+
+// CloseRead makes further writes fail, so a writer isn't left waiting on a
+// reader that gave up.
+func (p *Pipe) CloseRead() {
+	p.once.Do(func() { close(p.done) })
+}
+
+func NewPipe(depth int) *Pipe {
+	return &Pipe{
+		chunks: make(chan []byte, depth),
+		done:   make(chan struct{}),
+	}
+}
@@ -0,0 +1,46 @@
+package stream
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// pools holds buffers by size class, class n having a capacity of 1<<n, so
+// every negotiated chunk size gets buffers from a pool that fits it.
+var pools [bits.UintSize]sync.Pool
+
+// GetBuffer returns a buffer of length size, reused if one is free. Its
+// capacity is BufferCap(size).
+func GetBuffer(size int) *[]byte {
+	class := sizeClass(size)
+	if buf, ok := pools[class].Get().(*[]byte); ok {
+		*buf = (*buf)[:size]
+		return buf
+	}
+
+	buf := make([]byte, size, BufferCap(size))
+	return &buf
+}
+
+// BufferCap is the capacity of the buffers GetBuffer returns for size, the
+// memory they really take.
+func BufferCap(size int) int {
+	return 1 << sizeClass(size)
+}
+
+// PutBuffer gives the buffer back for reuse. It mustn't be used afterwards.
+func PutBuffer(buf *[]byte) {
+	c := cap(*buf)
+	if c == 0 || c&(c-1) != 0 {
+		// not one of ours
+		return
+	}
+	pools[sizeClass(c)].Put(buf)
+}
+
+func sizeClass(size int) int {
+	if size <= 1 {
+		return 0
+	}
+	return bits.Len(uint(size - 1))
+}
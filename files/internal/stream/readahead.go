@@ -0,0 +1,122 @@
+package stream
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+type chunk struct {
+	buf *[]byte
+	err error
+}
+
+// ReadAhead reads a file in chunks on its own goroutine, staying at most
+// depth chunks ahead of the consumer. Reading from storage and sending to
+// the client overlap, while a slow client holds back at most depth chunks.
+type ReadAhead struct {
+	chunks chan chunk
+	done   chan struct{}
+	once   sync.Once
+	last   *[]byte
+}
+
+// Next returns the next chunk, or io.EOF after the last one. The chunk is
+// only valid until the following call to Next or Close, anything that may
+// hold on to it longer, like a message passed to a gRPC stream, needs a
+// copy.
+func (ra *ReadAhead) Next() ([]byte, error) {
+	ra.release()
+
+	c, ok := <-ra.chunks
+	if !ok {
+		return nil, io.EOF
+	}
+	if c.err != nil {
+		if c.buf != nil {
+			PutBuffer(c.buf)
+		}
+		return nil, c.err
+	}
+	ra.last = c.buf
+
+	return *c.buf, nil
+}
+
+// Close stops reading ahead. The file is closed once the read in progress,
+// if any, returns.
+func (ra *ReadAhead) Close() {
+	ra.once.Do(func() { close(ra.done) })
+	ra.release()
+
+	for {
+		select {
+		case c, ok := <-ra.chunks:
+			if !ok {
+				return
+			}
+			if c.buf != nil {
+				PutBuffer(c.buf)
+			}
+		default:
+			// whatever the reader still queues is left to the garbage collector
+			return
+		}
+	}
+}
+
+func (ra *ReadAhead) release() {
+	if ra.last != nil {
+		PutBuffer(ra.last)
+		ra.last = nil
+	}
+}
+
+func (ra *ReadAhead) run(file io.ReadCloser, chunkSize int) {
+	defer close(ra.chunks)
+	defer file.Close()
+
+	for {
+		buf := GetBuffer(chunkSize)
+		n, err := io.ReadFull(file, *buf)
+		if n == 0 {
+			PutBuffer(buf)
+			buf = nil
+		} else {
+			*buf = (*buf)[:n]
+		}
+
+		eof := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if eof {
+			err = nil
+		}
+		if buf == nil && err == nil {
+			return
+		}
+
+		select {
+		case ra.chunks <- chunk{buf: buf, err: err}:
+		case <-ra.done:
+			if buf != nil {
+				PutBuffer(buf)
+			}
+			return
+		}
+
+		if eof || err != nil {
+			return
+		}
+	}
+}
+
+// NewReadAhead starts reading file in chunks of chunkSize. It takes over
+// the file and closes it when it's done.
+func NewReadAhead(file io.ReadCloser, chunkSize, depth int) *ReadAhead {
+	ra := &ReadAhead{
+		chunks: make(chan chunk, depth),
+		done:   make(chan struct{}),
+	}
+	go ra.run(file, chunkSize)
+
+	return ra
+}
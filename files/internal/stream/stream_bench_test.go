@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// consumerLatency is what the storage upload takes to accept each read.
+const consumerLatency = 100 * time.Microsecond
+
+type slowWriter struct{}
+
+func (slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(consumerLatency)
+	return len(p), nil
+}
+
+// BenchmarkPipe feeds an upload the way the controller does, one received
+// message at a time, into a consumer as slow as the storage upload.
+func BenchmarkPipe(b *testing.B) {
+	const chunks = 256
+
+	pipes := []struct {
+		name string
+		open func() (io.ReadCloser, func([]byte) error, func(error))
+	}{
+		{
+			name: "io.Pipe",
+			open: func() (io.ReadCloser, func([]byte) error, func(error)) {
+				r, w := io.Pipe()
+				send := func(p []byte) error {
+					_, err := w.Write(p)
+					return err
+				}
+				return r, send, func(err error) { w.CloseWithError(err) }
+			},
+		},
+		{
+			name: "Pipe",
+			open: func() (io.ReadCloser, func([]byte) error, func(error)) {
+				p := NewPipe(4)
+				return readCloser{p}, p.Send, p.CloseWithError
+			},
+		},
+	}
+
+	for _, chunkSize := range []int{64 << 10, 256 << 10, 1 << 20} {
+		chunk := make([]byte, chunkSize)
+		for _, pipe := range pipes {
+			b.Run(fmt.Sprintf("chunk=%dKiB/%s", chunkSize>>10, pipe.name), func(b *testing.B) {
+				b.SetBytes(int64(chunks * chunkSize))
+				b.ReportAllocs()
+
+				for i := 0; i < b.N; i++ {
+					r, send, closeWithError := pipe.open()
+					go func() {
+						for j := 0; j < chunks; j++ {
+							// the receive buffer is reused for the next message
+							msg := append([]byte(nil), chunk...)
+							if err := send(msg); err != nil {
+								closeWithError(err)
+								return
+							}
+						}
+						closeWithError(nil)
+					}()
+
+					n, err := io.CopyBuffer(slowWriter{}, r, make([]byte, chunkSize))
+					if err != nil || n != int64(chunks*chunkSize) {
+						b.Fatalf("copied %d bytes: %v", n, err)
+					}
+					r.Close()
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGetBuffer(b *testing.B) {
+	for _, size := range []int{64 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("size=%dKiB/make", size>>10), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf := make([]byte, size)
+				buf[0] = 1
+			}
+		})
+		b.Run(fmt.Sprintf("size=%dKiB/pool", size>>10), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf := GetBuffer(size)
+				(*buf)[0] = 1
+				PutBuffer(buf)
+			}
+		})
+	}
+}
+
+type readCloser struct {
+	*Pipe
+}
+
+func (r readCloser) Close() error {
+	r.CloseRead()
+	return nil
+}
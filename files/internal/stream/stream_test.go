@@ -0,0 +1,264 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+// file is an in-memory file that remembers being closed.
+type file struct {
+	io.Reader
+	closed bool
+}
+
+func (f *file) Close() error {
+	f.closed = true
+	return nil
+}
+
+func randomBytes(t testing.TB, n int) []byte {
+	t.Helper()
+
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestReadAhead(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		chunkSize int
+		depth     int
+		want      []int
+	}{
+		{name: "empty", size: 0, chunkSize: 4, depth: 2},
+		{name: "short chunk", size: 3, chunkSize: 4, depth: 2, want: []int{3}},
+		{name: "whole chunks", size: 8, chunkSize: 4, depth: 2, want: []int{4, 4}},
+		{name: "short last chunk", size: 10, chunkSize: 4, depth: 1, want: []int{4, 4, 2}},
+		{name: "deeper than the file", size: 10, chunkSize: 4, depth: 8, want: []int{4, 4, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := randomBytes(t, tt.size)
+			f := &file{Reader: bytes.NewReader(data)}
+			ra := NewReadAhead(f, tt.chunkSize, tt.depth)
+
+			var got []byte
+			var sizes []int
+			for {
+				chunk, err := ra.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				got = append(got, chunk...)
+				sizes = append(sizes, len(chunk))
+			}
+			ra.Close()
+
+			if !bytes.Equal(got, data) {
+				t.Errorf("read %d bytes, want the %d written", len(got), len(data))
+			}
+			if !slicesEqual(sizes, tt.want) {
+				t.Errorf("chunk sizes = %v, want %v", sizes, tt.want)
+			}
+			if !f.closed {
+				t.Error("the file wasn't closed")
+			}
+		})
+	}
+}
+
+func TestReadAheadError(t *testing.T) {
+	boom := errors.New("boom")
+	f := &file{Reader: io.MultiReader(bytes.NewReader(make([]byte, 6)), &failingReader{err: boom})}
+	ra := NewReadAhead(f, 4, 2)
+	defer ra.Close()
+
+	if chunk, err := ra.Next(); err != nil || len(chunk) != 4 {
+		t.Fatalf("Next() = %d bytes, %v, want a full chunk", len(chunk), err)
+	}
+	if _, err := ra.Next(); !errors.Is(err, boom) {
+		t.Errorf("Next() error = %v, want %v", err, boom)
+	}
+}
+
+func TestReadAheadCloseEarly(t *testing.T) {
+	f := &file{Reader: bytes.NewReader(make([]byte, 1<<20))}
+	ra := NewReadAhead(f, 1024, 2)
+
+	if _, err := ra.Next(); err != nil {
+		t.Fatal(err)
+	}
+	ra.Close()
+
+	// the reader stops at its next chunk and then closes the file
+	for range ra.chunks {
+	}
+	if !f.closed {
+		t.Error("the file wasn't closed")
+	}
+}
+
+func TestPipe(t *testing.T) {
+	boom := errors.New("boom")
+
+	tests := []struct {
+		name    string
+		chunks  [][]byte
+		closeBy error
+		wantErr error
+	}{
+		{name: "empty", wantErr: nil},
+		{name: "chunks", chunks: [][]byte{[]byte("hello "), {}, []byte("world")}},
+		{name: "writer failed", chunks: [][]byte{[]byte("partial")}, closeBy: boom, wantErr: boom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPipe(1)
+			var want []byte
+			go func() {
+				for i, chunk := range tt.chunks {
+					var err error
+					if i%2 == 0 {
+						err = p.Send(chunk)
+					} else {
+						_, err = p.Write(chunk)
+					}
+					if err != nil {
+						t.Errorf("writing chunk %d: %v", i, err)
+					}
+				}
+				p.CloseWithError(tt.closeBy)
+			}()
+			for _, chunk := range tt.chunks {
+				want = append(want, chunk...)
+			}
+
+			got, err := io.ReadAll(p)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadAll() error = %v, want %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("ReadAll() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPipeCloseTwice(t *testing.T) {
+	boom := errors.New("boom")
+
+	tests := []struct {
+		name    string
+		first   error
+		second  error
+		wantErr error
+	}{
+		{name: "error then close", first: boom, second: nil, wantErr: boom},
+		{name: "close then error", first: nil, second: boom, wantErr: nil},
+		{name: "error twice", first: boom, second: errors.New("later"), wantErr: boom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPipe(1)
+			if err := p.Send([]byte("queued")); err != nil {
+				t.Fatal(err)
+			}
+
+			p.CloseWithError(tt.first)
+			p.CloseWithError(tt.second)
+			p.Close()
+
+			got, err := io.ReadAll(p)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadAll() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != "queued" {
+				t.Errorf("ReadAll() = %q, want what was queued", got)
+			}
+		})
+	}
+}
+
+func TestPipeCloseRead(t *testing.T) {
+	p := NewPipe(1)
+	if err := p.Send([]byte("queued")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the queue is full, the next send only returns once the reader gives up
+	sent := make(chan error, 1)
+	go func() { sent <- p.Send([]byte("waiting")) }()
+	p.CloseRead()
+
+	if err := <-sent; !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Send() error = %v, want %v", err, io.ErrClosedPipe)
+	}
+	if _, err := p.Read(make([]byte, 8)); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Read() error = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestGetBuffer(t *testing.T) {
+	tests := []struct {
+		size    int
+		wantCap int
+	}{
+		{size: 0, wantCap: 1},
+		{size: 1, wantCap: 1},
+		{size: 3, wantCap: 4},
+		{size: 64 * 1024, wantCap: 64 * 1024},
+		{size: 1024*1024 + 1, wantCap: 2 * 1024 * 1024},
+	}
+
+	for _, tt := range tests {
+		buf := GetBuffer(tt.size)
+		if len(*buf) != tt.size || cap(*buf) != tt.wantCap {
+			t.Errorf("GetBuffer(%d) has len %d and cap %d, want cap %d", tt.size, len(*buf), cap(*buf), tt.wantCap)
+		}
+		if got := BufferCap(tt.size); got != cap(*buf) {
+			t.Errorf("BufferCap(%d) = %d, want %d", tt.size, got, cap(*buf))
+		}
+		PutBuffer(buf)
+	}
+
+	// a smaller request of the same class gets a buffer of the length asked
+	PutBuffer(GetBuffer(1000))
+	if buf := GetBuffer(600); len(*buf) != 600 || cap(*buf) != 1024 {
+		t.Errorf("GetBuffer(600) has len %d and cap %d", len(*buf), cap(*buf))
+	}
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func slicesEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
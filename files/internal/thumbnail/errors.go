@@ -0,0 +1,9 @@
+package thumbnail
+
+import "errors"
+
+var (
+	ErrUnsupportedType = errors.New("file type has no thumbnails")
+	ErrInvalidImage    = errors.New("failed to decode image")
+	ErrTooLarge        = errors.New("image is too large for thumbnails")
+)
@@ -0,0 +1,132 @@
+package thumbnail
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+
+	"golang.org/x/image/draw"
+
+	// registers the decoders of the supported types with image.Decode
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+)
+
+const jpegQuality = 85
+
+// Size is the longest edge of a thumbnail in pixels.
+type Size int
+
+const (
+	Small  Size = 128
+	Medium Size = 256
+	Large  Size = 512
+)
+
+// Sizes lists every generated size from the largest, each one is scaled
+// down from the one before.
+var Sizes = []Size{Large, Medium, Small}
+
+func (s Size) String() string {
+	return strconv.Itoa(int(s))
+}
+
+type Thumbnail struct {
+	Size        Size
+	ContentType string
+	Content     []byte
+}
+
+// Supported reports whether files of the content type get thumbnails.
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	default:
+		return false
+	}
+}
+
+// Generate decodes the image and returns a thumbnail of every size in Sizes.
+// Images are never scaled up. Opaque thumbnails are JPEG, the others PNG to
+// keep their transparency. Of animated GIFs only the first frame is used.
+// Sources over maxBytes or maxPixels are refused before decoding them.
+func Generate(r io.Reader, maxBytes int64, maxPixels int) ([]Thumbnail, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	conf, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+	if conf.Width*conf.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+
+	// the first step from the full image uses the cheaper kernel
+	scaler := draw.Scaler(draw.BiLinear)
+	thumbnails := make([]Thumbnail, 0, len(Sizes))
+	for _, size := range Sizes {
+		src = scale(src, size, scaler)
+		scaler = draw.CatmullRom
+
+		thumbnail, err := encode(src, size)
+		if err != nil {
+			return nil, err
+		}
+		thumbnails = append(thumbnails, thumbnail)
+	}
+
+	return thumbnails, nil
+}
+
+func scale(src image.Image, size Size, scaler draw.Scaler) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	edge := max(width, height)
+	if edge <= int(size) {
+		return src
+	}
+
+	width = max(1, width*int(size)/edge)
+	height = max(1, height*int(size)/edge)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	scaler.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+	return dst
+}
+
+func encode(img image.Image, size Size) (Thumbnail, error) {
+	var buf bytes.Buffer
+	thumbnail := Thumbnail{Size: size}
+
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		thumbnail.ContentType = "image/jpeg"
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return Thumbnail{}, fmt.Errorf("failed to encode thumbnail: %w", err)
+		}
+	} else {
+		thumbnail.ContentType = "image/png"
+		if err := png.Encode(&buf, img); err != nil {
+			return Thumbnail{}, fmt.Errorf("failed to encode thumbnail: %w", err)
+		}
+	}
+
+	thumbnail.Content = buf.Bytes()
+	return thumbnail, nil
+}
@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/avran02/decoplan/files/internal/config"
+	"github.com/avran02/decoplan/files/internal/events"
+)
+
+// Dispatcher turns file events into deliveries for the owner's webhooks and
+// sends them, retrying with exponential backoff. After MaxAttempts the
+// delivery goes to the dead letters. Pending deliveries are kept in the
+// store, so a restart resumes them.
+type Dispatcher struct {
+	store  *Store
+	sender *Sender
+	hub    *events.Hub
+	conf   config.Webhooks
+
+	mu    sync.Mutex
+	queue map[string]Delivery
+	kick  chan struct{}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	slog.Info("starting webhook dispatcher")
+
+	if err := d.store.Init(ctx, d.conf.LogRetention); err != nil {
+		slog.Error("failed to init webhook store", "error", err)
+	}
+
+	pending, err := d.store.PendingDeliveries(ctx)
+	if err != nil {
+		slog.Error("failed to load pending webhook deliveries", "error", err)
+	}
+	for _, delivery := range pending {
+		d.push(delivery)
+	}
+
+	go d.consume(ctx)
+
+	ticker := time.NewTicker(d.conf.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.kick:
+		}
+	}
+}
+
+// consume queues deliveries for hub events. If the hub drops us for being
+// slow, the events in between are lost and we subscribe again.
+func (d *Dispatcher) consume(ctx context.Context) {
+	for ctx.Err() == nil {
+		sub := d.hub.Subscribe(events.Filter{})
+		for event := range sub.Events() {
+			d.enqueue(ctx, event)
+		}
+
+		if sub.Lost() {
+			slog.Error("webhook dispatcher fell behind file events, some deliveries were skipped")
+		}
+	}
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, event events.Event) {
+	webhooks, err := d.store.ListWebhooks(ctx, event.Bucket)
+	if err != nil {
+		slog.Error("failed to get webhooks", "user", event.Bucket, "error", err)
+		return
+	}
+
+	var body []byte
+	for _, webhook := range webhooks {
+		if !webhook.Wants(event) {
+			continue
+		}
+
+		if body == nil {
+			if body, err = Marshal(uuid.NewString(), event); err != nil {
+				slog.Error("failed to encode file event", "error", err)
+				return
+			}
+		}
+
+		now := time.Now().UTC()
+		delivery := Delivery{
+			ID:            newDeliveryID(now),
+			WebhookID:     webhook.ID,
+			UserID:        webhook.UserID,
+			Kind:          event.Kind,
+			Body:          body,
+			Status:        StatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+
+		if err = d.store.SaveDelivery(ctx, delivery); err != nil {
+			slog.Error("failed to queue webhook delivery", "webhook", webhook.ID, "error", err)
+			continue
+		}
+		d.push(delivery)
+	}
+}
+
+func (d *Dispatcher) push(delivery Delivery) {
+	d.mu.Lock()
+	d.queue[delivery.ID] = delivery
+	d.mu.Unlock()
+
+	select {
+	case d.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) sendDue(ctx context.Context) {
+	now := time.Now().UTC()
+
+	d.mu.Lock()
+	due := make([]Delivery, 0)
+	for id, delivery := range d.queue {
+		if !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+			delete(d.queue, id)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		d.send(ctx, delivery)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery Delivery) {
+	webhook, err := d.store.GetWebhook(ctx, delivery.UserID, delivery.WebhookID)
+	if err != nil {
+		// deleted in the meantime, its deliveries are gone with it
+		slog.Warn("failed to get webhook", "webhook", delivery.WebhookID, "error", err)
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, d.conf.Timeout)
+	result := d.sender.Send(sendCtx, webhook, delivery.ID, delivery.Kind, delivery.Body)
+	cancel()
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastStatusCode = result.StatusCode
+	delivery.UpdatedAt = now
+
+	switch {
+	case result.Err == nil:
+		delivery.Status = StatusDelivered
+		delivery.LastError = ""
+	case int(delivery.Attempts) >= d.conf.MaxAttempts:
+		slog.Warn("webhook delivery dead", "delivery", delivery.ID, "webhook", webhook.ID, "attempts", delivery.Attempts, "error", result.Err)
+		delivery.Status = StatusDead
+		delivery.LastError = result.Err.Error()
+		if err = d.store.SaveDeadLetter(ctx, delivery); err != nil {
+			slog.Error("failed to dead-letter webhook delivery", "delivery", delivery.ID, "error", err)
+		}
+	default:
+		delivery.LastError = result.Err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts - 1))
+		slog.Warn("webhook delivery failed", "delivery", delivery.ID, "webhook", webhook.ID, "attempt", delivery.Attempts, "retryAt", delivery.NextAttemptAt, "error", result.Err)
+	}
+
+	if err = d.store.SaveDelivery(ctx, delivery); err != nil {
+		slog.Error("failed to save webhook delivery", "delivery", delivery.ID, "error", err)
+	}
+
+	if delivery.Status == StatusPending {
+		d.mu.Lock()
+		d.queue[delivery.ID] = delivery
+		d.mu.Unlock()
+	}
+}
+
+func (d *Dispatcher) backoff(attempts int32) time.Duration {
+	delay := d.conf.RetryBaseDelay
+	for i := int32(0); i < attempts && delay < d.conf.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, d.conf.RetryMaxDelay)
+}
+
+// Marshal encodes the event as a webhook body.
+func Marshal(id string, event events.Event) ([]byte, error) {
+	return json.Marshal(body{
+		ID:     id,
+		Kind:   event.Kind,
+		Bucket: event.Bucket,
+		Key:    event.Key,
+		OldKey: event.OldKey,
+		Size:   event.Size,
+		ETag:   event.ETag,
+		Actor:  event.Actor,
+		Time:   event.Time,
+	})
+}
+
+func NewDispatcher(store *Store, sender *Sender, hub *events.Hub, conf config.Webhooks) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		sender: sender,
+		hub:    hub,
+		conf:   conf,
+		queue:  make(map[string]Delivery),
+		kick:   make(chan struct{}, 1),
+	}
+}
@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/avran02/decoplan/files/internal/config"
+	"github.com/avran02/decoplan/files/internal/events"
+)
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := &Dispatcher{conf: config.Webhooks{RetryBaseDelay: 10 * time.Second, RetryMaxDelay: time.Minute}}
+
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{attempts: 0, want: 10 * time.Second},
+		{attempts: 1, want: 20 * time.Second},
+		{attempts: 2, want: 40 * time.Second},
+		{attempts: 3, want: time.Minute},
+		{attempts: 100, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookWants(t *testing.T) {
+	uploaded := events.Event{Kind: events.KindUploaded, Bucket: "alice", Key: "docs/a.txt"}
+	moved := events.Event{Kind: events.KindMoved, Bucket: "alice", Key: "b.txt", OldKey: "docs/b.txt"}
+
+	tests := []struct {
+		name    string
+		webhook Webhook
+		event   events.Event
+		want    bool
+	}{
+		{name: "every kind", webhook: Webhook{UserID: "alice"}, event: uploaded, want: true},
+		{name: "subscribed kind", webhook: Webhook{UserID: "alice", Kinds: []events.Kind{events.KindUploaded}}, event: uploaded, want: true},
+		{name: "other kind", webhook: Webhook{UserID: "alice", Kinds: []events.Kind{events.KindRemoved}}, event: uploaded},
+		{name: "other user", webhook: Webhook{UserID: "bob"}, event: uploaded},
+		{name: "under prefix", webhook: Webhook{UserID: "alice", Prefix: "docs/"}, event: uploaded, want: true},
+		{name: "outside prefix", webhook: Webhook{UserID: "alice", Prefix: "photos/"}, event: uploaded},
+		{name: "moved out of prefix", webhook: Webhook{UserID: "alice", Prefix: "docs/"}, event: moved, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.webhook.Wants(tt.event); got != tt.want {
+				t.Errorf("Wants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
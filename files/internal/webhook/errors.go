@@ -0,0 +1,9 @@
+package webhook
+
+import "errors"
+
+var (
+	ErrUnexpectedStatus = errors.New("unexpected response status")
+	ErrNotFound         = errors.New("webhook not found")
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/avran02/decoplan/files/internal/events"
+)
+
+// KindTest is only sent by TestWebhook.
+const KindTest events.Kind = "test"
+
+type Webhook struct {
+	ID     string        `json:"id"`
+	UserID string        `json:"userID"`
+	URL    string        `json:"url"`
+	Secret string        `json:"secret"`
+	Kinds  []events.Kind `json:"kinds"`
+	// Prefix limits the webhook to keys under it.
+	Prefix    string    `json:"prefix"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Wants reports whether the webhook is subscribed to the event. No kinds
+// means every kind.
+func (w Webhook) Wants(e events.Event) bool {
+	if len(w.Kinds) != 0 && !slices.Contains(w.Kinds, e.Kind) {
+		return false
+	}
+
+	return events.Filter{Bucket: w.UserID, Prefix: w.Prefix}.Match(e)
+}
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	// StatusDead is a delivery that ran out of attempts, it has a copy in
+	// the dead letters.
+	StatusDead Status = "dead"
+)
+
+type Delivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhookID"`
+	UserID         string          `json:"userID"`
+	Kind           events.Kind     `json:"kind"`
+	Body           json.RawMessage `json:"body"`
+	Status         Status          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	LastStatusCode int32           `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
+
+// Result is the outcome of one delivery attempt. StatusCode is zero if no
+// response was received.
+type Result struct {
+	StatusCode int32
+	Err        error
+}
+
+type DeliveryPage struct {
+	Deliveries    []Delivery
+	NextPageToken string
+}
+
+type body struct {
+	ID     string      `json:"id"`
+	Kind   events.Kind `json:"kind"`
+	Bucket string      `json:"bucket"`
+	Key    string      `json:"key"`
+	OldKey string      `json:"oldKey,omitempty"`
+	Size   int64       `json:"size,omitempty"`
+	ETag   string      `json:"etag,omitempty"`
+	Actor  string      `json:"actor"`
+	Time   time.Time   `json:"time"`
+}
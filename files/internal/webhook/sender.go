@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/avran02/decoplan/files/internal/events"
+)
+
+// Sender makes a single signed delivery attempt. Any 2xx response counts as
+// delivered.
+type Sender struct {
+	client *http.Client
+}
+
+func (s *Sender) Send(ctx context.Context, webhook Webhook, deliveryID string, kind events.Kind, body []byte) Result {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return Result{Err: fmt.Errorf("failed to build request: %w", err)}
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, deliveryID)
+	req.Header.Set(HeaderEvent, "file."+string(kind))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Result{Err: fmt.Errorf("failed to send request: %w", err)}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) //nolint:errcheck
+
+	result := Result{StatusCode: int32(resp.StatusCode)}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result.Err = fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+
+	return result
+}
+
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{client: &http.Client{Timeout: timeout}}
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/avran02/decoplan/files/internal/events"
+)
+
+func TestSenderSend(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string
+		status     int
+		wantErr    error
+		wantStatus int32
+	}{
+		{name: "delivered", secret: "s3cret", status: http.StatusNoContent, wantStatus: http.StatusNoContent},
+		{name: "rejected", secret: "s3cret", status: http.StatusInternalServerError, wantErr: ErrUnexpectedStatus, wantStatus: http.StatusInternalServerError},
+		{name: "not modified isn't delivered", secret: "other", status: http.StatusNotModified, wantErr: ErrUnexpectedStatus, wantStatus: http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(`{"id":"event"}`)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("failed to read body: %v", err)
+				}
+
+				timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+				if err != nil {
+					t.Errorf("bad %s header: %v", HeaderTimestamp, err)
+				}
+				if age := time.Since(time.Unix(timestamp, 0)); age < 0 || age > time.Minute {
+					t.Errorf("stale timestamp %d", timestamp)
+				}
+				if !Verify(tt.secret, timestamp, got, r.Header.Get(HeaderSignature)) {
+					t.Errorf("signature %q doesn't verify", r.Header.Get(HeaderSignature))
+				}
+				if id := r.Header.Get(HeaderID); id != "delivery" {
+					t.Errorf("%s = %q, want delivery", HeaderID, id)
+				}
+				if event := r.Header.Get(HeaderEvent); event != "file.uploaded" {
+					t.Errorf("%s = %q, want %s", HeaderEvent, event, "file.uploaded")
+				}
+
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			res := NewSender(time.Second).Send(context.Background(), Webhook{URL: srv.URL, Secret: tt.secret}, "delivery", events.KindUploaded, body)
+			if !errors.Is(res.Err, tt.wantErr) {
+				t.Errorf("Send() error = %v, want %v", res.Err, tt.wantErr)
+			}
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("Send() status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSenderSendUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	res := NewSender(time.Second).Send(context.Background(), Webhook{URL: srv.URL, Secret: "s3cret"}, "delivery", events.KindUploaded, nil)
+	if res.Err == nil || res.StatusCode != 0 {
+		t.Errorf("Send() = %+v, want an error without status", res)
+	}
+}
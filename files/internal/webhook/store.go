@@ -0,0 +1,272 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+
+	"github.com/avran02/decoplan/files/internal/config"
+)
+
+const (
+	webhooksPrefix    = "webhooks/"
+	deliveriesPrefix  = "deliveries/"
+	deadLettersPrefix = "dead-letters/"
+	// pendingPrefix holds an empty marker per undelivered delivery, so the
+	// dispatcher can pick them up again after a restart.
+	pendingPrefix = "pending/"
+)
+
+// Store keeps webhooks, the delivery log and dead letters as JSON objects in
+// a system bucket, since the files service has no database.
+type Store struct {
+	minio  *minio.Client
+	bucket string
+}
+
+// Init creates the bucket and expires delivery logs after the retention.
+// Dead letters are kept until the webhook is deleted.
+func (s *Store) Init(ctx context.Context, retention time.Duration) error {
+	exists, err := s.minio.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to check if webhooks bucket exists: %w", err)
+	}
+
+	if !exists {
+		if err = s.minio.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{Region: config.DefaultLocation}); err != nil {
+			return fmt.Errorf("failed to create webhooks bucket: %w", err)
+		}
+	}
+
+	rules := lifecycle.NewConfiguration()
+	rules.Rules = []lifecycle.Rule{{
+		ID:         "expire-deliveries",
+		Status:     "Enabled",
+		RuleFilter: lifecycle.Filter{Prefix: deliveriesPrefix},
+		Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(max(1, int(retention.Hours()/24)))},
+	}}
+	if err = s.minio.SetBucketLifecycle(ctx, s.bucket, rules); err != nil {
+		return fmt.Errorf("failed to set webhooks bucket lifecycle: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, webhook Webhook) error {
+	return s.put(ctx, webhookKey(webhook.UserID, webhook.ID), webhook)
+}
+
+func (s *Store) GetWebhook(ctx context.Context, userID, webhookID string) (Webhook, error) {
+	var webhook Webhook
+	if err := s.get(ctx, webhookKey(userID, webhookID), &webhook); err != nil {
+		return Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func (s *Store) ListWebhooks(ctx context.Context, userID string) ([]Webhook, error) {
+	webhooks := make([]Webhook, 0)
+	for object := range s.minio.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: webhooksPrefix + userID + "/"}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list webhooks: %w", object.Err)
+		}
+
+		var webhook Webhook
+		if err := s.get(ctx, object.Key, &webhook); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook with its deliveries and dead letters.
+func (s *Store) DeleteWebhook(ctx context.Context, userID, webhookID string) error {
+	if _, err := s.GetWebhook(ctx, userID, webhookID); err != nil {
+		return err
+	}
+
+	if err := s.minio.RemoveObject(ctx, s.bucket, webhookKey(userID, webhookID), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	for _, prefix := range []string{deliveriesPrefix, deadLettersPrefix, pendingPrefix} {
+		if err := s.removePrefix(ctx, prefix+webhookID+"/"); err != nil {
+			slog.Warn("failed to remove webhook objects", "webhook", webhookID, "prefix", prefix, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveDelivery writes the delivery to the log and keeps its pending marker
+// in line with its status.
+func (s *Store) SaveDelivery(ctx context.Context, delivery Delivery) error {
+	if err := s.put(ctx, deliveryKey(deliveriesPrefix, delivery), delivery); err != nil {
+		return err
+	}
+
+	marker := deliveryKey(pendingPrefix, delivery)
+	if delivery.Status == StatusPending {
+		_, err := s.minio.PutObject(ctx, s.bucket, marker, bytes.NewReader(nil), 0, minio.PutObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to mark delivery pending: %w", err)
+		}
+		return nil
+	}
+
+	if err := s.minio.RemoveObject(ctx, s.bucket, marker, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to unmark pending delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) SaveDeadLetter(ctx context.Context, delivery Delivery) error {
+	return s.put(ctx, deliveryKey(deadLettersPrefix, delivery), delivery)
+}
+
+// PendingDeliveries returns every delivery that still has to be sent.
+func (s *Store) PendingDeliveries(ctx context.Context) ([]Delivery, error) {
+	deliveries := make([]Delivery, 0)
+	for object := range s.minio.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: pendingPrefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list pending deliveries: %w", object.Err)
+		}
+
+		var delivery Delivery
+		key := deliveriesPrefix + strings.TrimPrefix(object.Key, pendingPrefix)
+		if err := s.get(ctx, key, &delivery); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// ListDeliveries returns the webhook's deliveries from the newest. Delivery
+// IDs sort in reverse creation order, so this is a plain listing.
+func (s *Store) ListDeliveries(ctx context.Context, webhookID string, pageSize int, pageToken string) (DeliveryPage, error) {
+	opts := minio.ListObjectsOptions{Prefix: deliveriesPrefix + webhookID + "/"}
+	if pageToken != "" {
+		startAfter, err := base64.RawURLEncoding.DecodeString(pageToken)
+		if err != nil || !strings.HasPrefix(string(startAfter), opts.Prefix) {
+			return DeliveryPage{}, ErrInvalidPageToken
+		}
+		opts.StartAfter = string(startAfter)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	page := DeliveryPage{Deliveries: make([]Delivery, 0, pageSize)}
+	for object := range s.minio.ListObjects(ctx, s.bucket, opts) {
+		if object.Err != nil {
+			return DeliveryPage{}, fmt.Errorf("failed to list deliveries: %w", object.Err)
+		}
+
+		if len(page.Deliveries) == pageSize {
+			last := page.Deliveries[pageSize-1]
+			page.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(deliveryKey(deliveriesPrefix, last)))
+			break
+		}
+
+		var delivery Delivery
+		if err := s.get(ctx, object.Key, &delivery); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return DeliveryPage{}, err
+		}
+		page.Deliveries = append(page.Deliveries, delivery)
+	}
+
+	return page, nil
+}
+
+func (s *Store) put(ctx context.Context, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+
+	_, err = s.minio.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/json",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *Store) get(ctx context.Context, key string, v any) error {
+	object, err := s.minio.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	defer object.Close()
+
+	if err = json.NewDecoder(object).Decode(v); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *Store) removePrefix(ctx context.Context, prefix string) error {
+	objects := s.minio.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true})
+
+	var err error
+	for rErr := range s.minio.RemoveObjects(ctx, s.bucket, objects, minio.RemoveObjectsOptions{}) {
+		if err == nil {
+			err = fmt.Errorf("failed to remove object %s: %w", rErr.ObjectName, rErr.Err)
+		}
+	}
+
+	return err
+}
+
+func webhookKey(userID, webhookID string) string {
+	return path.Join(webhooksPrefix, userID, webhookID+".json")
+}
+
+func deliveryKey(prefix string, delivery Delivery) string {
+	return prefix + path.Join(delivery.WebhookID, delivery.ID+".json")
+}
+
+// newDeliveryID makes IDs that sort from the newest delivery to the oldest.
+func newDeliveryID(now time.Time) string {
+	return fmt.Sprintf("%019d-%s", math.MaxInt64-now.UnixNano(), uuid.NewString()[:8])
+}
+
+func NewStore(client *minio.Client, bucket string) *Store {
+	return &Store{
+		minio:  client,
+		bucket: bucket,
+	}
+}
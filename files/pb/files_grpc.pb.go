@@ -19,11 +19,27 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	FileService_ListFiles_FullMethodName    = "/service.FileService/ListFiles"
-	FileService_RegisterUser_FullMethodName = "/service.FileService/RegisterUser"
-	FileService_RemoveFile_FullMethodName   = "/service.FileService/RemoveFile"
-	FileService_DownloadFile_FullMethodName = "/service.FileService/DownloadFile"
-	FileService_UploadFile_FullMethodName   = "/service.FileService/UploadFile"
+	FileService_ListFiles_FullMethodName                = "/service.FileService/ListFiles"
+	FileService_RegisterUser_FullMethodName             = "/service.FileService/RegisterUser"
+	FileService_UnregisterUser_FullMethodName           = "/service.FileService/UnregisterUser"
+	FileService_RemoveFile_FullMethodName               = "/service.FileService/RemoveFile"
+	FileService_StatFile_FullMethodName                 = "/service.FileService/StatFile"
+	FileService_MoveFile_FullMethodName                 = "/service.FileService/MoveFile"
+	FileService_BatchOperation_FullMethodName           = "/service.FileService/BatchOperation"
+	FileService_GetUsage_FullMethodName                 = "/service.FileService/GetUsage"
+	FileService_GetThumbnail_FullMethodName             = "/service.FileService/GetThumbnail"
+	FileService_GetUploadProgress_FullMethodName        = "/service.FileService/GetUploadProgress"
+	FileService_DownloadFile_FullMethodName             = "/service.FileService/DownloadFile"
+	FileService_DownloadFileWithProgress_FullMethodName = "/service.FileService/DownloadFileWithProgress"
+	FileService_DownloadArchive_FullMethodName          = "/service.FileService/DownloadArchive"
+	FileService_UploadFile_FullMethodName               = "/service.FileService/UploadFile"
+	FileService_UploadFileWithProgress_FullMethodName   = "/service.FileService/UploadFileWithProgress"
+	FileService_WatchFiles_FullMethodName               = "/service.FileService/WatchFiles"
+	FileService_CreateWebhook_FullMethodName            = "/service.FileService/CreateWebhook"
+	FileService_ListWebhooks_FullMethodName             = "/service.FileService/ListWebhooks"
+	FileService_DeleteWebhook_FullMethodName            = "/service.FileService/DeleteWebhook"
+	FileService_TestWebhook_FullMethodName              = "/service.FileService/TestWebhook"
+	FileService_ListWebhookDeliveries_FullMethodName    = "/service.FileService/ListWebhookDeliveries"
 )
 
 // FileServiceClient is the client API for FileService service.
@@ -32,9 +48,25 @@ const (
 type FileServiceClient interface {
 	ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error)
 	RegisterUser(ctx context.Context, in *RegisterUserRequest, opts ...grpc.CallOption) (*RegisterUserResponse, error)
+	UnregisterUser(ctx context.Context, in *UnregisterUserRequest, opts ...grpc.CallOption) (*UnregisterUserResponse, error)
 	RemoveFile(ctx context.Context, in *RemoveFileRequest, opts ...grpc.CallOption) (*RemoveFileResponse, error)
+	StatFile(ctx context.Context, in *StatFileRequest, opts ...grpc.CallOption) (*StatFileResponse, error)
+	MoveFile(ctx context.Context, in *MoveFileRequest, opts ...grpc.CallOption) (*MoveFileResponse, error)
+	BatchOperation(ctx context.Context, in *BatchOperationRequest, opts ...grpc.CallOption) (FileService_BatchOperationClient, error)
+	GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error)
+	GetThumbnail(ctx context.Context, in *GetThumbnailRequest, opts ...grpc.CallOption) (*GetThumbnailResponse, error)
+	GetUploadProgress(ctx context.Context, in *GetUploadProgressRequest, opts ...grpc.CallOption) (*GetUploadProgressResponse, error)
 	DownloadFile(ctx context.Context, in *DownloadFileRequest, opts ...grpc.CallOption) (FileService_DownloadFileClient, error)
+	DownloadFileWithProgress(ctx context.Context, in *DownloadFileRequest, opts ...grpc.CallOption) (FileService_DownloadFileWithProgressClient, error)
+	DownloadArchive(ctx context.Context, in *DownloadArchiveRequest, opts ...grpc.CallOption) (FileService_DownloadArchiveClient, error)
 	UploadFile(ctx context.Context, opts ...grpc.CallOption) (FileService_UploadFileClient, error)
+	UploadFileWithProgress(ctx context.Context, opts ...grpc.CallOption) (FileService_UploadFileWithProgressClient, error)
+	WatchFiles(ctx context.Context, in *WatchFilesRequest, opts ...grpc.CallOption) (FileService_WatchFilesClient, error)
+	CreateWebhook(ctx context.Context, in *CreateWebhookRequest, opts ...grpc.CallOption) (*CreateWebhookResponse, error)
+	ListWebhooks(ctx context.Context, in *ListWebhooksRequest, opts ...grpc.CallOption) (*ListWebhooksResponse, error)
+	DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error)
+	TestWebhook(ctx context.Context, in *TestWebhookRequest, opts ...grpc.CallOption) (*TestWebhookResponse, error)
+	ListWebhookDeliveries(ctx context.Context, in *ListWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ListWebhookDeliveriesResponse, error)
 }
 
 type fileServiceClient struct {
@@ -63,6 +95,15 @@ func (c *fileServiceClient) RegisterUser(ctx context.Context, in *RegisterUserRe
 	return out, nil
 }
 
+func (c *fileServiceClient) UnregisterUser(ctx context.Context, in *UnregisterUserRequest, opts ...grpc.CallOption) (*UnregisterUserResponse, error) {
+	out := new(UnregisterUserResponse)
+	err := c.cc.Invoke(ctx, FileService_UnregisterUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *fileServiceClient) RemoveFile(ctx context.Context, in *RemoveFileRequest, opts ...grpc.CallOption) (*RemoveFileResponse, error) {
 	out := new(RemoveFileResponse)
 	err := c.cc.Invoke(ctx, FileService_RemoveFile_FullMethodName, in, out, opts...)
@@ -72,8 +113,85 @@ func (c *fileServiceClient) RemoveFile(ctx context.Context, in *RemoveFileReques
 	return out, nil
 }
 
+func (c *fileServiceClient) StatFile(ctx context.Context, in *StatFileRequest, opts ...grpc.CallOption) (*StatFileResponse, error) {
+	out := new(StatFileResponse)
+	err := c.cc.Invoke(ctx, FileService_StatFile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) MoveFile(ctx context.Context, in *MoveFileRequest, opts ...grpc.CallOption) (*MoveFileResponse, error) {
+	out := new(MoveFileResponse)
+	err := c.cc.Invoke(ctx, FileService_MoveFile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) BatchOperation(ctx context.Context, in *BatchOperationRequest, opts ...grpc.CallOption) (FileService_BatchOperationClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[0], FileService_BatchOperation_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileServiceBatchOperationClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FileService_BatchOperationClient interface {
+	Recv() (*BatchOperationResult, error)
+	grpc.ClientStream
+}
+
+type fileServiceBatchOperationClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileServiceBatchOperationClient) Recv() (*BatchOperationResult, error) {
+	m := new(BatchOperationResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *fileServiceClient) GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error) {
+	out := new(GetUsageResponse)
+	err := c.cc.Invoke(ctx, FileService_GetUsage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) GetThumbnail(ctx context.Context, in *GetThumbnailRequest, opts ...grpc.CallOption) (*GetThumbnailResponse, error) {
+	out := new(GetThumbnailResponse)
+	err := c.cc.Invoke(ctx, FileService_GetThumbnail_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) GetUploadProgress(ctx context.Context, in *GetUploadProgressRequest, opts ...grpc.CallOption) (*GetUploadProgressResponse, error) {
+	out := new(GetUploadProgressResponse)
+	err := c.cc.Invoke(ctx, FileService_GetUploadProgress_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *fileServiceClient) DownloadFile(ctx context.Context, in *DownloadFileRequest, opts ...grpc.CallOption) (FileService_DownloadFileClient, error) {
-	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[0], FileService_DownloadFile_FullMethodName, opts...)
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[1], FileService_DownloadFile_FullMethodName, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -104,8 +222,72 @@ func (x *fileServiceDownloadFileClient) Recv() (*DownloadFileResponse, error) {
 	return m, nil
 }
 
+func (c *fileServiceClient) DownloadFileWithProgress(ctx context.Context, in *DownloadFileRequest, opts ...grpc.CallOption) (FileService_DownloadFileWithProgressClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[2], FileService_DownloadFileWithProgress_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileServiceDownloadFileWithProgressClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FileService_DownloadFileWithProgressClient interface {
+	Recv() (*DownloadFileWithProgressResponse, error)
+	grpc.ClientStream
+}
+
+type fileServiceDownloadFileWithProgressClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileServiceDownloadFileWithProgressClient) Recv() (*DownloadFileWithProgressResponse, error) {
+	m := new(DownloadFileWithProgressResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *fileServiceClient) DownloadArchive(ctx context.Context, in *DownloadArchiveRequest, opts ...grpc.CallOption) (FileService_DownloadArchiveClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[3], FileService_DownloadArchive_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileServiceDownloadArchiveClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FileService_DownloadArchiveClient interface {
+	Recv() (*DownloadArchiveResponse, error)
+	grpc.ClientStream
+}
+
+type fileServiceDownloadArchiveClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileServiceDownloadArchiveClient) Recv() (*DownloadArchiveResponse, error) {
+	m := new(DownloadArchiveResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *fileServiceClient) UploadFile(ctx context.Context, opts ...grpc.CallOption) (FileService_UploadFileClient, error) {
-	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[1], FileService_UploadFile_FullMethodName, opts...)
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[4], FileService_UploadFile_FullMethodName, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -138,15 +320,139 @@ func (x *fileServiceUploadFileClient) CloseAndRecv() (*UploadFileResponse, error
 	return m, nil
 }
 
+func (c *fileServiceClient) UploadFileWithProgress(ctx context.Context, opts ...grpc.CallOption) (FileService_UploadFileWithProgressClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[5], FileService_UploadFileWithProgress_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileServiceUploadFileWithProgressClient{stream}
+	return x, nil
+}
+
+type FileService_UploadFileWithProgressClient interface {
+	Send(*UploadFileRequest) error
+	Recv() (*UploadFileWithProgressResponse, error)
+	grpc.ClientStream
+}
+
+type fileServiceUploadFileWithProgressClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileServiceUploadFileWithProgressClient) Send(m *UploadFileRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *fileServiceUploadFileWithProgressClient) Recv() (*UploadFileWithProgressResponse, error) {
+	m := new(UploadFileWithProgressResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *fileServiceClient) WatchFiles(ctx context.Context, in *WatchFilesRequest, opts ...grpc.CallOption) (FileService_WatchFilesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[6], FileService_WatchFiles_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileServiceWatchFilesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FileService_WatchFilesClient interface {
+	Recv() (*FileEvent, error)
+	grpc.ClientStream
+}
+
+type fileServiceWatchFilesClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileServiceWatchFilesClient) Recv() (*FileEvent, error) {
+	m := new(FileEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *fileServiceClient) CreateWebhook(ctx context.Context, in *CreateWebhookRequest, opts ...grpc.CallOption) (*CreateWebhookResponse, error) {
+	out := new(CreateWebhookResponse)
+	err := c.cc.Invoke(ctx, FileService_CreateWebhook_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) ListWebhooks(ctx context.Context, in *ListWebhooksRequest, opts ...grpc.CallOption) (*ListWebhooksResponse, error) {
+	out := new(ListWebhooksResponse)
+	err := c.cc.Invoke(ctx, FileService_ListWebhooks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error) {
+	out := new(DeleteWebhookResponse)
+	err := c.cc.Invoke(ctx, FileService_DeleteWebhook_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) TestWebhook(ctx context.Context, in *TestWebhookRequest, opts ...grpc.CallOption) (*TestWebhookResponse, error) {
+	out := new(TestWebhookResponse)
+	err := c.cc.Invoke(ctx, FileService_TestWebhook_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) ListWebhookDeliveries(ctx context.Context, in *ListWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ListWebhookDeliveriesResponse, error) {
+	out := new(ListWebhookDeliveriesResponse)
+	err := c.cc.Invoke(ctx, FileService_ListWebhookDeliveries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FileServiceServer is the server API for FileService service.
 // All implementations must embed UnimplementedFileServiceServer
 // for forward compatibility
 type FileServiceServer interface {
 	ListFiles(context.Context, *ListFilesRequest) (*ListFilesResponse, error)
 	RegisterUser(context.Context, *RegisterUserRequest) (*RegisterUserResponse, error)
+	UnregisterUser(context.Context, *UnregisterUserRequest) (*UnregisterUserResponse, error)
 	RemoveFile(context.Context, *RemoveFileRequest) (*RemoveFileResponse, error)
+	StatFile(context.Context, *StatFileRequest) (*StatFileResponse, error)
+	MoveFile(context.Context, *MoveFileRequest) (*MoveFileResponse, error)
+	BatchOperation(*BatchOperationRequest, FileService_BatchOperationServer) error
+	GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error)
+	GetThumbnail(context.Context, *GetThumbnailRequest) (*GetThumbnailResponse, error)
+	GetUploadProgress(context.Context, *GetUploadProgressRequest) (*GetUploadProgressResponse, error)
 	DownloadFile(*DownloadFileRequest, FileService_DownloadFileServer) error
+	DownloadFileWithProgress(*DownloadFileRequest, FileService_DownloadFileWithProgressServer) error
+	DownloadArchive(*DownloadArchiveRequest, FileService_DownloadArchiveServer) error
 	UploadFile(FileService_UploadFileServer) error
+	UploadFileWithProgress(FileService_UploadFileWithProgressServer) error
+	WatchFiles(*WatchFilesRequest, FileService_WatchFilesServer) error
+	CreateWebhook(context.Context, *CreateWebhookRequest) (*CreateWebhookResponse, error)
+	ListWebhooks(context.Context, *ListWebhooksRequest) (*ListWebhooksResponse, error)
+	DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error)
+	TestWebhook(context.Context, *TestWebhookRequest) (*TestWebhookResponse, error)
+	ListWebhookDeliveries(context.Context, *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error)
 	mustEmbedUnimplementedFileServiceServer()
 }
 
@@ -160,15 +466,63 @@ func (UnimplementedFileServiceServer) ListFiles(context.Context, *ListFilesReque
 func (UnimplementedFileServiceServer) RegisterUser(context.Context, *RegisterUserRequest) (*RegisterUserResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RegisterUser not implemented")
 }
+func (UnimplementedFileServiceServer) UnregisterUser(context.Context, *UnregisterUserRequest) (*UnregisterUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnregisterUser not implemented")
+}
 func (UnimplementedFileServiceServer) RemoveFile(context.Context, *RemoveFileRequest) (*RemoveFileResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveFile not implemented")
 }
+func (UnimplementedFileServiceServer) StatFile(context.Context, *StatFileRequest) (*StatFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StatFile not implemented")
+}
+func (UnimplementedFileServiceServer) MoveFile(context.Context, *MoveFileRequest) (*MoveFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveFile not implemented")
+}
+func (UnimplementedFileServiceServer) BatchOperation(*BatchOperationRequest, FileService_BatchOperationServer) error {
+	return status.Errorf(codes.Unimplemented, "method BatchOperation not implemented")
+}
+func (UnimplementedFileServiceServer) GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsage not implemented")
+}
+func (UnimplementedFileServiceServer) GetThumbnail(context.Context, *GetThumbnailRequest) (*GetThumbnailResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetThumbnail not implemented")
+}
+func (UnimplementedFileServiceServer) GetUploadProgress(context.Context, *GetUploadProgressRequest) (*GetUploadProgressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUploadProgress not implemented")
+}
 func (UnimplementedFileServiceServer) DownloadFile(*DownloadFileRequest, FileService_DownloadFileServer) error {
 	return status.Errorf(codes.Unimplemented, "method DownloadFile not implemented")
 }
+func (UnimplementedFileServiceServer) DownloadFileWithProgress(*DownloadFileRequest, FileService_DownloadFileWithProgressServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadFileWithProgress not implemented")
+}
+func (UnimplementedFileServiceServer) DownloadArchive(*DownloadArchiveRequest, FileService_DownloadArchiveServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadArchive not implemented")
+}
 func (UnimplementedFileServiceServer) UploadFile(FileService_UploadFileServer) error {
 	return status.Errorf(codes.Unimplemented, "method UploadFile not implemented")
 }
+func (UnimplementedFileServiceServer) UploadFileWithProgress(FileService_UploadFileWithProgressServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadFileWithProgress not implemented")
+}
+func (UnimplementedFileServiceServer) WatchFiles(*WatchFilesRequest, FileService_WatchFilesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchFiles not implemented")
+}
+func (UnimplementedFileServiceServer) CreateWebhook(context.Context, *CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWebhook not implemented")
+}
+func (UnimplementedFileServiceServer) ListWebhooks(context.Context, *ListWebhooksRequest) (*ListWebhooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhooks not implemented")
+}
+func (UnimplementedFileServiceServer) DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhook not implemented")
+}
+func (UnimplementedFileServiceServer) TestWebhook(context.Context, *TestWebhookRequest) (*TestWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TestWebhook not implemented")
+}
+func (UnimplementedFileServiceServer) ListWebhookDeliveries(context.Context, *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhookDeliveries not implemented")
+}
 func (UnimplementedFileServiceServer) mustEmbedUnimplementedFileServiceServer() {}
 
 // UnsafeFileServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -218,6 +572,24 @@ func _FileService_RegisterUser_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FileService_UnregisterUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnregisterUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).UnregisterUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_UnregisterUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).UnregisterUser(ctx, req.(*UnregisterUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _FileService_RemoveFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RemoveFileRequest)
 	if err := dec(in); err != nil {
@@ -236,6 +608,117 @@ func _FileService_RemoveFile_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FileService_StatFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).StatFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_StatFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).StatFile(ctx, req.(*StatFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_MoveFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).MoveFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_MoveFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).MoveFile(ctx, req.(*MoveFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_BatchOperation_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BatchOperationRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileServiceServer).BatchOperation(m, &fileServiceBatchOperationServer{stream})
+}
+
+type FileService_BatchOperationServer interface {
+	Send(*BatchOperationResult) error
+	grpc.ServerStream
+}
+
+type fileServiceBatchOperationServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileServiceBatchOperationServer) Send(m *BatchOperationResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _FileService_GetUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).GetUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_GetUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).GetUsage(ctx, req.(*GetUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_GetThumbnail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetThumbnailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).GetThumbnail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_GetThumbnail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).GetThumbnail(ctx, req.(*GetThumbnailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_GetUploadProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUploadProgressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).GetUploadProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_GetUploadProgress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).GetUploadProgress(ctx, req.(*GetUploadProgressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _FileService_DownloadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(DownloadFileRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -257,6 +740,48 @@ func (x *fileServiceDownloadFileServer) Send(m *DownloadFileResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _FileService_DownloadFileWithProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadFileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileServiceServer).DownloadFileWithProgress(m, &fileServiceDownloadFileWithProgressServer{stream})
+}
+
+type FileService_DownloadFileWithProgressServer interface {
+	Send(*DownloadFileWithProgressResponse) error
+	grpc.ServerStream
+}
+
+type fileServiceDownloadFileWithProgressServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileServiceDownloadFileWithProgressServer) Send(m *DownloadFileWithProgressResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _FileService_DownloadArchive_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadArchiveRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileServiceServer).DownloadArchive(m, &fileServiceDownloadArchiveServer{stream})
+}
+
+type FileService_DownloadArchiveServer interface {
+	Send(*DownloadArchiveResponse) error
+	grpc.ServerStream
+}
+
+type fileServiceDownloadArchiveServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileServiceDownloadArchiveServer) Send(m *DownloadArchiveResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _FileService_UploadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(FileServiceServer).UploadFile(&fileServiceUploadFileServer{stream})
 }
@@ -283,6 +808,143 @@ func (x *fileServiceUploadFileServer) Recv() (*UploadFileRequest, error) {
 	return m, nil
 }
 
+func _FileService_UploadFileWithProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FileServiceServer).UploadFileWithProgress(&fileServiceUploadFileWithProgressServer{stream})
+}
+
+type FileService_UploadFileWithProgressServer interface {
+	Send(*UploadFileWithProgressResponse) error
+	Recv() (*UploadFileRequest, error)
+	grpc.ServerStream
+}
+
+type fileServiceUploadFileWithProgressServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileServiceUploadFileWithProgressServer) Send(m *UploadFileWithProgressResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *fileServiceUploadFileWithProgressServer) Recv() (*UploadFileRequest, error) {
+	m := new(UploadFileRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _FileService_WatchFiles_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchFilesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileServiceServer).WatchFiles(m, &fileServiceWatchFilesServer{stream})
+}
+
+type FileService_WatchFilesServer interface {
+	Send(*FileEvent) error
+	grpc.ServerStream
+}
+
+type fileServiceWatchFilesServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileServiceWatchFilesServer) Send(m *FileEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _FileService_CreateWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).CreateWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_CreateWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).CreateWebhook(ctx, req.(*CreateWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_ListWebhooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhooksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).ListWebhooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_ListWebhooks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).ListWebhooks(ctx, req.(*ListWebhooksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_DeleteWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).DeleteWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_DeleteWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).DeleteWebhook(ctx, req.(*DeleteWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_TestWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TestWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).TestWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_TestWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).TestWebhook(ctx, req.(*TestWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_ListWebhookDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhookDeliveriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).ListWebhookDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_ListWebhookDeliveries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).ListWebhookDeliveries(ctx, req.(*ListWebhookDeliveriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // FileService_ServiceDesc is the grpc.ServiceDesc for FileService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -298,22 +960,92 @@ var FileService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RegisterUser",
 			Handler:    _FileService_RegisterUser_Handler,
 		},
+		{
+			MethodName: "UnregisterUser",
+			Handler:    _FileService_UnregisterUser_Handler,
+		},
 		{
 			MethodName: "RemoveFile",
 			Handler:    _FileService_RemoveFile_Handler,
 		},
+		{
+			MethodName: "StatFile",
+			Handler:    _FileService_StatFile_Handler,
+		},
+		{
+			MethodName: "MoveFile",
+			Handler:    _FileService_MoveFile_Handler,
+		},
+		{
+			MethodName: "GetUsage",
+			Handler:    _FileService_GetUsage_Handler,
+		},
+		{
+			MethodName: "GetThumbnail",
+			Handler:    _FileService_GetThumbnail_Handler,
+		},
+		{
+			MethodName: "GetUploadProgress",
+			Handler:    _FileService_GetUploadProgress_Handler,
+		},
+		{
+			MethodName: "CreateWebhook",
+			Handler:    _FileService_CreateWebhook_Handler,
+		},
+		{
+			MethodName: "ListWebhooks",
+			Handler:    _FileService_ListWebhooks_Handler,
+		},
+		{
+			MethodName: "DeleteWebhook",
+			Handler:    _FileService_DeleteWebhook_Handler,
+		},
+		{
+			MethodName: "TestWebhook",
+			Handler:    _FileService_TestWebhook_Handler,
+		},
+		{
+			MethodName: "ListWebhookDeliveries",
+			Handler:    _FileService_ListWebhookDeliveries_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchOperation",
+			Handler:       _FileService_BatchOperation_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "DownloadFile",
 			Handler:       _FileService_DownloadFile_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "DownloadFileWithProgress",
+			Handler:       _FileService_DownloadFileWithProgress_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "DownloadArchive",
+			Handler:       _FileService_DownloadArchive_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "UploadFile",
 			Handler:       _FileService_UploadFile_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "UploadFileWithProgress",
+			Handler:       _FileService_UploadFileWithProgress_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchFiles",
+			Handler:       _FileService_WatchFiles_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "files.proto",
 }
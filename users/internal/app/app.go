@@ -1,15 +1,20 @@
 package app
 
 import (
+	"context"
 	"log/slog"
 	"net"
 	"os"
 
 	"github.com/avran02/decoplan/users/internal/config"
 	"github.com/avran02/decoplan/users/internal/controller"
+	"github.com/avran02/decoplan/users/internal/events"
+	"github.com/avran02/decoplan/users/internal/files"
+	"github.com/avran02/decoplan/users/internal/lifecycle"
 	"github.com/avran02/decoplan/users/internal/repository"
 	"github.com/avran02/decoplan/users/internal/server"
 	"github.com/avran02/decoplan/users/internal/service"
+	"github.com/avran02/decoplan/users/internal/webhook"
 	"github.com/avran02/decoplan/users/logger"
 	"github.com/avran02/decoplan/users/pb"
 
@@ -21,8 +26,11 @@ import (
 var opts []grpc.ServerOption
 
 type App struct {
-	Config *config.Config
-	Server server.UsersServer
+	Config    *config.Config
+	Server    server.UsersServer
+	Lifecycle lifecycle.Coordinator
+	Relay     *events.Relay
+	Webhooks  *webhook.Dispatcher
 }
 
 func (app *App) Run() {
@@ -35,6 +43,10 @@ func (app *App) Run() {
 
 	slog.Info("Listening on " + host)
 
+	go app.Lifecycle.Run(context.Background())
+	go app.Relay.Run(context.Background())
+	go app.Webhooks.Run(context.Background())
+
 	grpcServer := grpc.NewServer(opts...)
 	pb.RegisterUsersServiceServer(grpcServer, app.Server)
 
@@ -53,12 +65,21 @@ func New() *App {
 	conf := config.New()
 	logger.Setup(conf.Server)
 	repository := repository.New(conf.DB)
-	service := service.New(repository)
+	files := files.New(conf.FilesService)
+	lifecycle := lifecycle.New(repository, files, conf.Lifecycle, conf.Avatars.Bucket)
+	hub := events.NewHub()
+	sender := webhook.NewSender(conf.Webhooks.Timeout)
+	webhooks := webhook.NewDispatcher(repository, sender, conf.Webhooks)
+	relay := events.NewRelay(repository, events.MultiSink{webhooks, hub}, conf.Outbox)
+	service := service.New(repository, files, lifecycle, hub, sender, conf)
 	controller := controller.New(service)
 	server := server.New(controller)
 
 	return &App{
-		Config: conf,
-		Server: server,
+		Config:    conf,
+		Server:    server,
+		Lifecycle: lifecycle,
+		Relay:     relay,
+		Webhooks:  webhooks,
 	}
 }
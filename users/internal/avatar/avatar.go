@@ -0,0 +1,97 @@
+package avatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var formats = map[string]string{
+	"jpeg": "jpg",
+	"png":  "png",
+	"gif":  "gif",
+	"webp": "webp",
+}
+
+type Limits struct {
+	MaxSize      int64
+	MaxDimension int
+	MinDimension int
+}
+
+type Image struct {
+	Format     string
+	Extension  string
+	Original   []byte
+	Thumbnails map[int][]byte
+}
+
+// Process validates an uploaded avatar and renders square PNG thumbnails of
+// the given sizes. Only the header is decoded until the image passes the
+// size and dimension checks.
+func Process(data []byte, limits Limits, sizes []int) (Image, error) {
+	if int64(len(data)) > limits.MaxSize {
+		return Image{}, ErrTooLarge
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrUnsupportedFormat
+	}
+
+	ext, ok := formats[format]
+	if !ok {
+		return Image{}, ErrUnsupportedFormat
+	}
+
+	if cfg.Width > limits.MaxDimension || cfg.Height > limits.MaxDimension ||
+		cfg.Width < limits.MinDimension || cfg.Height < limits.MinDimension {
+		return Image{}, ErrInvalidDimensions
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("%w: %w", ErrUnsupportedFormat, err)
+	}
+
+	thumbnails := make(map[int][]byte, len(sizes))
+	for _, size := range sizes {
+		thumb, err := thumbnail(img, size)
+		if err != nil {
+			return Image{}, err
+		}
+		thumbnails[size] = thumb
+	}
+
+	return Image{
+		Format:     format,
+		Extension:  ext,
+		Original:   data,
+		Thumbnails: thumbnails,
+	}, nil
+}
+
+// thumbnail crops the centered square of the image and scales it to size.
+func thumbnail(img image.Image, size int) ([]byte, error) {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	src := image.Rect(x0, y0, x0+side, y0+side)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,93 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// webp1x1 is a lossless 1x1 webp, the standard library can't encode one.
+const webp1x1 = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func encode(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, height/2, color.RGBA{R: 255, A: 255})
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	case "webp":
+		var data []byte
+		data, err = base64.StdEncoding.DecodeString(webp1x1)
+		buf.Write(data)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	limits := Limits{MaxSize: 1 << 20, MaxDimension: 1000, MinDimension: 1}
+	sizes := []int{16, 64}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		limits  Limits
+		wantExt string
+		wantErr error
+	}{
+		{name: "png", data: encode(t, "png", 200, 100), limits: limits, wantExt: "png"},
+		{name: "jpeg", data: encode(t, "jpeg", 100, 200), limits: limits, wantExt: "jpg"},
+		{name: "gif", data: encode(t, "gif", 50, 50), limits: limits, wantExt: "gif"},
+		{name: "webp", data: encode(t, "webp", 1, 1), limits: limits, wantExt: "webp"},
+		{name: "too large", data: encode(t, "png", 200, 100), limits: Limits{MaxSize: 10, MaxDimension: 1000, MinDimension: 1}, wantErr: ErrTooLarge},
+		{name: "too wide", data: encode(t, "png", 1001, 10), limits: limits, wantErr: ErrInvalidDimensions},
+		{name: "too small", data: encode(t, "png", 10, 10), limits: Limits{MaxSize: 1 << 20, MaxDimension: 1000, MinDimension: 32}, wantErr: ErrInvalidDimensions},
+		{name: "not an image", data: []byte("<svg></svg>"), limits: limits, wantErr: ErrUnsupportedFormat},
+		{name: "truncated", data: encode(t, "png", 200, 100)[:100], limits: limits, wantErr: ErrUnsupportedFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Process(tt.data, tt.limits, sizes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Process() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if img.Extension != tt.wantExt {
+				t.Errorf("extension = %q, want %q", img.Extension, tt.wantExt)
+			}
+			for _, size := range sizes {
+				thumb, err := png.Decode(bytes.NewReader(img.Thumbnails[size]))
+				if err != nil {
+					t.Fatalf("thumbnail %d: %v", size, err)
+				}
+				if b := thumb.Bounds(); b.Dx() != size || b.Dy() != size {
+					t.Errorf("thumbnail %d is %dx%d", size, b.Dx(), b.Dy())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package avatar
+
+import "errors"
+
+var (
+	ErrTooLarge          = errors.New("avatar is too large")
+	ErrUnsupportedFormat = errors.New("avatar must be a jpeg, png, gif or webp image")
+	ErrInvalidDimensions = errors.New("avatar dimensions are out of bounds")
+)
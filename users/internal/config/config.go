@@ -5,6 +5,8 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +14,12 @@ import (
 type Config struct {
 	Server
 	DB
+	Invitations
+	FilesService
+	Avatars
+	Lifecycle
+	Outbox
+	Webhooks
 }
 
 type Server struct {
@@ -28,6 +36,42 @@ type DB struct {
 	Database string
 }
 
+type Invitations struct {
+	TTL         time.Duration
+	LinkBaseURL string
+}
+
+type FilesService struct {
+	Addr string
+}
+
+type Avatars struct {
+	Bucket       string
+	MaxSize      int64
+	MaxDimension int
+	MinDimension int
+}
+
+type Lifecycle struct {
+	PollInterval   time.Duration
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+type Outbox struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Retention    time.Duration
+}
+
+type Webhooks struct {
+	PollInterval   time.Duration
+	Timeout        time.Duration
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
 func New() *Config {
 	if os.Getenv("LOAD_DOT_ENV") != "false" {
 		if err := godotenv.Load(); err != nil {
@@ -48,8 +92,74 @@ func New() *Config {
 			Password: os.Getenv("DB_PASSWORD"),
 			Database: os.Getenv("DB_DATABASE"),
 		},
+		Invitations: Invitations{
+			TTL:         getDuration("INVITATION_TTL", defaultInvitationTTL),
+			LinkBaseURL: os.Getenv("INVITATION_LINK_BASE_URL"),
+		},
+		FilesService: FilesService{
+			Addr: os.Getenv("FILES_SERVICE_ADDR"),
+		},
+		Avatars: Avatars{
+			Bucket:       getString("AVATAR_BUCKET", defaultAvatarBucket),
+			MaxSize:      int64(getInt("AVATAR_MAX_SIZE", defaultAvatarMaxSize)),
+			MaxDimension: getInt("AVATAR_MAX_DIMENSION", defaultAvatarMaxDimension),
+			MinDimension: getInt("AVATAR_MIN_DIMENSION", defaultAvatarMinDimension),
+		},
+		Lifecycle: Lifecycle{
+			PollInterval:   getDuration("LIFECYCLE_POLL_INTERVAL", defaultLifecyclePollInterval),
+			RetryBaseDelay: getDuration("LIFECYCLE_RETRY_BASE_DELAY", defaultLifecycleRetryBaseDelay),
+			RetryMaxDelay:  getDuration("LIFECYCLE_RETRY_MAX_DELAY", defaultLifecycleRetryMaxDelay),
+		},
+		Outbox: Outbox{
+			PollInterval: getDuration("OUTBOX_POLL_INTERVAL", defaultOutboxPollInterval),
+			BatchSize:    getInt("OUTBOX_BATCH_SIZE", defaultOutboxBatchSize),
+			Retention:    getDuration("OUTBOX_RETENTION", defaultOutboxRetention),
+		},
+		Webhooks: Webhooks{
+			PollInterval:   getDuration("WEBHOOK_POLL_INTERVAL", defaultWebhookPollInterval),
+			Timeout:        getDuration("WEBHOOK_TIMEOUT", defaultWebhookTimeout),
+			MaxAttempts:    getInt("WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts),
+			RetryBaseDelay: getDuration("WEBHOOK_RETRY_BASE_DELAY", defaultWebhookRetryBaseDelay),
+			RetryMaxDelay:  getDuration("WEBHOOK_RETRY_MAX_DELAY", defaultWebhookRetryMaxDelay),
+		},
 	}
 
 	slog.Debug(fmt.Sprintf("config: %+v", conf))
 	return conf
 }
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %s", key, err)
+	}
+
+	return d
+}
+
+func getInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %s", key, err)
+	}
+
+	return i
+}
+
+func getString(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return fallback
+}
@@ -0,0 +1,29 @@
+package config
+
+import "time"
+
+const (
+	defaultInvitationTTL = 7 * 24 * time.Hour
+
+	defaultAvatarBucket       = "avatars"
+	defaultAvatarMaxSize      = 5 * 1024 * 1024 // 5 MB
+	defaultAvatarMaxDimension = 4096
+	defaultAvatarMinDimension = 16
+
+	defaultLifecyclePollInterval   = 10 * time.Second
+	defaultLifecycleRetryBaseDelay = 5 * time.Second
+	defaultLifecycleRetryMaxDelay  = 10 * time.Minute
+
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxBatchSize    = 100
+	defaultOutboxRetention    = 7 * 24 * time.Hour
+
+	defaultWebhookPollInterval   = 5 * time.Second
+	defaultWebhookTimeout        = 10 * time.Second
+	defaultWebhookMaxAttempts    = 10
+	defaultWebhookRetryBaseDelay = 10 * time.Second
+	defaultWebhookRetryMaxDelay  = time.Hour
+)
+
+// AvatarThumbnailSizes are the square thumbnails rendered for every avatar.
+var AvatarThumbnailSizes = []int{64, 256}
@@ -2,6 +2,10 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/avran02/decoplan/users/internal/models"
 	"github.com/avran02/decoplan/users/internal/service"
@@ -14,43 +18,48 @@ type UserController struct {
 }
 
 func (c *UserController) AddUserToGroup(ctx context.Context, req *pb.AddUserToGroupRequest) (*pb.AddUserToGroupResponse, error) {
-	if err := c.service.AddUserToGroup(ctx, models.UserGroup{
+	if err := c.service.AddUserToGroup(ctx, req.GetCallerID(), models.UserGroup{
 		GroupID: req.GroupID,
 		UserID:  req.UserID,
 	}); err != nil {
-		return nil, err
+		return nil, toStatus(err)
 	}
 
 	return &pb.AddUserToGroupResponse{Ok: true}, nil
 }
 
 func (c *UserController) CreateGroup(ctx context.Context, req *pb.CreateGroupRequest) (*pb.CreateGroupResponse, error) {
-	groupID, err := c.service.CreateGroup(ctx, req.GetName(), req.GetUserIDs())
+	groupID, err := c.service.CreateGroup(ctx, req.GetCallerID(), req.GetName(), req.Avatar, req.GetUserIDs())
 	if err != nil {
-		return nil, err
+		return nil, toStatus(err)
 	}
 
 	return &pb.CreateGroupResponse{GroupID: groupID}, nil
 }
 
 func (c *UserController) DeleteGroup(ctx context.Context, req *pb.DeleteGroupRequest) (*pb.DeleteGroupResponse, error) {
-	if err := c.service.DeleteGroup(ctx, req.GetId()); err != nil {
-		return nil, err
+	if err := c.service.DeleteGroup(ctx, req.GetCallerID(), req.GetId()); err != nil {
+		return nil, toStatus(err)
 	}
 
 	return &pb.DeleteGroupResponse{Ok: true}, nil
 }
 
 func (c *UserController) GetGroup(ctx context.Context, req *pb.GetGroupRequest) (*pb.GetGroupResponse, error) {
-	group, err := c.service.GetGroup(ctx, req.GetId())
+	group, err := c.service.GetGroup(ctx, req.GetId(), req.GetIncludeProfiles())
 	if err != nil {
-		return nil, err
+		return nil, toStatus(err)
 	}
 
 	members := make([]*pb.UserMember, 0)
 	for _, v := range group.Members {
+		if req.GetIncludeProfiles() {
+			members = append(members, memberToPb(v))
+			continue
+		}
+
 		members = append(members, &pb.UserMember{
-			UserID: v.ID,
+			UserID: v.UserID,
 		})
 	}
 
@@ -59,19 +68,213 @@ func (c *UserController) GetGroup(ctx context.Context, req *pb.GetGroupRequest)
 		GroupName: &group.Name,
 		Avatar:    group.Avatar,
 		Members:   members,
+		OwnerID:   group.OwnerID,
+		Version:   group.Version,
 	}, nil
 }
 
+func (c *UserController) UpdateGroup(ctx context.Context, req *pb.UpdateGroupRequest) (*pb.UpdateGroupResponse, error) {
+	version, err := c.service.UpdateGroup(ctx, req.GetCallerID(), models.UpdateGroup{
+		ID:      req.GetId(),
+		Name:    req.Name,
+		Avatar:  req.Avatar,
+		Version: req.GetVersion(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.UpdateGroupResponse{Ok: true, Version: version}, nil
+}
+
 func (c *UserController) RemoveUserFromGroup(ctx context.Context, req *pb.RemoveUserFromGroupRequest) (*pb.RemoveUserFromGroupResponse, error) {
-	if err := c.service.RemoveUserFromGroup(ctx, models.UserGroup{
+	if err := c.service.RemoveUserFromGroup(ctx, req.GetCallerID(), models.UserGroup{
 		GroupID: req.GroupID,
 		UserID:  req.UserID,
 	}); err != nil {
-		return nil, err
+		return nil, toStatus(err)
 	}
 
 	return &pb.RemoveUserFromGroupResponse{Ok: true}, nil
 }
+
+func (c *UserController) TransferGroupOwnership(ctx context.Context, req *pb.TransferGroupOwnershipRequest) (*pb.TransferGroupOwnershipResponse, error) {
+	if err := c.service.TransferGroupOwnership(ctx, req.GetCallerID(), req.GetGroupID(), req.GetNewOwnerID()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.TransferGroupOwnershipResponse{Ok: true}, nil
+}
+
+func (c *UserController) SetMemberRole(ctx context.Context, req *pb.SetMemberRoleRequest) (*pb.SetMemberRoleResponse, error) {
+	role, err := roleFromPb(req.GetRole())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := c.service.SetMemberRole(ctx, req.GetCallerID(), models.UserGroup{
+		GroupID: req.GetGroupID(),
+		UserID:  req.GetUserID(),
+		Role:    role,
+	}); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.SetMemberRoleResponse{Ok: true}, nil
+}
+
+func (c *UserController) InviteToGroup(ctx context.Context, req *pb.InviteToGroupRequest) (*pb.InviteToGroupResponse, error) {
+	inv, err := c.service.InviteToGroup(ctx, req.GetCallerID(), req.GetGroupID(), req.InviteeID, req.MaxUses)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.InviteToGroupResponse{Invitation: invitationToPb(inv)}, nil
+}
+
+func (c *UserController) ListMyInvitations(ctx context.Context, req *pb.ListMyInvitationsRequest) (*pb.ListMyInvitationsResponse, error) {
+	invitations, err := c.service.ListMyInvitations(ctx, req.GetCallerID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := make([]*pb.Invitation, 0, len(invitations))
+	for _, inv := range invitations {
+		res = append(res, invitationToPb(inv))
+	}
+
+	return &pb.ListMyInvitationsResponse{Invitations: res}, nil
+}
+
+func (c *UserController) AcceptInvitation(ctx context.Context, req *pb.AcceptInvitationRequest) (*pb.AcceptInvitationResponse, error) {
+	groupID, err := c.service.AcceptInvitation(ctx, req.GetCallerID(), req.GetInvitationID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.AcceptInvitationResponse{GroupID: groupID}, nil
+}
+
+func (c *UserController) DeclineInvitation(ctx context.Context, req *pb.DeclineInvitationRequest) (*pb.DeclineInvitationResponse, error) {
+	if err := c.service.DeclineInvitation(ctx, req.GetCallerID(), req.GetInvitationID()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.DeclineInvitationResponse{Ok: true}, nil
+}
+
+func (c *UserController) RedeemInviteCode(ctx context.Context, req *pb.RedeemInviteCodeRequest) (*pb.RedeemInviteCodeResponse, error) {
+	groupID, err := c.service.RedeemInviteCode(ctx, req.GetCallerID(), req.GetCode())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.RedeemInviteCodeResponse{GroupID: groupID}, nil
+}
+
+func (c *UserController) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	page, err := c.service.ListUsers(ctx, listOptionsFromPb(req.GetPage(), req.GetSortBy(), req.GetDirection()), models.UserFilter{
+		NamePrefix: req.NamePrefix,
+		HasAvatar:  req.HasAvatar,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.ListUsersResponse{
+		Users:         usersToPb(page.Users),
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
+
+func (c *UserController) SearchUsers(ctx context.Context, req *pb.SearchUsersRequest) (*pb.SearchUsersResponse, error) {
+	opts := models.ListOptions{
+		PageSize:  int(req.GetPage().GetPageSize()),
+		PageToken: req.GetPage().GetPageToken(),
+	}
+
+	page, err := c.service.SearchUsers(ctx, req.GetQuery(), searchModeFromPb(req.GetMode()), opts)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.SearchUsersResponse{
+		Users:         usersToPb(page.Users),
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
+
+func (c *UserController) ListGroups(ctx context.Context, req *pb.ListGroupsRequest) (*pb.ListGroupsResponse, error) {
+	page, err := c.service.ListGroups(ctx, listOptionsFromPb(req.GetPage(), req.GetSortBy(), req.GetDirection()), models.GroupFilter{
+		NamePrefix: req.NamePrefix,
+		OwnerID:    req.OwnerID,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.ListGroupsResponse{
+		Groups:        groupsToPb(page.Groups),
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
+
+func (c *UserController) ListGroupsForUser(ctx context.Context, req *pb.ListGroupsForUserRequest) (*pb.ListGroupsForUserResponse, error) {
+	filter := models.GroupFilter{NamePrefix: req.NamePrefix}
+	if req.Role != nil {
+		role, err := roleFromPb(req.GetRole())
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		filter.Role = &role
+	}
+
+	page, err := c.service.ListGroupsForUser(ctx, req.GetUserID(), listOptionsFromPb(req.GetPage(), req.GetSortBy(), req.GetDirection()), filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.ListGroupsForUserResponse{
+		Groups:        groupsToPb(page.Groups),
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
+
+func (c *UserController) ListGroupMembers(ctx context.Context, req *pb.ListGroupMembersRequest) (*pb.ListGroupMembersResponse, error) {
+	filter := models.GroupFilter{NamePrefix: req.NamePrefix}
+	if req.Role != nil {
+		role, err := roleFromPb(req.GetRole())
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		filter.Role = &role
+	}
+
+	page, err := c.service.ListGroupMembers(ctx, req.GetGroupID(), listOptionsFromPb(req.GetPage(), req.GetSortBy(), req.GetDirection()), filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	members := make([]*pb.UserMember, 0, len(page.Members))
+	for _, v := range page.Members {
+		members = append(members, memberToPb(v))
+	}
+
+	return &pb.ListGroupMembersResponse{
+		Members:       members,
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
+
+func (c *UserController) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.GetUsersResponse, error) {
+	users, err := c.service.GetUsers(ctx, req.GetIds())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.GetUsersResponse{Users: usersToPb(users)}, nil
+}
+
 func (c *UserController) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
 	if err := c.service.CreateUser(ctx, req.GetId(), req.GetName(), req.BirthDate.AsTime()); err != nil {
 		return nil, err
@@ -96,12 +299,17 @@ func (c *UserController) GetUser(ctx context.Context, req *pb.GetUserRequest) (*
 
 func (c *UserController) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
 	name := req.Name
-	birthDate := req.GetBirthDate().AsTime()
 	avatar := req.Avatar
+	var birthDate *time.Time
+	if req.BirthDate != nil {
+		t := req.GetBirthDate().AsTime()
+		birthDate = &t
+	}
+
 	if err := c.service.UpdateUser(ctx, models.UpdateUser{
 		ID:        req.GetId(),
 		Name:      name,
-		BirthDate: &birthDate,
+		BirthDate: birthDate,
 		Avatar:    avatar,
 	}); err != nil {
 		return nil, err
@@ -110,12 +318,149 @@ func (c *UserController) UpdateUser(ctx context.Context, req *pb.UpdateUserReque
 	return &pb.UpdateUserResponse{Ok: true}, nil
 }
 
+func (c *UserController) UploadAvatar(stream pb.UsersService_UploadAvatarServer) error {
+	header, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("failed to receive upload avatar request: %w", err)
+	}
+
+	if len(header.GetContent()) != 0 {
+		return toStatus(ErrNotEmptyFirstChunk)
+	}
+
+	target := models.AvatarTarget{
+		UserID:  header.GetUserID(),
+		GroupID: header.GetGroupID(),
+	}
+
+	avatar, err := c.service.UploadAvatar(stream.Context(), header.GetCallerID(), target, &avatarStreamReader{stream: stream})
+	if err != nil {
+		slog.Error("failed to upload avatar", "error", err)
+		return toStatus(err)
+	}
+
+	thumbnails := make([]*pb.AvatarThumbnail, 0, len(avatar.Thumbnails))
+	for size, filePath := range avatar.Thumbnails {
+		thumbnails = append(thumbnails, &pb.AvatarThumbnail{
+			Size:     int32(size),
+			FilePath: filePath,
+		})
+	}
+	slices.SortFunc(thumbnails, func(a, b *pb.AvatarThumbnail) int {
+		return int(a.Size - b.Size)
+	})
+
+	return stream.SendAndClose(&pb.UploadAvatarResponse{
+		Avatar:           avatar.Ref,
+		OriginalFilePath: avatar.Original,
+		Thumbnails:       thumbnails,
+	})
+}
+
+func (c *UserController) WatchEvents(req *pb.WatchEventsRequest, stream pb.UsersService_WatchEventsServer) error {
+	send := func(event models.Event) error {
+		return stream.Send(eventToPb(event))
+	}
+
+	err := c.service.WatchEvents(stream.Context(), eventTypesFromPb(req.GetTypes()), req.AfterSequence, send)
+	if err != nil {
+		slog.Error("failed to watch events", "error", err)
+		return toStatus(err)
+	}
+
+	return nil
+}
+
+func (c *UserController) CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.CreateWebhookResponse, error) {
+	webhook, err := c.service.CreateWebhook(ctx, req.GetCallerID(), req.GetUrl(), eventTypesFromPb(req.GetEventTypes()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.CreateWebhookResponse{
+		Webhook: webhookToPb(webhook),
+		Secret:  webhook.Secret,
+	}, nil
+}
+
+func (c *UserController) ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	webhooks, err := c.service.ListWebhooks(ctx, req.GetCallerID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := make([]*pb.Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		res = append(res, webhookToPb(webhook))
+	}
+
+	return &pb.ListWebhooksResponse{Webhooks: res}, nil
+}
+
+func (c *UserController) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	if err := c.service.DeleteWebhook(ctx, req.GetCallerID(), req.GetWebhookID()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.DeleteWebhookResponse{Ok: true}, nil
+}
+
+func (c *UserController) TestWebhook(ctx context.Context, req *pb.TestWebhookRequest) (*pb.TestWebhookResponse, error) {
+	result, err := c.service.TestWebhook(ctx, req.GetCallerID(), req.GetWebhookID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &pb.TestWebhookResponse{
+		Delivered:  result.Err == nil,
+		StatusCode: result.StatusCode,
+	}
+	if result.Err != nil {
+		msg := result.Err.Error()
+		res.Error = &msg
+	}
+
+	return res, nil
+}
+
+func (c *UserController) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	opts := models.ListOptions{
+		PageSize:  int(req.GetPage().GetPageSize()),
+		PageToken: req.GetPage().GetPageToken(),
+	}
+
+	page, err := c.service.ListWebhookDeliveries(ctx, req.GetCallerID(), req.GetWebhookID(), opts)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	deliveries := make([]*pb.WebhookDelivery, 0, len(page.Deliveries))
+	for _, d := range page.Deliveries {
+		deliveries = append(deliveries, webhookDeliveryToPb(d))
+	}
+
+	return &pb.ListWebhookDeliveriesResponse{
+		Deliveries:    deliveries,
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
+
 func (c *UserController) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
-	if err := c.service.DeleteUser(ctx, req.GetUserID()); err != nil {
-		return nil, err
+	jobID, err := c.service.DeleteUser(ctx, req.GetUserID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.DeleteUserResponse{Ok: true, PurgeJobID: jobID}, nil
+}
+
+func (c *UserController) GetUserLifecycle(ctx context.Context, req *pb.GetUserLifecycleRequest) (*pb.GetUserLifecycleResponse, error) {
+	lc, err := c.service.GetUserLifecycle(ctx, req.GetUserID())
+	if err != nil {
+		return nil, toStatus(err)
 	}
 
-	return &pb.DeleteUserResponse{Ok: true}, nil
+	return userLifecycleToPb(lc), nil
 }
 
 func New(service service.UserService) *UserController {
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/avran02/decoplan/users/internal/avatar"
+	"github.com/avran02/decoplan/users/internal/repository"
+	"github.com/avran02/decoplan/users/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrUnknownRole        = errors.New("unknown group role")
+	ErrNotEmptyFirstChunk = errors.New("first chunk is not empty")
+)
+
+// toStatus maps domain errors to gRPC status errors. Errors it doesn't know
+// about are returned as is.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, service.ErrEmptyCallerID):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, service.ErrPermissionDenied),
+		errors.Is(err, service.ErrOwnerRemoval),
+		errors.Is(err, service.ErrNotInvitee):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrInvalidRole),
+		errors.Is(err, service.ErrInvalidMaxUses),
+		errors.Is(err, service.ErrVersionRequired),
+		errors.Is(err, service.ErrInvalidPageSize),
+		errors.Is(err, service.ErrInvalidSortField),
+		errors.Is(err, service.ErrInvalidSearchMode),
+		errors.Is(err, service.ErrEmptySearchQuery),
+		errors.Is(err, service.ErrBatchTooLarge),
+		errors.Is(err, service.ErrInvalidAvatarTarget),
+		errors.Is(err, service.ErrInvalidEventType),
+		errors.Is(err, service.ErrInvalidWebhookURL),
+		errors.Is(err, avatar.ErrTooLarge),
+		errors.Is(err, avatar.ErrUnsupportedFormat),
+		errors.Is(err, avatar.ErrInvalidDimensions),
+		errors.Is(err, ErrNotEmptyFirstChunk),
+		errors.Is(err, repository.ErrInvalidPageToken),
+		errors.Is(err, repository.ErrNothingToUpdate),
+		errors.Is(err, ErrUnknownRole):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrAlreadyMember),
+		errors.Is(err, repository.ErrAlreadyInvited):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrInvitationClosed),
+		errors.Is(err, service.ErrInvitationExpired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, repository.ErrVersionConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, service.ErrNotGroupMember),
+		errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/avran02/decoplan/users/internal/avatar"
+	"github.com/avran02/decoplan/users/internal/repository"
+	"github.com/avran02/decoplan/users/internal/service"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+	}{
+		{name: "no caller", err: service.ErrEmptyCallerID, wantCode: codes.Unauthenticated},
+		{name: "not allowed", err: fmt.Errorf("failed to remove member: %w", service.ErrPermissionDenied), wantCode: codes.PermissionDenied},
+		{name: "owner removal", err: service.ErrOwnerRemoval, wantCode: codes.PermissionDenied},
+		{name: "not the invitee", err: service.ErrNotInvitee, wantCode: codes.PermissionDenied},
+		{name: "invalid role", err: ErrUnknownRole, wantCode: codes.InvalidArgument},
+		{name: "avatar too large", err: avatar.ErrTooLarge, wantCode: codes.InvalidArgument},
+		{name: "bad page token", err: repository.ErrInvalidPageToken, wantCode: codes.InvalidArgument},
+		{name: "already member", err: service.ErrAlreadyMember, wantCode: codes.AlreadyExists},
+		{name: "already invited", err: fmt.Errorf("failed to invite: %w", repository.ErrAlreadyInvited), wantCode: codes.AlreadyExists},
+		{name: "invitation expired", err: service.ErrInvitationExpired, wantCode: codes.FailedPrecondition},
+		{name: "stale version", err: fmt.Errorf("failed to update group: %w", repository.ErrVersionConflict), wantCode: codes.Aborted},
+		{name: "not a member", err: service.ErrNotGroupMember, wantCode: codes.NotFound},
+		{name: "not found", err: repository.ErrNotFound, wantCode: codes.NotFound},
+		{name: "unknown", err: errors.New("boom"), wantCode: codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatus(tt.err))
+			if st.Code() != tt.wantCode {
+				t.Errorf("code = %s, want %s", st.Code(), tt.wantCode)
+			}
+			if st.Message() != tt.err.Error() {
+				t.Errorf("message = %q, want %q", st.Message(), tt.err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func eventTypesFromPb(types []string) []models.EventType {
+	res := make([]models.EventType, 0, len(types))
+	for _, t := range types {
+		res = append(res, models.EventType(t))
+	}
+
+	return res
+}
+
+func eventToPb(event models.Event) *pb.Event {
+	return &pb.Event{
+		Id:            event.ID,
+		Sequence:      event.Sequence,
+		Type:          string(event.Type),
+		AggregateType: event.Type.AggregateType(),
+		AggregateID:   event.AggregateID,
+		Payload:       event.Payload,
+		CreatedAt:     timestamppb.New(event.CreatedAt),
+	}
+}
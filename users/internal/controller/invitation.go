@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func invitationToPb(inv models.Invitation) *pb.Invitation {
+	return &pb.Invitation{
+		Id:        inv.ID,
+		GroupID:   inv.GroupID,
+		InviterID: inv.InviterID,
+		InviteeID: inv.InviteeID,
+		Code:      inv.Code,
+		Link:      inv.Link,
+		Status:    invitationStatusToPb(inv.Status),
+		MaxUses:   inv.MaxUses,
+		Uses:      inv.Uses,
+		CreatedAt: timestamppb.New(inv.CreatedAt),
+		ExpiresAt: timestamppb.New(inv.ExpiresAt),
+	}
+}
+
+func invitationStatusToPb(status models.InvitationStatus) pb.InvitationStatus {
+	switch status {
+	case models.InvitationPending:
+		return pb.InvitationStatus_INVITATION_STATUS_PENDING
+	case models.InvitationAccepted:
+		return pb.InvitationStatus_INVITATION_STATUS_ACCEPTED
+	case models.InvitationDeclined:
+		return pb.InvitationStatus_INVITATION_STATUS_DECLINED
+	default:
+		return pb.InvitationStatus_INVITATION_STATUS_UNSPECIFIED
+	}
+}
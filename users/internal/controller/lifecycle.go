@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func userLifecycleToPb(lc models.UserLifecycle) *pb.GetUserLifecycleResponse {
+	jobs := make([]*pb.LifecycleJob, 0, len(lc.Jobs))
+	for _, job := range lc.Jobs {
+		jobs = append(jobs, &pb.LifecycleJob{
+			Id:            job.ID,
+			Kind:          lifecycleJobKindToPb(job.Kind),
+			Status:        lifecycleJobStatusToPb(job.Status),
+			Attempts:      job.Attempts,
+			LastError:     job.LastError,
+			NextAttemptAt: timestamppb.New(job.NextAttemptAt),
+			CreatedAt:     timestamppb.New(job.CreatedAt),
+			UpdatedAt:     timestamppb.New(job.UpdatedAt),
+		})
+	}
+
+	return &pb.GetUserLifecycleResponse{
+		UserID: lc.UserID,
+		State:  userStateToPb(lc.State),
+		Jobs:   jobs,
+	}
+}
+
+func userStateToPb(state models.UserState) pb.UserState {
+	switch state {
+	case models.UserActive:
+		return pb.UserState_USER_STATE_ACTIVE
+	case models.UserDeleting:
+		return pb.UserState_USER_STATE_DELETING
+	case models.UserDeleted:
+		return pb.UserState_USER_STATE_DELETED
+	default:
+		return pb.UserState_USER_STATE_UNSPECIFIED
+	}
+}
+
+func lifecycleJobKindToPb(kind models.LifecycleJobKind) pb.LifecycleJobKind {
+	switch kind {
+	case models.LifecycleProvision:
+		return pb.LifecycleJobKind_LIFECYCLE_JOB_KIND_PROVISION
+	case models.LifecyclePurge:
+		return pb.LifecycleJobKind_LIFECYCLE_JOB_KIND_PURGE
+	default:
+		return pb.LifecycleJobKind_LIFECYCLE_JOB_KIND_UNSPECIFIED
+	}
+}
+
+func lifecycleJobStatusToPb(status models.LifecycleJobStatus) pb.LifecycleJobStatus {
+	switch status {
+	case models.LifecycleJobPending:
+		return pb.LifecycleJobStatus_LIFECYCLE_JOB_STATUS_PENDING
+	case models.LifecycleJobDone:
+		return pb.LifecycleJobStatus_LIFECYCLE_JOB_STATUS_DONE
+	case models.LifecycleJobCancelled:
+		return pb.LifecycleJobStatus_LIFECYCLE_JOB_STATUS_CANCELLED
+	default:
+		return pb.LifecycleJobStatus_LIFECYCLE_JOB_STATUS_UNSPECIFIED
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func listOptionsFromPb(page *pb.PageRequest, sortBy pb.SortField, direction pb.SortDirection) models.ListOptions {
+	opts := models.ListOptions{
+		PageSize:  int(page.GetPageSize()),
+		PageToken: page.GetPageToken(),
+		SortBy:    models.SortByName,
+		Desc:      direction == pb.SortDirection_SORT_DIRECTION_DESC,
+	}
+
+	if sortBy == pb.SortField_SORT_FIELD_ID {
+		opts.SortBy = models.SortByID
+	}
+
+	return opts
+}
+
+func searchModeFromPb(mode pb.SearchMode) models.SearchMode {
+	if mode == pb.SearchMode_SEARCH_MODE_TRIGRAM {
+		return models.SearchTrigram
+	}
+
+	return models.SearchPrefix
+}
+
+func usersToPb(users []models.User) []*pb.User {
+	res := make([]*pb.User, 0, len(users))
+	for _, user := range users {
+		res = append(res, &pb.User{
+			Id:        user.ID,
+			Name:      user.Name,
+			Avatar:    user.Avatar,
+			BirthDate: timestamppb.New(user.BirthDate),
+		})
+	}
+
+	return res
+}
+
+func groupsToPb(groups []models.Group) []*pb.GroupSummary {
+	res := make([]*pb.GroupSummary, 0, len(groups))
+	for _, group := range groups {
+		res = append(res, &pb.GroupSummary{
+			Id:      group.ID,
+			Name:    group.Name,
+			Avatar:  group.Avatar,
+			OwnerID: group.OwnerID,
+			Version: group.Version,
+			Role:    roleToPb(group.Role),
+		})
+	}
+
+	return res
+}
+
+func memberToPb(member models.Member) *pb.UserMember {
+	return &pb.UserMember{
+		UserID:   member.UserID,
+		Name:     member.Name,
+		Avatar:   member.Avatar,
+		Role:     roleToPb(member.Role),
+		JoinedAt: timestamppb.New(member.JoinedAt),
+	}
+}
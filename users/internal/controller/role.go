@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/pb"
+)
+
+func roleFromPb(role pb.GroupRole) (models.Role, error) {
+	switch role {
+	case pb.GroupRole_GROUP_ROLE_OWNER:
+		return models.RoleOwner, nil
+	case pb.GroupRole_GROUP_ROLE_ADMIN:
+		return models.RoleAdmin, nil
+	case pb.GroupRole_GROUP_ROLE_MEMBER:
+		return models.RoleMember, nil
+	default:
+		return "", ErrUnknownRole
+	}
+}
+
+func roleToPb(role models.Role) pb.GroupRole {
+	switch role {
+	case models.RoleOwner:
+		return pb.GroupRole_GROUP_ROLE_OWNER
+	case models.RoleAdmin:
+		return pb.GroupRole_GROUP_ROLE_ADMIN
+	case models.RoleMember:
+		return pb.GroupRole_GROUP_ROLE_MEMBER
+	default:
+		return pb.GroupRole_GROUP_ROLE_UNSPECIFIED
+	}
+}
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"errors"
+	"io"
+
+	"github.com/avran02/decoplan/users/pb"
+)
+
+// avatarStreamReader reads the image bytes that follow the header message
+// of an UploadAvatar stream.
+type avatarStreamReader struct {
+	stream pb.UsersService_UploadAvatarServer
+	buf    []byte
+}
+
+func (r *avatarStreamReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+		r.buf = req.GetContent()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func webhookToPb(webhook models.Webhook) *pb.Webhook {
+	eventTypes := make([]string, 0, len(webhook.EventTypes))
+	for _, t := range webhook.EventTypes {
+		eventTypes = append(eventTypes, string(t))
+	}
+
+	return &pb.Webhook{
+		Id:         webhook.ID,
+		Url:        webhook.URL,
+		EventTypes: eventTypes,
+		CreatedAt:  timestamppb.New(webhook.CreatedAt),
+	}
+}
+
+func webhookDeliveryToPb(d models.WebhookDelivery) *pb.WebhookDelivery {
+	return &pb.WebhookDelivery{
+		Id:             d.ID,
+		EventID:        d.EventID,
+		EventType:      string(d.EventType),
+		Status:         deliveryStatusToPb(d.Status),
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		NextAttemptAt:  timestamppb.New(d.NextAttemptAt),
+		CreatedAt:      timestamppb.New(d.CreatedAt),
+		UpdatedAt:      timestamppb.New(d.UpdatedAt),
+	}
+}
+
+func deliveryStatusToPb(status models.DeliveryStatus) pb.WebhookDeliveryStatus {
+	switch status {
+	case models.DeliveryPending:
+		return pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_PENDING
+	case models.DeliveryDelivered:
+		return pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_DELIVERED
+	case models.DeliveryDead:
+		return pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_DEAD
+	default:
+		return pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_UNSPECIFIED
+	}
+}
@@ -0,0 +1,5 @@
+package events
+
+import "errors"
+
+var ErrPublishFailed = errors.New("failed to publish event")
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/avran02/decoplan/users/internal/models"
+)
+
+// Hub wakes up local watchers whenever the relay publishes something. It
+// carries no events itself: watchers read them from the outbox, which also
+// covers events relayed by other instances.
+type Hub struct {
+	mu      sync.Mutex
+	waiters map[chan struct{}]struct{}
+}
+
+func (h *Hub) Publish(_ context.Context, _ models.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.waiters {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Subscribe returns a channel that receives a signal after new events are
+// published and a function that stops the subscription.
+func (h *Hub) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	h.waiters[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.waiters, ch)
+		h.mu.Unlock()
+	}
+}
+
+func NewHub() *Hub {
+	return &Hub{waiters: make(map[chan struct{}]struct{})}
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/config"
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/internal/repository"
+)
+
+// Relay moves events from the outbox to the sink in insert order and
+// drops published events once they're older than the retention.
+type Relay struct {
+	repo repository.Repository
+	sink Sink
+	conf config.Outbox
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	slog.Info("starting outbox relay")
+	ticker := time.NewTicker(r.conf.PollInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		r.publishPending(ctx)
+
+		if time.Since(lastCleanup) > r.conf.Retention/2 {
+			r.cleanup(ctx)
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) publishPending(ctx context.Context) {
+	publish := func(event models.Event) error {
+		return r.sink.Publish(ctx, event)
+	}
+
+	for {
+		n, err := r.repo.PublishPendingEvents(ctx, r.conf.BatchSize, publish)
+		if err != nil {
+			slog.Error("failed to relay outbox events", "error", err)
+			return
+		}
+
+		if n < r.conf.BatchSize {
+			return
+		}
+	}
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	n, err := r.repo.DeletePublishedEvents(ctx, time.Now().UTC().Add(-r.conf.Retention))
+	if err != nil {
+		slog.Error("failed to delete published events", "error", err)
+		return
+	}
+
+	if n > 0 {
+		slog.Info("deleted published events", "count", n)
+	}
+}
+
+func NewRelay(repo repository.Repository, sink Sink, conf config.Outbox) *Relay {
+	return &Relay{
+		repo: repo,
+		sink: sink,
+		conf: conf,
+	}
+}
@@ -0,0 +1,149 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/config"
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/internal/repository"
+)
+
+// fakeOutbox publishes its pending events the way the repository does: in
+// order, a batch at a time, stopping at the first event that fails.
+type fakeOutbox struct {
+	repository.Repository
+	pending   []models.Event
+	published []models.Event
+	batches   int
+	deleted   time.Time
+}
+
+func (o *fakeOutbox) PublishPendingEvents(_ context.Context, limit int, publish func(models.Event) error) (int, error) {
+	o.batches++
+
+	n := 0
+	for n < limit && len(o.pending) > 0 {
+		event := o.pending[0]
+		if err := publish(event); err != nil {
+			return n, err
+		}
+		o.pending = o.pending[1:]
+		o.published = append(o.published, event)
+		n++
+	}
+
+	return n, nil
+}
+
+func (o *fakeOutbox) DeletePublishedEvents(_ context.Context, before time.Time) (int64, error) {
+	o.deleted = before
+	return 0, nil
+}
+
+type failingSink struct {
+	failAt string
+	seen   []string
+}
+
+func (s *failingSink) Publish(_ context.Context, event models.Event) error {
+	s.seen = append(s.seen, event.ID)
+	if event.ID == s.failAt {
+		return errors.New("broker is down")
+	}
+
+	return nil
+}
+
+func pendingEvents(n int) []models.Event {
+	events := make([]models.Event, n)
+	for i := range events {
+		events[i] = models.Event{ID: fmt.Sprint(i), Type: models.EventUserUpdated, AggregateID: "alice"}
+	}
+
+	return events
+}
+
+func TestPublishPending(t *testing.T) {
+	tests := []struct {
+		name          string
+		pending       int
+		failAt        string
+		wantPublished int
+		wantBatches   int
+	}{
+		{name: "nothing pending", pending: 0, wantBatches: 1},
+		{name: "short batch", pending: 2, wantPublished: 2, wantBatches: 1},
+		{name: "full batches", pending: 6, wantPublished: 6, wantBatches: 3},
+		{name: "several batches", pending: 7, wantPublished: 7, wantBatches: 3},
+		{name: "sink failed", pending: 7, failAt: "4", wantPublished: 4, wantBatches: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &fakeOutbox{pending: pendingEvents(tt.pending)}
+			sink := &failingSink{failAt: tt.failAt}
+			r := NewRelay(outbox, sink, config.Outbox{BatchSize: 3})
+
+			r.publishPending(context.Background())
+
+			if len(outbox.published) != tt.wantPublished {
+				t.Errorf("published %d events, want %d", len(outbox.published), tt.wantPublished)
+			}
+			if outbox.batches != tt.wantBatches {
+				t.Errorf("ran %d batches, want %d", outbox.batches, tt.wantBatches)
+			}
+			for i, event := range outbox.published {
+				if event.ID != fmt.Sprint(i) {
+					t.Fatalf("event %d published as %s, out of order", i, event.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	outbox := &fakeOutbox{}
+	r := NewRelay(outbox, MultiSink{}, config.Outbox{Retention: time.Hour})
+
+	before := time.Now().UTC().Add(-time.Hour)
+	r.cleanup(context.Background())
+	after := time.Now().UTC().Add(-time.Hour)
+
+	if outbox.deleted.Before(before) || outbox.deleted.After(after) {
+		t.Errorf("deleted events before %v, want an hour ago", outbox.deleted)
+	}
+}
+
+func TestMultiSink(t *testing.T) {
+	first := &failingSink{}
+	second := &failingSink{failAt: "1"}
+	third := &failingSink{}
+	sink := MultiSink{first, second, third}
+
+	// a failure stops the event short of the later sinks, it's retried for all
+	for _, event := range pendingEvents(2) {
+		err := sink.Publish(context.Background(), event)
+		if (err != nil) != (event.ID == "1") {
+			t.Errorf("Publish(%s) error = %v", event.ID, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		sink *failingSink
+		want []string
+	}{
+		{name: "first", sink: first, want: []string{"0", "1"}},
+		{name: "second", sink: second, want: []string{"0", "1"}},
+		{name: "third", sink: third, want: []string{"0"}},
+	}
+	for _, tt := range tests {
+		if fmt.Sprint(tt.sink.seen) != fmt.Sprint(tt.want) {
+			t.Errorf("%s sink saw %v, want %v", tt.name, tt.sink.seen, tt.want)
+		}
+	}
+}
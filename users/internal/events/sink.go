@@ -0,0 +1,116 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/models"
+)
+
+const timeFormat = time.RFC3339Nano
+
+// Sink receives events relayed from the outbox. Delivery is at least once:
+// an event is published again if the relay stops before recording it.
+type Sink interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// ChannelSink hands events over a channel, it's meant for tests and for
+// wiring consumers inside the same process.
+type ChannelSink struct {
+	events chan models.Event
+}
+
+func (s *ChannelSink) Publish(ctx context.Context, event models.Event) error {
+	select {
+	case s.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *ChannelSink) Events() <-chan models.Event {
+	return s.events
+}
+
+func NewChannelSink(buffer int) *ChannelSink {
+	return &ChannelSink{events: make(chan models.Event, buffer)}
+}
+
+// Publisher is the part of a message broker client the relay needs. It fits
+// both NATS, where subject is the subject and key can be ignored, and Kafka,
+// where subject is the topic and key picks the partition.
+type Publisher interface {
+	Publish(ctx context.Context, subject, key string, data []byte) error
+}
+
+// BrokerSink publishes every event as JSON to "<prefix>.<event type>" keyed
+// by aggregate ID, so events of one aggregate keep their order.
+type BrokerSink struct {
+	publisher Publisher
+	prefix    string
+}
+
+func (s *BrokerSink) Publish(ctx context.Context, event models.Event) error {
+	data, err := Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	subject := s.prefix + "." + string(event.Type)
+	if err = s.publisher.Publish(ctx, subject, event.AggregateID, data); err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishFailed, err)
+	}
+
+	return nil
+}
+
+func NewBrokerSink(publisher Publisher, prefix string) *BrokerSink {
+	return &BrokerSink{publisher: publisher, prefix: prefix}
+}
+
+type message struct {
+	ID            string          `json:"id"`
+	Sequence      int64           `json:"sequence"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregateType"`
+	AggregateID   string          `json:"aggregateID"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     string          `json:"createdAt"`
+}
+
+// Marshal encodes the event the way it's sent outside of the service, to
+// brokers and webhooks alike.
+func Marshal(event models.Event) ([]byte, error) {
+	data, err := json.Marshal(message{
+		ID:            event.ID,
+		Sequence:      event.Sequence,
+		Type:          string(event.Type),
+		AggregateType: event.Type.AggregateType(),
+		AggregateID:   event.AggregateID,
+		Payload:       event.Payload,
+		CreatedAt:     event.CreatedAt.Format(timeFormat),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event %s: %w", event.ID, err)
+	}
+
+	return data, nil
+}
+
+// MultiSink publishes to every sink in order and stops at the first one that
+// fails, so the event stays in the outbox and is retried for all of them.
+type MultiSink []Sink
+
+func (m MultiSink) Publish(ctx context.Context, event models.Event) error {
+	for _, sink := range m {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/models"
+)
+
+type published struct {
+	subject, key string
+	data         []byte
+}
+
+type fakePublisher struct {
+	err  error
+	msgs []published
+}
+
+func (p *fakePublisher) Publish(_ context.Context, subject, key string, data []byte) error {
+	p.msgs = append(p.msgs, published{subject: subject, key: key, data: data})
+	return p.err
+}
+
+func TestBrokerSink(t *testing.T) {
+	event := models.Event{
+		Sequence:    42,
+		ID:          "event",
+		Type:        models.EventMembershipAdded,
+		AggregateID: "group",
+		Payload:     json.RawMessage(`{"userID":"alice"}`),
+		CreatedAt:   time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC),
+	}
+
+	publisher := &fakePublisher{}
+	if err := NewBrokerSink(publisher, "decoplan.users").Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := publisher.msgs[0]
+	if msg.subject != "decoplan.users.membership.added" || msg.key != "group" {
+		t.Errorf("published to %q keyed %q", msg.subject, msg.key)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(msg.data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"id":            "event",
+		"sequence":      float64(42),
+		"type":          "membership.added",
+		"aggregateType": "membership",
+		"aggregateID":   "group",
+		"payload":       map[string]any{"userID": "alice"},
+		"createdAt":     "2024-05-01T12:00:00.0000005Z",
+	}
+	for key, value := range want {
+		if gotJSON, wantJSON := mustJSON(t, got[key]), mustJSON(t, value); gotJSON != wantJSON {
+			t.Errorf("%s = %s, want %s", key, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestBrokerSinkFailed(t *testing.T) {
+	publisher := &fakePublisher{err: errors.New("no route")}
+	err := NewBrokerSink(publisher, "decoplan").Publish(context.Background(), models.Event{Type: models.EventUserCreated})
+	if !errors.Is(err, ErrPublishFailed) {
+		t.Errorf("Publish() error = %v, want %v", err, ErrPublishFailed)
+	}
+}
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	woken, stop := hub.Subscribe()
+	idle, stopIdle := hub.Subscribe()
+	stopIdle()
+
+	// signals collapse, a watcher reading the outbox sees everything anyway
+	for range 3 {
+		if err := hub.Publish(context.Background(), models.Event{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(woken) != 1 {
+		t.Errorf("subscriber has %d signals, want 1", len(woken))
+	}
+	if len(idle) != 0 {
+		t.Error("a stopped subscription was signalled")
+	}
+
+	stop()
+	if len(hub.waiters) != 0 {
+		t.Errorf("%d waiters left after stopping", len(hub.waiters))
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,172 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+
+	"github.com/avran02/decoplan/users/internal/config"
+	"github.com/avran02/decoplan/users/pb/filespb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const uploadChunkSize = 1024 * 1024 // 1 MB
+
+// Client talks to the files service. Buckets are named after their owner,
+// just like the userID of the files service API.
+type Client interface {
+	RegisterUser(ctx context.Context, userID string) error
+	UnregisterUser(ctx context.Context, userID string) error
+	UploadFile(ctx context.Context, bucket, filePath string, content io.Reader) error
+	// RemoveFolder removes everything under dir, a path ending in "/".
+	RemoveFolder(ctx context.Context, bucket, dir string) error
+}
+
+type grpcClient struct {
+	client filespb.FileServiceClient
+}
+
+func (c *grpcClient) RegisterUser(ctx context.Context, userID string) error {
+	res, err := c.client.RegisterUser(ctx, &filespb.RegisterUserRequest{UserID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to register user: %w", err)
+	}
+
+	if !res.GetSuccess() {
+		return ErrRequestFailed
+	}
+
+	return nil
+}
+
+func (c *grpcClient) UnregisterUser(ctx context.Context, userID string) error {
+	res, err := c.client.UnregisterUser(ctx, &filespb.UnregisterUserRequest{UserID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to unregister user: %w", err)
+	}
+
+	if !res.GetSuccess() {
+		return ErrRequestFailed
+	}
+
+	return nil
+}
+
+// UploadFile streams content to the files service. The first message only
+// carries the destination, as the files service expects.
+func (c *grpcClient) UploadFile(ctx context.Context, bucket, filePath string, content io.Reader) error {
+	stream, err := c.client.UploadFile(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open upload stream: %w", err)
+	}
+
+	if err = stream.Send(&filespb.UploadFileRequest{UserID: bucket, FilePath: filePath}); err != nil {
+		return fmt.Errorf("failed to send upload header: %w", err)
+	}
+
+	buf := make([]byte, uploadChunkSize)
+	for {
+		n, err := content.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&filespb.UploadFileRequest{Content: buf[:n]}); err != nil {
+				return fmt.Errorf("failed to send file chunk: %w", err)
+			}
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	if !res.GetSuccess() {
+		return ErrRequestFailed
+	}
+
+	return nil
+}
+
+func (c *grpcClient) RemoveFolder(ctx context.Context, bucket, dir string) error {
+	paths, err := c.listFolder(ctx, bucket, dir)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	items := make([]*filespb.BatchOperationItem, 0, len(paths))
+	for _, filePath := range paths {
+		items = append(items, &filespb.BatchOperationItem{
+			Kind:     filespb.BatchOperationKind_BATCH_OPERATION_KIND_DELETE,
+			FilePath: filePath,
+		})
+	}
+
+	stream, err := c.client.BatchOperation(ctx, &filespb.BatchOperationRequest{UserID: bucket, Items: items})
+	if err != nil {
+		return fmt.Errorf("failed to remove folder: %w", err)
+	}
+
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove folder: %w", err)
+		}
+
+		if !res.GetSuccess() {
+			return fmt.Errorf("%w: failed to remove %s: %s", ErrRequestFailed, paths[res.GetIndex()], res.GetError())
+		}
+	}
+}
+
+// listFolder returns the paths of all files under dir, subfolders included.
+func (c *grpcClient) listFolder(ctx context.Context, bucket, dir string) ([]string, error) {
+	res, err := c.client.ListFiles(ctx, &filespb.ListFilesRequest{UserID: bucket, FilePath: dir})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folder: %w", err)
+	}
+
+	paths := make([]string, 0, len(res.GetFiles()))
+	for _, file := range res.GetFiles() {
+		name := file.GetName()
+		if name == dir || !strings.HasSuffix(name, "/") {
+			paths = append(paths, name)
+			continue
+		}
+
+		nested, err := c.listFolder(ctx, bucket, name)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, nested...)
+	}
+
+	return paths, nil
+}
+
+func New(conf config.FilesService) Client {
+	slog.Info("connecting to files service", "addr", conf.Addr)
+	conn, err := grpc.NewClient(conf.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatal("can't create files service client:", err)
+	}
+
+	return &grpcClient{client: filespb.NewFileServiceClient(conn)}
+}
@@ -0,0 +1,5 @@
+package files
+
+import "errors"
+
+var ErrRequestFailed = errors.New("files service reported failure")
@@ -0,0 +1,155 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/config"
+	"github.com/avran02/decoplan/users/internal/files"
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/internal/repository"
+)
+
+const (
+	batchSize = 10
+	jobLease  = 5 * time.Minute
+)
+
+// Coordinator runs the storage side of user lifecycle jobs against the files
+// service. Jobs are retried with exponential backoff until they succeed.
+type Coordinator interface {
+	Run(ctx context.Context)
+	// Kick makes Run look for due jobs now instead of at the next poll.
+	Kick()
+}
+
+type coordinator struct {
+	repo         repository.Repository
+	files        files.Client
+	conf         config.Lifecycle
+	avatarBucket string
+	kick         chan struct{}
+}
+
+func (c *coordinator) Run(ctx context.Context) {
+	slog.Info("starting lifecycle coordinator")
+	ticker := time.NewTicker(c.conf.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		c.runDueJobs(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.kick:
+		}
+	}
+}
+
+func (c *coordinator) Kick() {
+	select {
+	case c.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (c *coordinator) runDueJobs(ctx context.Context) {
+	for {
+		now := time.Now().UTC()
+		jobs, err := c.repo.ClaimDueJobs(ctx, now, now.Add(jobLease), batchSize)
+		if err != nil {
+			slog.Error("failed to claim lifecycle jobs", "error", err)
+			return
+		}
+
+		for _, job := range jobs {
+			c.runJob(ctx, job)
+		}
+
+		if len(jobs) < batchSize {
+			return
+		}
+	}
+}
+
+func (c *coordinator) runJob(ctx context.Context, job models.LifecycleJob) {
+	var err error
+	switch job.Kind {
+	case models.LifecycleProvision:
+		err = c.provision(ctx, job.UserID)
+	case models.LifecyclePurge:
+		err = c.purge(ctx, job.UserID)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownJobKind, job.Kind)
+	}
+
+	now := time.Now().UTC()
+	if err != nil {
+		next := now.Add(c.backoff(job.Attempts))
+		slog.Warn("lifecycle job failed", "job", job.ID, "kind", job.Kind, "user", job.UserID, "attempt", job.Attempts+1, "retryAt", next, "error", err)
+		if err = c.repo.FailJob(ctx, job.ID, err.Error(), next, now); err != nil {
+			slog.Error("failed to record lifecycle job failure", "job", job.ID, "error", err)
+		}
+		return
+	}
+
+	if err = c.repo.CompleteJob(ctx, job, now); err != nil {
+		slog.Error("failed to complete lifecycle job", "job", job.ID, "error", err)
+		return
+	}
+
+	slog.Info("lifecycle job done", "job", job.ID, "kind", job.Kind, "user", job.UserID)
+}
+
+// provision creates the user's storage, unless the user was deleted in the
+// meantime: the purge may already be done and must not be undone.
+func (c *coordinator) provision(ctx context.Context, userID string) error {
+	_, err := c.repo.GetUser(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		slog.Info("skipping provisioning of deleted user", "user", userID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return c.files.RegisterUser(ctx, userID)
+}
+
+// purge removes the user's storage and avatars.
+func (c *coordinator) purge(ctx context.Context, userID string) error {
+	if err := c.files.UnregisterUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := c.files.RemoveFolder(ctx, c.avatarBucket, path.Join("users", userID)+"/"); err != nil {
+		return fmt.Errorf("failed to remove avatars: %w", err)
+	}
+
+	return nil
+}
+
+func (c *coordinator) backoff(attempts int32) time.Duration {
+	delay := c.conf.RetryBaseDelay
+	for i := int32(0); i < attempts && delay < c.conf.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, c.conf.RetryMaxDelay)
+}
+
+func New(repo repository.Repository, files files.Client, conf config.Lifecycle, avatarBucket string) Coordinator {
+	return &coordinator{
+		repo:         repo,
+		files:        files,
+		conf:         conf,
+		avatarBucket: avatarBucket,
+		kick:         make(chan struct{}, 1),
+	}
+}
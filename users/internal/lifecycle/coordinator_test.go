@@ -0,0 +1,217 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/config"
+	"github.com/avran02/decoplan/users/internal/files"
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/internal/repository"
+)
+
+// fakeRepo keeps the jobs handed to the coordinator and what became of them.
+type fakeRepo struct {
+	repository.Repository
+	users     map[string]bool
+	due       [][]models.LifecycleJob
+	completed []string
+	failed    map[string]time.Time
+}
+
+func (r *fakeRepo) GetUser(_ context.Context, userID string) (models.User, error) {
+	if !r.users[userID] {
+		return models.User{}, repository.ErrNotFound
+	}
+
+	return models.User{ID: userID}, nil
+}
+
+func (r *fakeRepo) ClaimDueJobs(context.Context, time.Time, time.Time, int) ([]models.LifecycleJob, error) {
+	if len(r.due) == 0 {
+		return nil, nil
+	}
+	jobs := r.due[0]
+	r.due = r.due[1:]
+
+	return jobs, nil
+}
+
+func (r *fakeRepo) CompleteJob(_ context.Context, job models.LifecycleJob, _ time.Time) error {
+	r.completed = append(r.completed, job.ID)
+	return nil
+}
+
+func (r *fakeRepo) FailJob(_ context.Context, jobID, _ string, nextAttemptAt, _ time.Time) error {
+	r.failed[jobID] = nextAttemptAt
+	return nil
+}
+
+// fakeFiles records the calls made to the files service.
+type fakeFiles struct {
+	files.Client
+	err   error
+	calls []string
+}
+
+func (f *fakeFiles) RegisterUser(_ context.Context, userID string) error {
+	f.calls = append(f.calls, "register "+userID)
+	return f.err
+}
+
+func (f *fakeFiles) UnregisterUser(_ context.Context, userID string) error {
+	f.calls = append(f.calls, "unregister "+userID)
+	return f.err
+}
+
+func (f *fakeFiles) RemoveFolder(_ context.Context, bucket, dir string) error {
+	f.calls = append(f.calls, "remove "+bucket+"/"+dir)
+	return f.err
+}
+
+var testConf = config.Lifecycle{
+	PollInterval:   time.Minute,
+	RetryBaseDelay: time.Second,
+	RetryMaxDelay:  time.Minute,
+}
+
+func newTestCoordinator(repo *fakeRepo, f *fakeFiles) *coordinator {
+	return New(repo, f, testConf, "avatars").(*coordinator)
+}
+
+func TestRunJob(t *testing.T) {
+	boom := errors.New("files service is down")
+
+	tests := []struct {
+		name          string
+		job           models.LifecycleJob
+		users         map[string]bool
+		filesErr      error
+		wantCalls     []string
+		wantCompleted bool
+	}{
+		{
+			name:          "provision",
+			job:           models.LifecycleJob{ID: "1", UserID: "alice", Kind: models.LifecycleProvision},
+			users:         map[string]bool{"alice": true},
+			wantCalls:     []string{"register alice"},
+			wantCompleted: true,
+		},
+		{
+			name:          "provision of a deleted user",
+			job:           models.LifecycleJob{ID: "1", UserID: "alice", Kind: models.LifecycleProvision},
+			wantCompleted: true,
+		},
+		{
+			name:      "provision failed",
+			job:       models.LifecycleJob{ID: "1", UserID: "alice", Kind: models.LifecycleProvision},
+			users:     map[string]bool{"alice": true},
+			filesErr:  boom,
+			wantCalls: []string{"register alice"},
+		},
+		{
+			name:          "purge",
+			job:           models.LifecycleJob{ID: "1", UserID: "alice", Kind: models.LifecyclePurge},
+			wantCalls:     []string{"unregister alice", "remove avatars/users/alice/"},
+			wantCompleted: true,
+		},
+		{
+			name:      "purge failed",
+			job:       models.LifecycleJob{ID: "1", UserID: "alice", Kind: models.LifecyclePurge},
+			filesErr:  boom,
+			wantCalls: []string{"unregister alice"},
+		},
+		{
+			name: "unknown kind",
+			job:  models.LifecycleJob{ID: "1", UserID: "alice", Kind: "rename"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{users: tt.users, failed: map[string]time.Time{}}
+			f := &fakeFiles{err: tt.filesErr}
+			c := newTestCoordinator(repo, f)
+
+			before := time.Now().UTC()
+			c.runJob(context.Background(), tt.job)
+
+			if fmt.Sprint(f.calls) != fmt.Sprint(tt.wantCalls) {
+				t.Errorf("files calls = %v, want %v", f.calls, tt.wantCalls)
+			}
+			if completed := len(repo.completed) == 1; completed != tt.wantCompleted {
+				t.Errorf("completed = %v, want %v", completed, tt.wantCompleted)
+			}
+
+			next, failed := repo.failed[tt.job.ID]
+			if failed == tt.wantCompleted {
+				t.Errorf("failed = %v, want %v", failed, !tt.wantCompleted)
+			}
+			if failed && next.Before(before.Add(testConf.RetryBaseDelay)) {
+				t.Errorf("retry at %v, before the base delay", next)
+			}
+		})
+	}
+}
+
+func TestRunDueJobs(t *testing.T) {
+	full := make([]models.LifecycleJob, batchSize)
+	for i := range full {
+		full[i] = models.LifecycleJob{ID: fmt.Sprint(i), UserID: "alice", Kind: models.LifecyclePurge}
+	}
+	repo := &fakeRepo{
+		due: [][]models.LifecycleJob{
+			full,
+			{{ID: "last", UserID: "bob", Kind: models.LifecyclePurge}},
+			{{ID: "next poll", UserID: "carol", Kind: models.LifecyclePurge}},
+		},
+		failed: map[string]time.Time{},
+	}
+	c := newTestCoordinator(repo, &fakeFiles{})
+
+	// a full batch means more may be due, a short one ends the round
+	c.runDueJobs(context.Background())
+
+	if len(repo.completed) != batchSize+1 {
+		t.Errorf("completed %d jobs, want %d", len(repo.completed), batchSize+1)
+	}
+	if len(repo.due) != 1 {
+		t.Errorf("%d batches left, want the one for the next poll", len(repo.due))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := newTestCoordinator(&fakeRepo{}, &fakeFiles{})
+
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{attempts: 0, want: time.Second},
+		{attempts: 1, want: 2 * time.Second},
+		{attempts: 5, want: 32 * time.Second},
+		{attempts: 6, want: time.Minute},
+		{attempts: 1000, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := c.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestKick(t *testing.T) {
+	c := newTestCoordinator(&fakeRepo{}, &fakeFiles{})
+
+	// kicks while Run is busy collapse into one
+	c.Kick()
+	c.Kick()
+
+	if len(c.kick) != 1 {
+		t.Errorf("%d kicks queued, want 1", len(c.kick))
+	}
+}
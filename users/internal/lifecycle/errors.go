@@ -0,0 +1,5 @@
+package lifecycle
+
+import "errors"
+
+var ErrUnknownJobKind = errors.New("unknown lifecycle job kind")
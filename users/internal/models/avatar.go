@@ -0,0 +1,16 @@
+package models
+
+// AvatarTarget names the user or the group whose avatar is replaced.
+// Exactly one of the IDs is set.
+type AvatarTarget struct {
+	UserID  string
+	GroupID string
+}
+
+// Avatar is a stored avatar. Ref is written to avatar_url, it's the avatars
+// bucket followed by the prefix that holds the original and the thumbnails.
+type Avatar struct {
+	Ref        string
+	Original   string
+	Thumbnails map[int]string
+}
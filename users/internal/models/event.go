@@ -0,0 +1,82 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventUserCreated EventType = "user.created"
+	EventUserUpdated EventType = "user.updated"
+	EventUserDeleted EventType = "user.deleted"
+
+	EventGroupCreated          EventType = "group.created"
+	EventGroupUpdated          EventType = "group.updated"
+	EventGroupDeleted          EventType = "group.deleted"
+	EventGroupOwnershipChanged EventType = "group.ownership_transferred"
+	EventMembershipAdded       EventType = "membership.added"
+	EventMembershipRemoved     EventType = "membership.removed"
+	EventMembershipRoleChanged EventType = "membership.role_changed"
+)
+
+func (t EventType) IsValid() bool {
+	switch t {
+	case EventUserCreated, EventUserUpdated, EventUserDeleted,
+		EventGroupCreated, EventGroupUpdated, EventGroupDeleted, EventGroupOwnershipChanged,
+		EventMembershipAdded, EventMembershipRemoved, EventMembershipRoleChanged:
+		return true
+	default:
+		return false
+	}
+}
+
+// AggregateType is the part of the event type before the dot.
+func (t EventType) AggregateType() string {
+	aggregate, _, _ := strings.Cut(string(t), ".")
+	return aggregate
+}
+
+// Event is a domain event as stored in the outbox. Sequence grows with every
+// published event and lets consumers resume after the last event they've
+// seen.
+type Event struct {
+	Sequence    int64
+	ID          string
+	Type        EventType
+	AggregateID string
+	Payload     json.RawMessage
+	CreatedAt   time.Time
+	// Audience are the users the event is about, only their webhooks get
+	// it. It isn't sent anywhere.
+	Audience []string
+}
+
+type UserEvent struct {
+	ID        string     `json:"id"`
+	Name      *string    `json:"name,omitempty"`
+	Avatar    *string    `json:"avatar,omitempty"`
+	BirthDate *time.Time `json:"birthDate,omitempty"`
+}
+
+type GroupEvent struct {
+	ID      string  `json:"id"`
+	Name    *string `json:"name,omitempty"`
+	Avatar  *string `json:"avatar,omitempty"`
+	OwnerID *string `json:"ownerID,omitempty"`
+	Version *int64  `json:"version,omitempty"`
+}
+
+type OwnershipEvent struct {
+	GroupID    string `json:"groupID"`
+	FromUserID string `json:"fromUserID"`
+	ToUserID   string `json:"toUserID"`
+}
+
+type MembershipEvent struct {
+	GroupID string `json:"groupID"`
+	UserID  string `json:"userID"`
+	Role    Role   `json:"role,omitempty"`
+}
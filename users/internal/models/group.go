@@ -4,5 +4,19 @@ type Group struct {
 	ID      string
 	Name    string
 	Avatar  *string
-	Members []*User
+	OwnerID string
+	Version int64
+	Members []Member
+
+	// Role is the listed user's role, set only by ListGroupsForUser.
+	Role Role
+}
+
+// UpdateGroup changes only the non-nil fields, and only if the group is
+// still at Version.
+type UpdateGroup struct {
+	ID      string
+	Name    *string
+	Avatar  *string
+	Version int64
 }
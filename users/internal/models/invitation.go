@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationDeclined InvitationStatus = "declined"
+)
+
+// Invitation is either addressed to a single invitee or carries a code that
+// any authenticated user can redeem until it expires or runs out of uses.
+type Invitation struct {
+	ID        string
+	GroupID   string
+	InviterID string
+	InviteeID *string
+	Code      *string
+	Status    InvitationStatus
+	MaxUses   *int32
+	Uses      int32
+	CreatedAt time.Time
+	ExpiresAt time.Time
+
+	// Link is built from the code on the way out and isn't stored.
+	Link *string
+}
+
+func (i Invitation) IsExpired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
+
+func (i Invitation) IsExhausted() bool {
+	return i.MaxUses != nil && i.Uses >= *i.MaxUses
+}
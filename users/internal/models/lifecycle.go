@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+type LifecycleJobKind string
+
+const (
+	// LifecycleProvision creates the user's storage in the files service.
+	LifecycleProvision LifecycleJobKind = "provision"
+	// LifecyclePurge removes the user's storage and then the user row.
+	LifecyclePurge LifecycleJobKind = "purge"
+)
+
+type LifecycleJobStatus string
+
+const (
+	LifecycleJobPending LifecycleJobStatus = "pending"
+	LifecycleJobDone    LifecycleJobStatus = "done"
+	// LifecycleJobCancelled is a provision of a user deleted before it ran.
+	LifecycleJobCancelled LifecycleJobStatus = "cancelled"
+)
+
+type LifecycleJob struct {
+	ID            string
+	UserID        string
+	Kind          LifecycleJobKind
+	Status        LifecycleJobStatus
+	Attempts      int32
+	LastError     *string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type UserState string
+
+const (
+	UserActive   UserState = "active"
+	UserDeleting UserState = "deleting"
+	UserDeleted  UserState = "deleted"
+)
+
+// UserLifecycle is the progress of provisioning and deletion of a user.
+type UserLifecycle struct {
+	UserID string
+	State  UserState
+	Jobs   []LifecycleJob
+}
@@ -0,0 +1,49 @@
+package models
+
+type SortField string
+
+const (
+	SortByName SortField = "name"
+	SortByID   SortField = "id"
+
+	// SortByRelevance orders trigram search results from the closest match.
+	SortByRelevance SortField = "relevance"
+)
+
+type SearchMode string
+
+const (
+	SearchPrefix  SearchMode = "prefix"
+	SearchTrigram SearchMode = "trigram"
+)
+
+// ListOptions describes one page of a keyset-paginated listing. PageToken is
+// the opaque token returned with the previous page and must be used with
+// the same sorting.
+type ListOptions struct {
+	PageSize  int
+	PageToken string
+	SortBy    SortField
+	Desc      bool
+}
+
+type UserFilter struct {
+	NamePrefix *string
+	HasAvatar  *bool
+}
+
+type GroupFilter struct {
+	NamePrefix *string
+	OwnerID    *string
+	Role       *Role
+}
+
+type UserPage struct {
+	Users         []User
+	NextPageToken string
+}
+
+type GroupPage struct {
+	Groups        []Group
+	NextPageToken string
+}
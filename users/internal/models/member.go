@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Member is a user as seen from a group. Name and Avatar are only filled
+// when the profile was requested.
+type Member struct {
+	UserID   string
+	Name     *string
+	Avatar   *string
+	Role     Role
+	JoinedAt time.Time
+}
+
+type MemberPage struct {
+	Members       []Member
+	NextPageToken string
+}
@@ -0,0 +1,24 @@
+package models
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+)
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleOwner, RoleAdmin, RoleMember:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanManageGroup reports whether the role is allowed to change membership,
+// rename or delete the group.
+func (r Role) CanManageGroup() bool {
+	return r == RoleOwner || r == RoleAdmin
+}
@@ -3,4 +3,5 @@ package models
 type UserGroup struct {
 	GroupID string
 	UserID  string
+	Role    Role
 }
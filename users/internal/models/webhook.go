@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// EventWebhookTest is only sent by TestWebhook and never written to the
+// outbox.
+const EventWebhookTest EventType = "webhook.test"
+
+type Webhook struct {
+	ID      string
+	OwnerID string
+	URL     string
+	// Secret signs the deliveries. It's returned only when the webhook is
+	// created.
+	Secret     string
+	EventTypes []EventType
+	CreatedAt  time.Time
+}
+
+// Wants reports whether the webhook gets the event: its owner has to be in
+// the event's audience and subscribed to the type. No types means every
+// type.
+func (w Webhook) Wants(e Event) bool {
+	if len(w.EventTypes) != 0 && !slices.Contains(w.EventTypes, e.Type) {
+		return false
+	}
+
+	return slices.Contains(e.Audience, w.OwnerID)
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead is a delivery that ran out of attempts, it has a copy in
+	// the dead letters.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      string
+	EventID        string
+	EventType      EventType
+	Payload        json.RawMessage
+	Status         DeliveryStatus
+	Attempts       int32
+	LastStatusCode *int32
+	LastError      *string
+	NextAttemptAt  time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// DeliveryResult is the outcome of one delivery attempt. StatusCode is zero
+// if no response was received.
+type DeliveryResult struct {
+	StatusCode int32
+	Err        error
+}
+
+type DeliveryPage struct {
+	Deliveries    []WebhookDelivery
+	NextPageToken string
+}
@@ -4,4 +4,9 @@ import "errors"
 
 var (
 	ErrNothingToUpdate = errors.New("nothing to update")
+	ErrNotFound        = errors.New("not found")
+	ErrVersionConflict = errors.New("version conflict")
+	ErrAlreadyInvited  = errors.New("user already has a pending invitation to the group")
+
+	ErrInvalidPageToken = errors.New("invalid page token")
 )
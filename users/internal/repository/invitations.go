@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/avran02/decoplan/users/internal/models"
+)
+
+const invitationColumns = `id, group_id, inviter_id, invitee_id, code, status, max_uses, uses, created_at, expires_at`
+
+const (
+	uniqueViolation     = "23505"
+	pendingInviteeIndex = "group_invitations_pending_invitee_idx"
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func (p *postgres) CreateInvitation(ctx context.Context, inv models.Invitation) error {
+	return createInvitation(ctx, p.db, inv)
+}
+
+func (p *postgres) GetInvitation(ctx context.Context, invitationID string) (models.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM group_invitations WHERE id = $1`
+	return scanInvitation(p.db.QueryRowContext(ctx, query, invitationID))
+}
+
+func (p *postgres) GetInvitationByCode(ctx context.Context, code string) (models.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM group_invitations WHERE code = $1`
+	return scanInvitation(p.db.QueryRowContext(ctx, query, code))
+}
+
+func (p *postgres) ListPendingInvitations(ctx context.Context, inviteeID string, now time.Time) ([]models.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM group_invitations
+              WHERE invitee_id = $1 AND status = $2 AND expires_at > $3
+              ORDER BY created_at DESC`
+
+	rows, err := p.db.QueryContext(ctx, query, inviteeID, models.InvitationPending, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := make([]models.Invitation, 0)
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// AcceptInvitation adds the user to the invitation's group. Direct
+// invitations are closed, code invitations just count one more use. The
+// guarded update makes concurrent accepts of the same invitation safe:
+// ErrNotFound means it's no longer pending, expired or used up.
+func (p *postgres) AcceptInvitation(ctx context.Context, inv models.Invitation, userID string, now time.Time) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var res sql.Result
+	if inv.InviteeID != nil {
+		query := `UPDATE group_invitations SET status = $1, responded_at = $2
+                  WHERE id = $3 AND invitee_id = $4 AND status = $5 AND expires_at > $2`
+		res, err = tx.ExecContext(ctx, query, models.InvitationAccepted, now, inv.ID, userID, models.InvitationPending)
+	} else {
+		query := `UPDATE group_invitations SET uses = uses + 1
+                  WHERE id = $1 AND status = $2 AND expires_at > $3
+                  AND (max_uses IS NULL OR uses < max_uses)`
+		res, err = tx.ExecContext(ctx, query, inv.ID, models.InvitationPending, now)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO user_groups (group_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err = tx.ExecContext(ctx, query, inv.GroupID, userID, models.RoleMember); err != nil {
+		return fmt.Errorf("failed to add user to group: %w", err)
+	}
+
+	event := models.MembershipEvent{GroupID: inv.GroupID, UserID: userID, Role: models.RoleMember}
+	if err = insertEvent(ctx, tx, models.EventMembershipAdded, inv.GroupID, event); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (p *postgres) DeclineInvitation(ctx context.Context, invitationID, userID string, now time.Time) error {
+	query := `UPDATE group_invitations SET status = $1, responded_at = $2
+              WHERE id = $3 AND invitee_id = $4 AND status = $5`
+	res, err := p.db.ExecContext(ctx, query, models.InvitationDeclined, now, invitationID, userID, models.InvitationPending)
+	if err != nil {
+		return fmt.Errorf("failed to decline invitation: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// createInvitation fails with ErrAlreadyInvited if the invitee already has
+// a pending invitation to the group. An expired one is replaced.
+func createInvitation(ctx context.Context, db execer, inv models.Invitation) error {
+	if inv.InviteeID != nil {
+		query := `DELETE FROM group_invitations
+                  WHERE group_id = $1 AND invitee_id = $2 AND status = $3 AND expires_at <= $4`
+		if _, err := db.ExecContext(ctx, query, inv.GroupID, *inv.InviteeID, models.InvitationPending, inv.CreatedAt); err != nil {
+			return fmt.Errorf("failed to remove expired invitation: %w", err)
+		}
+	}
+
+	query := `INSERT INTO group_invitations (id, group_id, inviter_id, invitee_id, code, status, max_uses, created_at, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := db.ExecContext(ctx, query,
+		inv.ID, inv.GroupID, inv.InviterID, inv.InviteeID, inv.Code, models.InvitationPending, inv.MaxUses, inv.CreatedAt, inv.ExpiresAt,
+	)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == pendingInviteeIndex {
+		return ErrAlreadyInvited
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return nil
+}
+
+func scanInvitation(row rowScanner) (models.Invitation, error) {
+	var inv models.Invitation
+	var inviterID sql.NullString
+
+	if err := row.Scan(
+		&inv.ID, &inv.GroupID, &inviterID, &inv.InviteeID, &inv.Code, &inv.Status, &inv.MaxUses, &inv.Uses, &inv.CreatedAt, &inv.ExpiresAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Invitation{}, ErrNotFound
+		}
+		return models.Invitation{}, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	inv.InviterID = inviterID.String
+
+	return inv, nil
+}
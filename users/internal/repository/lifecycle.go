@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/models"
+)
+
+const lifecycleJobColumns = `id, user_id, kind, status, attempts, last_error, next_attempt_at, created_at, updated_at`
+
+// MarkUserDeleted is the first phase of a user deletion: the user disappears
+// from every read, pending provisions are cancelled and a purge job is
+// queued for the rest. A provision may still be running under its lease, so
+// the purge doesn't start before the lease is over.
+func (p *postgres) MarkUserDeleted(ctx context.Context, userID string, job models.LifecycleJob) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := `UPDATE users SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+	res, err := tx.ExecContext(ctx, query, job.CreatedAt, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark user deleted: %w", err)
+	}
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+
+	query = `UPDATE user_lifecycle_jobs SET status = $1, updated_at = $2
+             WHERE user_id = $3 AND kind = $4 AND status = $5
+             RETURNING next_attempt_at`
+	rows, err := tx.QueryContext(ctx, query, models.LifecycleJobCancelled, job.CreatedAt, userID, models.LifecycleProvision, models.LifecycleJobPending)
+	if err != nil {
+		return fmt.Errorf("failed to cancel provisioning: %w", err)
+	}
+	for rows.Next() {
+		var leasedUntil time.Time
+		if err = rows.Scan(&leasedUntil); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan cancelled provisioning: %w", err)
+		}
+		if leasedUntil.After(job.NextAttemptAt) {
+			job.NextAttemptAt = leasedUntil
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to cancel provisioning: %w", err)
+	}
+
+	if err = createLifecycleJob(ctx, tx, job); err != nil {
+		return err
+	}
+
+	if err = insertEvent(ctx, tx, models.EventUserDeleted, userID, models.UserEvent{ID: userID}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueJobs leases up to limit pending jobs that are due. A leased job is
+// pushed back to leaseUntil, so another instance only picks it up again if
+// this one dies before completing or failing it.
+func (p *postgres) ClaimDueJobs(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.LifecycleJob, error) {
+	query := `UPDATE user_lifecycle_jobs SET next_attempt_at = $1, updated_at = $2
+              WHERE id IN (
+                  SELECT id FROM user_lifecycle_jobs
+                  WHERE status = $3 AND next_attempt_at <= $2
+                  ORDER BY next_attempt_at
+                  LIMIT $4
+                  FOR UPDATE SKIP LOCKED
+              )
+              RETURNING ` + lifecycleJobColumns
+
+	rows, err := p.db.QueryContext(ctx, query, leaseUntil, now, models.LifecycleJobPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim lifecycle jobs: %w", err)
+	}
+	defer rows.Close()
+
+	return scanLifecycleJobs(rows)
+}
+
+// CompleteJob marks the job done. Completing a purge also hands the user's
+// groups over and removes the user row, which cascades to memberships and
+// invitations.
+func (p *postgres) CompleteJob(ctx context.Context, job models.LifecycleJob, now time.Time) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if job.Kind == models.LifecyclePurge {
+		if err = handOverGroups(ctx, tx, job.UserID); err != nil {
+			return err
+		}
+
+		query := `DELETE FROM users WHERE id = $1 AND deleted_at IS NOT NULL`
+		if _, err = tx.ExecContext(ctx, query, job.UserID); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+	}
+
+	query := `UPDATE user_lifecycle_jobs SET status = $1, attempts = attempts + 1, last_error = NULL, updated_at = $2
+              WHERE id = $3`
+	if _, err = tx.ExecContext(ctx, query, models.LifecycleJobDone, now, job.ID); err != nil {
+		return fmt.Errorf("failed to complete lifecycle job: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (p *postgres) FailJob(ctx context.Context, jobID, lastError string, nextAttemptAt, now time.Time) error {
+	query := `UPDATE user_lifecycle_jobs SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2, updated_at = $3
+              WHERE id = $4`
+	if _, err := p.db.ExecContext(ctx, query, lastError, nextAttemptAt, now, jobID); err != nil {
+		return fmt.Errorf("failed to fail lifecycle job: %w", err)
+	}
+
+	return nil
+}
+
+func (p *postgres) GetUserLifecycle(ctx context.Context, userID string) (models.UserLifecycle, error) {
+	query := `SELECT ` + lifecycleJobColumns + ` FROM user_lifecycle_jobs WHERE user_id = $1 ORDER BY created_at`
+	rows, err := p.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return models.UserLifecycle{}, fmt.Errorf("failed to get user lifecycle: %w", err)
+	}
+	defer rows.Close()
+
+	jobs, err := scanLifecycleJobs(rows)
+	if err != nil {
+		return models.UserLifecycle{}, err
+	}
+
+	var deletedAt sql.NullTime
+	query = `SELECT deleted_at FROM users WHERE id = $1`
+	err = p.db.QueryRowContext(ctx, query, userID).Scan(&deletedAt)
+	switch {
+	case err == nil && deletedAt.Valid:
+		return models.UserLifecycle{UserID: userID, State: models.UserDeleting, Jobs: jobs}, nil
+	case err == nil:
+		return models.UserLifecycle{UserID: userID, State: models.UserActive, Jobs: jobs}, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return models.UserLifecycle{}, fmt.Errorf("failed to get user lifecycle: %w", err)
+	case len(jobs) == 0:
+		return models.UserLifecycle{}, ErrNotFound
+	default:
+		return models.UserLifecycle{UserID: userID, State: models.UserDeleted, Jobs: jobs}, nil
+	}
+}
+
+// handOverGroups moves ownership of the user's groups to another member,
+// admins first and then by seniority. Groups without anyone else left are
+// deleted, nobody could manage them.
+func handOverGroups(ctx context.Context, tx *sql.Tx, userID string) error {
+	query := `SELECT g.id, (
+                  SELECT ug.user_id FROM user_groups ug JOIN users u ON u.id = ug.user_id
+                  WHERE ug.group_id = g.id AND ug.user_id <> $1 AND u.deleted_at IS NULL
+                  ORDER BY ug.role = $2 DESC, ug.joined_at, ug.user_id
+                  LIMIT 1
+              ) FROM groups g WHERE g.owner_id = $1 FOR UPDATE`
+	rows, err := tx.QueryContext(ctx, query, userID, models.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to get owned groups: %w", err)
+	}
+
+	successors := make(map[string]sql.NullString)
+	for rows.Next() {
+		var groupID string
+		var successor sql.NullString
+		if err = rows.Scan(&groupID, &successor); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan owned group: %w", err)
+		}
+		successors[groupID] = successor
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to scan owned groups: %w", err)
+	}
+
+	for groupID, successor := range successors {
+		if !successor.Valid {
+			if err = insertEvent(ctx, tx, models.EventGroupDeleted, groupID, models.GroupEvent{ID: groupID}); err != nil {
+				return err
+			}
+			if _, err = tx.ExecContext(ctx, `DELETE FROM groups WHERE id = $1`, groupID); err != nil {
+				return fmt.Errorf("failed to delete ownerless group: %w", err)
+			}
+			continue
+		}
+
+		query = `UPDATE groups SET owner_id = $1 WHERE id = $2`
+		if _, err = tx.ExecContext(ctx, query, successor.String, groupID); err != nil {
+			return fmt.Errorf("failed to transfer group ownership: %w", err)
+		}
+
+		query = `UPDATE user_groups SET role = $1 WHERE group_id = $2 AND user_id = $3`
+		if _, err = tx.ExecContext(ctx, query, models.RoleOwner, groupID, successor.String); err != nil {
+			return fmt.Errorf("failed to promote new owner: %w", err)
+		}
+
+		event := models.OwnershipEvent{GroupID: groupID, FromUserID: userID, ToUserID: successor.String}
+		if err = insertEvent(ctx, tx, models.EventGroupOwnershipChanged, groupID, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createLifecycleJob(ctx context.Context, db execer, job models.LifecycleJob) error {
+	query := `INSERT INTO user_lifecycle_jobs (id, user_id, kind, status, next_attempt_at, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $6)`
+	_, err := db.ExecContext(ctx, query, job.ID, job.UserID, job.Kind, models.LifecycleJobPending, job.NextAttemptAt, job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create lifecycle job: %w", err)
+	}
+
+	return nil
+}
+
+func scanLifecycleJobs(rows *sql.Rows) ([]models.LifecycleJob, error) {
+	jobs := make([]models.LifecycleJob, 0)
+	for rows.Next() {
+		var job models.LifecycleJob
+		if err := rows.Scan(
+			&job.ID, &job.UserID, &job.Kind, &job.Status, &job.Attempts, &job.LastError, &job.NextAttemptAt, &job.CreatedAt, &job.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan lifecycle job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan lifecycle jobs: %w", err)
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,345 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/avran02/decoplan/users/internal/models"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// cursor is the position after the last row of a page. It remembers the
+// sorting it was made for, so it can't be replayed against another order.
+type cursor struct {
+	SortBy models.SortField `json:"s"`
+	Desc   bool             `json:"d"`
+	Key    string           `json:"k,omitempty"`
+	Score  float32          `json:"r,omitempty"`
+	ID     string           `json:"id"`
+}
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c) //nolint:errchkjson
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(token string, sortBy models.SortField, desc bool) (cursor, bool, error) {
+	if token == "" {
+		return cursor{}, false, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor{}, false, ErrInvalidPageToken
+	}
+
+	var c cursor
+	if err = json.Unmarshal(b, &c); err != nil {
+		return cursor{}, false, ErrInvalidPageToken
+	}
+
+	if c.SortBy != sortBy || c.Desc != desc {
+		return cursor{}, false, ErrInvalidPageToken
+	}
+
+	return c, true, nil
+}
+
+type queryBuilder struct {
+	conds []string
+	args  []any
+}
+
+func (b *queryBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *queryBuilder) where(cond string) {
+	b.conds = append(b.conds, cond)
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(b.conds, " AND ")
+}
+
+// keyset adds the seek condition for the cursor and returns the matching
+// ORDER BY clause. Rows are ordered by keyExpr with idExpr as tie-breaker.
+func (b *queryBuilder) keyset(keyExpr, idExpr string, c cursor, hasCursor, desc bool) string {
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if keyExpr == idExpr {
+		if hasCursor {
+			b.where(fmt.Sprintf("%s %s %s", idExpr, cmp, b.arg(c.ID)))
+		}
+		return fmt.Sprintf("ORDER BY %s %s", idExpr, dir)
+	}
+
+	if hasCursor {
+		b.where(fmt.Sprintf("(%s, %s) %s (%s, %s)", keyExpr, idExpr, cmp, b.arg(c.Key), b.arg(c.ID)))
+	}
+	return fmt.Sprintf("ORDER BY %s %s, %s %s", keyExpr, dir, idExpr, dir)
+}
+
+func sortKeyExpr(sortBy models.SortField, table string) string {
+	if sortBy == models.SortByID {
+		return table + ".id"
+	}
+
+	return "LOWER(" + table + ".name)"
+}
+
+func likePrefix(prefix string) string {
+	return likeEscaper.Replace(prefix) + "%"
+}
+
+func (p *postgres) ListUsers(ctx context.Context, opts models.ListOptions, filter models.UserFilter) (models.UserPage, error) {
+	c, hasCursor, err := decodeCursor(opts.PageToken, opts.SortBy, opts.Desc)
+	if err != nil {
+		return models.UserPage{}, err
+	}
+
+	var b queryBuilder
+	b.where("u.deleted_at IS NULL")
+
+	if filter.NamePrefix != nil {
+		b.where("LOWER(u.name) LIKE LOWER(" + b.arg(likePrefix(*filter.NamePrefix)) + ")")
+	}
+
+	if filter.HasAvatar != nil {
+		if *filter.HasAvatar {
+			b.where("u.avatar_url IS NOT NULL")
+		} else {
+			b.where("u.avatar_url IS NULL")
+		}
+	}
+
+	keyExpr := sortKeyExpr(opts.SortBy, "u")
+	orderBy := b.keyset(keyExpr, "u.id", c, hasCursor, opts.Desc)
+	query := fmt.Sprintf(`SELECT u.id, u.name, u.birth_date, u.avatar_url, %s FROM users u %s %s LIMIT %s`,
+		keyExpr, b.whereClause(), orderBy, b.arg(opts.PageSize+1))
+
+	return p.queryUsers(ctx, query, b.args, opts, false)
+}
+
+// SearchUsers matches names by prefix, ordered by name, or by trigram
+// similarity, ordered from the closest match.
+func (p *postgres) SearchUsers(ctx context.Context, text string, mode models.SearchMode, opts models.ListOptions) (models.UserPage, error) {
+	if mode == models.SearchPrefix {
+		return p.ListUsers(ctx, opts, models.UserFilter{NamePrefix: &text})
+	}
+
+	c, hasCursor, err := decodeCursor(opts.PageToken, opts.SortBy, opts.Desc)
+	if err != nil {
+		return models.UserPage{}, err
+	}
+
+	var b queryBuilder
+	q := b.arg(text)
+	score := fmt.Sprintf("similarity(LOWER(u.name), LOWER(%s))", q)
+	b.where(fmt.Sprintf("LOWER(u.name) %% LOWER(%s)", q))
+	b.where("u.deleted_at IS NULL")
+
+	if hasCursor {
+		s, id := b.arg(c.Score), b.arg(c.ID)
+		b.where(fmt.Sprintf("(%[1]s < %[2]s::real OR (%[1]s = %[2]s::real AND u.id > %[3]s))", score, s, id))
+	}
+
+	query := fmt.Sprintf(`SELECT u.id, u.name, u.birth_date, u.avatar_url, %[1]s FROM users u %[2]s
+              ORDER BY %[1]s DESC, u.id ASC LIMIT %[3]s`, score, b.whereClause(), b.arg(opts.PageSize+1))
+
+	return p.queryUsers(ctx, query, b.args, opts, true)
+}
+
+func (p *postgres) ListGroups(ctx context.Context, opts models.ListOptions, filter models.GroupFilter) (models.GroupPage, error) {
+	c, hasCursor, err := decodeCursor(opts.PageToken, opts.SortBy, opts.Desc)
+	if err != nil {
+		return models.GroupPage{}, err
+	}
+
+	var b queryBuilder
+	if filter.NamePrefix != nil {
+		b.where("LOWER(g.name) LIKE LOWER(" + b.arg(likePrefix(*filter.NamePrefix)) + ")")
+	}
+
+	if filter.OwnerID != nil {
+		b.where("g.owner_id = " + b.arg(*filter.OwnerID))
+	}
+
+	keyExpr := sortKeyExpr(opts.SortBy, "g")
+	orderBy := b.keyset(keyExpr, "g.id", c, hasCursor, opts.Desc)
+	query := fmt.Sprintf(`SELECT g.id, g.name, g.avatar_url, g.owner_id, g.version, NULL, %s FROM groups g %s %s LIMIT %s`,
+		keyExpr, b.whereClause(), orderBy, b.arg(opts.PageSize+1))
+
+	return p.queryGroups(ctx, query, b.args, opts)
+}
+
+func (p *postgres) ListGroupsForUser(ctx context.Context, userID string, opts models.ListOptions, filter models.GroupFilter) (models.GroupPage, error) {
+	c, hasCursor, err := decodeCursor(opts.PageToken, opts.SortBy, opts.Desc)
+	if err != nil {
+		return models.GroupPage{}, err
+	}
+
+	var b queryBuilder
+	b.where("ug.user_id = " + b.arg(userID))
+
+	if filter.NamePrefix != nil {
+		b.where("LOWER(g.name) LIKE LOWER(" + b.arg(likePrefix(*filter.NamePrefix)) + ")")
+	}
+
+	if filter.Role != nil {
+		b.where("ug.role = " + b.arg(*filter.Role))
+	}
+
+	keyExpr := sortKeyExpr(opts.SortBy, "g")
+	orderBy := b.keyset(keyExpr, "g.id", c, hasCursor, opts.Desc)
+	query := fmt.Sprintf(`SELECT g.id, g.name, g.avatar_url, g.owner_id, g.version, ug.role, %s FROM groups g
+              JOIN user_groups ug ON ug.group_id = g.id %s %s LIMIT %s`,
+		keyExpr, b.whereClause(), orderBy, b.arg(opts.PageSize+1))
+
+	return p.queryGroups(ctx, query, b.args, opts)
+}
+
+func (p *postgres) ListGroupMembers(ctx context.Context, groupID string, opts models.ListOptions, filter models.GroupFilter) (models.MemberPage, error) {
+	c, hasCursor, err := decodeCursor(opts.PageToken, opts.SortBy, opts.Desc)
+	if err != nil {
+		return models.MemberPage{}, err
+	}
+
+	var b queryBuilder
+	b.where("ug.group_id = " + b.arg(groupID))
+	b.where("u.deleted_at IS NULL")
+
+	if filter.NamePrefix != nil {
+		b.where("LOWER(u.name) LIKE LOWER(" + b.arg(likePrefix(*filter.NamePrefix)) + ")")
+	}
+
+	if filter.Role != nil {
+		b.where("ug.role = " + b.arg(*filter.Role))
+	}
+
+	keyExpr := sortKeyExpr(opts.SortBy, "u")
+	orderBy := b.keyset(keyExpr, "u.id", c, hasCursor, opts.Desc)
+	query := fmt.Sprintf(`SELECT u.id, u.name, u.avatar_url, ug.role, ug.joined_at, %s FROM user_groups ug
+              JOIN users u ON u.id = ug.user_id %s %s LIMIT %s`,
+		keyExpr, b.whereClause(), orderBy, b.arg(opts.PageSize+1))
+
+	rows, err := p.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return models.MemberPage{}, fmt.Errorf("failed to list group members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]models.Member, 0, opts.PageSize)
+	var last cursor
+	for rows.Next() {
+		var member models.Member
+		var name string
+		var key string
+
+		if err := rows.Scan(&member.UserID, &name, &member.Avatar, &member.Role, &member.JoinedAt, &key); err != nil {
+			return models.MemberPage{}, fmt.Errorf("failed to list group members: %w", err)
+		}
+
+		if len(members) == opts.PageSize {
+			return models.MemberPage{Members: members, NextPageToken: last.encode()}, nil
+		}
+
+		member.Name = &name
+		members = append(members, member)
+		last = cursor{SortBy: opts.SortBy, Desc: opts.Desc, Key: key, ID: member.UserID}
+	}
+
+	if err = rows.Err(); err != nil {
+		return models.MemberPage{}, fmt.Errorf("failed to list group members: %w", err)
+	}
+
+	return models.MemberPage{Members: members}, nil
+}
+
+func (p *postgres) queryUsers(ctx context.Context, query string, args []any, opts models.ListOptions, byScore bool) (models.UserPage, error) {
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.UserPage{}, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0, opts.PageSize)
+	var last cursor
+	for rows.Next() {
+		var user models.User
+		var birthDate sql.NullTime
+		var key string
+		var score float32
+
+		dest := []any{&user.ID, &user.Name, &birthDate, &user.Avatar, &key}
+		if byScore {
+			dest[4] = &score
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return models.UserPage{}, fmt.Errorf("failed to list users: %w", err)
+		}
+
+		if len(users) == opts.PageSize {
+			return models.UserPage{Users: users, NextPageToken: last.encode()}, nil
+		}
+
+		user.BirthDate = birthDate.Time
+		users = append(users, user)
+		last = cursor{SortBy: opts.SortBy, Desc: opts.Desc, Key: key, Score: score, ID: user.ID}
+	}
+
+	if err = rows.Err(); err != nil {
+		return models.UserPage{}, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return models.UserPage{Users: users}, nil
+}
+
+func (p *postgres) queryGroups(ctx context.Context, query string, args []any, opts models.ListOptions) (models.GroupPage, error) {
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.GroupPage{}, fmt.Errorf("failed to list groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]models.Group, 0, opts.PageSize)
+	var last cursor
+	for rows.Next() {
+		var group models.Group
+		var ownerID, role sql.NullString
+		var key string
+
+		if err := rows.Scan(&group.ID, &group.Name, &group.Avatar, &ownerID, &group.Version, &role, &key); err != nil {
+			return models.GroupPage{}, fmt.Errorf("failed to list groups: %w", err)
+		}
+
+		if len(groups) == opts.PageSize {
+			return models.GroupPage{Groups: groups, NextPageToken: last.encode()}, nil
+		}
+
+		group.OwnerID = ownerID.String
+		group.Role = models.Role(role.String)
+		groups = append(groups, group)
+		last = cursor{SortBy: opts.SortBy, Desc: opts.Desc, Key: key, ID: group.ID}
+	}
+
+	if err = rows.Err(); err != nil {
+		return models.GroupPage{}, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	return models.GroupPage{Groups: groups}, nil
+}
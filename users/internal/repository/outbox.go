@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	eventColumns        = `published_sequence, id, type, aggregate_id, payload, created_at, audience`
+	pendingEventColumns = `sequence, id, type, aggregate_id, payload, created_at, audience`
+)
+
+// publishLock serializes relays, see PublishPendingEvents.
+const publishLock = 0x6f7574626f78
+
+// PublishPendingEvents takes up to limit unpublished events in insert order
+// and hands them to publish one by one, each with the next published
+// sequence. Events are marked published up to the first failure, the rest
+// stay in the outbox for the next round.
+//
+// Insert sequences become visible out of order when transactions commit out
+// of order, so consumers page on the published sequence instead. Relays take
+// turns under a lock held until commit, which makes published sequences
+// visible in order too.
+func (p *postgres) PublishPendingEvents(ctx context.Context, limit int, publish func(models.Event) error) (int, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, publishLock); err != nil {
+		return 0, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+
+	query := `SELECT ` + pendingEventColumns + ` FROM outbox WHERE published_at IS NULL
+              ORDER BY sequence LIMIT $1`
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending events: %w", err)
+	}
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	sequences := make([]int64, 0, len(events))
+	published := make([]int64, 0, len(events))
+	var publishErr error
+	for _, event := range events {
+		sequence := event.Sequence
+		query = `SELECT nextval('outbox_published_sequence_seq')`
+		if err = tx.QueryRowContext(ctx, query).Scan(&event.Sequence); err != nil {
+			return 0, fmt.Errorf("failed to get published sequence: %w", err)
+		}
+
+		if publishErr = publish(event); publishErr != nil {
+			break
+		}
+		sequences = append(sequences, sequence)
+		published = append(published, event.Sequence)
+	}
+
+	if len(sequences) == 0 {
+		if publishErr != nil {
+			return 0, fmt.Errorf("failed to publish event: %w", publishErr)
+		}
+		return 0, nil
+	}
+
+	query = `UPDATE outbox SET published_at = $1, published_sequence = p.published_sequence
+             FROM unnest($2::BIGINT[], $3::BIGINT[]) AS p(sequence, published_sequence)
+             WHERE outbox.sequence = p.sequence`
+	if _, err = tx.ExecContext(ctx, query, time.Now().UTC(), pq.Array(sequences), pq.Array(published)); err != nil {
+		return 0, fmt.Errorf("failed to mark events published: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(sequences), nil
+}
+
+// ListEvents returns published events after the given published sequence,
+// it's used to replay history to consumers that reconnect.
+func (p *postgres) ListEvents(ctx context.Context, afterSequence int64, limit int) ([]models.Event, error) {
+	query := `SELECT ` + eventColumns + ` FROM outbox WHERE published_sequence > $1
+              ORDER BY published_sequence LIMIT $2`
+	rows, err := p.db.QueryContext(ctx, query, afterSequence, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	return scanEvents(rows)
+}
+
+// LastEventSequence returns the sequence of the newest published event, or 0
+// if there are none.
+func (p *postgres) LastEventSequence(ctx context.Context) (int64, error) {
+	query := `SELECT COALESCE(MAX(published_sequence), 0) FROM outbox`
+
+	var sequence int64
+	if err := p.db.QueryRowContext(ctx, query).Scan(&sequence); err != nil {
+		return 0, fmt.Errorf("failed to get last event sequence: %w", err)
+	}
+
+	return sequence, nil
+}
+
+// DeletePublishedEvents drops events published before the cutoff, consumers
+// that fall further behind have to resync from the API.
+func (p *postgres) DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM outbox WHERE published_at < $1`
+	res, err := p.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published events: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// insertEvent writes the event to the outbox. Its audience is the user it's
+// about, or the group's members at the time plus the users named in the
+// payload, like a member who just left.
+func insertEvent(ctx context.Context, db execer, eventType models.EventType, aggregateID string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	var named []string
+	switch p := payload.(type) {
+	case models.MembershipEvent:
+		named = []string{p.UserID}
+	case models.OwnershipEvent:
+		named = []string{p.FromUserID, p.ToUserID}
+	}
+
+	query := `INSERT INTO outbox (id, type, aggregate_type, aggregate_id, payload, created_at, audience)
+              VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $3 = 'user' THEN ARRAY[$4::VARCHAR(255)]
+                  ELSE ARRAY(SELECT user_id FROM user_groups WHERE group_id = $4) END || $7::VARCHAR(255)[])`
+	_, err = db.ExecContext(ctx, query,
+		uuid.NewString(), eventType, eventType.AggregateType(), aggregateID, data, time.Now().UTC(), pq.Array(named))
+	if err != nil {
+		return fmt.Errorf("failed to write %s event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+func scanEvents(rows *sql.Rows) ([]models.Event, error) {
+	defer rows.Close()
+
+	events := make([]models.Event, 0)
+	for rows.Next() {
+		var event models.Event
+		if err := rows.Scan(
+			&event.Sequence, &event.ID, &event.Type, &event.AggregateID, &event.Payload, &event.CreatedAt, pq.Array(&event.Audience),
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan events: %w", err)
+	}
+
+	return events, nil
+}
@@ -3,27 +3,68 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"github.com/avran02/decoplan/users/internal/config"
 	"github.com/avran02/decoplan/users/internal/models"
 )
 
 type Repository interface {
-	AddUserToGroup(ctx context.Context, ug models.UserGroup) error
-	CreateGroup(ctx context.Context, name, groupID string, userIDs []string) error
+	CreateGroup(ctx context.Context, group models.Group, invitations []models.Invitation) error
 	DeleteGroup(ctx context.Context, groupID string) error
-	GetGroup(ctx context.Context, groupID string) (models.Group, error)
+	GetGroup(ctx context.Context, groupID string, withProfiles bool) (models.Group, error)
+	ListGroupMembers(ctx context.Context, groupID string, opts models.ListOptions, filter models.GroupFilter) (models.MemberPage, error)
+	UpdateGroup(ctx context.Context, group models.UpdateGroup) (int64, error)
+	SetGroupAvatar(ctx context.Context, groupID, avatar string) error
+	GetMemberRole(ctx context.Context, groupID, userID string) (models.Role, error)
 	RemoveUserFromGroup(ctx context.Context, ug models.UserGroup) error
-	DeleteUser(ctx context.Context, userID string) error
+	SetMemberRole(ctx context.Context, ug models.UserGroup) error
+	TransferGroupOwnership(ctx context.Context, groupID, fromUserID, toUserID string) error
 	GetUser(ctx context.Context, userID string) (models.User, error)
+	GetUsers(ctx context.Context, userIDs []string) ([]models.User, error)
 	UpdateUser(ctx context.Context, user models.UpdateUser) error
-	CreateUser(ctx context.Context, user models.User) error
+	CreateUser(ctx context.Context, user models.User, job models.LifecycleJob) error
+	MarkUserDeleted(ctx context.Context, userID string, job models.LifecycleJob) error
+	ClaimDueJobs(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.LifecycleJob, error)
+	CompleteJob(ctx context.Context, job models.LifecycleJob, now time.Time) error
+	FailJob(ctx context.Context, jobID, lastError string, nextAttemptAt, now time.Time) error
+	GetUserLifecycle(ctx context.Context, userID string) (models.UserLifecycle, error)
+
+	ListUsers(ctx context.Context, opts models.ListOptions, filter models.UserFilter) (models.UserPage, error)
+	SearchUsers(ctx context.Context, text string, mode models.SearchMode, opts models.ListOptions) (models.UserPage, error)
+	ListGroups(ctx context.Context, opts models.ListOptions, filter models.GroupFilter) (models.GroupPage, error)
+	ListGroupsForUser(ctx context.Context, userID string, opts models.ListOptions, filter models.GroupFilter) (models.GroupPage, error)
+
+	CreateInvitation(ctx context.Context, inv models.Invitation) error
+	GetInvitation(ctx context.Context, invitationID string) (models.Invitation, error)
+	GetInvitationByCode(ctx context.Context, code string) (models.Invitation, error)
+	ListPendingInvitations(ctx context.Context, inviteeID string, now time.Time) ([]models.Invitation, error)
+	AcceptInvitation(ctx context.Context, inv models.Invitation, userID string, now time.Time) error
+	DeclineInvitation(ctx context.Context, invitationID, userID string, now time.Time) error
+
+	PublishPendingEvents(ctx context.Context, limit int, publish func(models.Event) error) (int, error)
+	ListEvents(ctx context.Context, afterSequence int64, limit int) ([]models.Event, error)
+	LastEventSequence(ctx context.Context) (int64, error)
+	DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error)
+
+	CreateWebhook(ctx context.Context, webhook models.Webhook) error
+	GetWebhook(ctx context.Context, webhookID string) (models.Webhook, error)
+	ListWebhooks(ctx context.Context, ownerID string) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID, ownerID string) error
+	ListWebhooksOf(ctx context.Context, ownerIDs []string) ([]models.Webhook, error)
+	EnqueueDeliveries(ctx context.Context, event models.Event, body []byte, webhookIDs []string, now time.Time) (int64, error)
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	CompleteDelivery(ctx context.Context, deliveryID int64, statusCode int32, now time.Time) error
+	FailDelivery(ctx context.Context, deliveryID int64, statusCode *int32, lastError string, nextAttemptAt, now time.Time) error
+	KillDelivery(ctx context.Context, deliveryID int64, statusCode *int32, lastError string, now time.Time) error
+	ListDeliveries(ctx context.Context, webhookID string, opts models.ListOptions) (models.DeliveryPage, error)
 }
 
 type postgres struct {
@@ -31,68 +72,223 @@ type postgres struct {
 }
 
 func (p *postgres) RemoveUserFromGroup(ctx context.Context, ug models.UserGroup) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
 	query := `DELETE FROM user_groups WHERE group_id = $1 AND user_id = $2`
-	_, err := p.db.ExecContext(ctx, query, ug.GroupID, ug.UserID)
+	res, err := tx.ExecContext(ctx, query, ug.GroupID, ug.UserID)
 	if err != nil {
 		return fmt.Errorf("failed to remove user from group: %w", err)
 	}
 
-	return nil
-}
+	// removing someone who isn't a member is not an error, but not an event either
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
 
-func (p *postgres) DeleteUser(ctx context.Context, userID string) error {
-	query := `DELETE FROM users WHERE id = $1`
-	_, err := p.db.ExecContext(ctx, query, userID)
-	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+	event := models.MembershipEvent{GroupID: ug.GroupID, UserID: ug.UserID}
+	if err = insertEvent(ctx, tx, models.EventMembershipRemoved, ug.GroupID, event); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
 func (p *postgres) DeleteGroup(ctx context.Context, groupID string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// the event goes first, so its audience still has the members. Deleting
+	// nothing rolls it back.
+	if err = insertEvent(ctx, tx, models.EventGroupDeleted, groupID, models.GroupEvent{ID: groupID}); err != nil {
+		return err
+	}
+
 	query := `DELETE FROM groups WHERE id = $1`
-	_, err := p.db.ExecContext(ctx, query, groupID)
+	res, err := tx.ExecContext(ctx, query, groupID)
 	if err != nil {
 		return fmt.Errorf("failed to delete group: %w", err)
 	}
 
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-func (p *postgres) AddUserToGroup(ctx context.Context, ug models.UserGroup) error {
-	query := `INSERT INTO user_groups (group_id, user_id) VALUES ($1, $2)`
-	_, err := p.db.ExecContext(ctx, query, ug.GroupID, ug.UserID)
+func (p *postgres) GetMemberRole(ctx context.Context, groupID, userID string) (models.Role, error) {
+	query := `SELECT ug.role FROM user_groups ug JOIN users u ON u.id = ug.user_id
+              WHERE ug.group_id = $1 AND ug.user_id = $2 AND u.deleted_at IS NULL`
+	row := p.db.QueryRowContext(ctx, query, groupID, userID)
+
+	var role models.Role
+	if err := row.Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to get member role: %w", err)
+	}
+
+	return role, nil
+}
+
+func (p *postgres) SetMemberRole(ctx context.Context, ug models.UserGroup) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := `UPDATE user_groups SET role = $1 WHERE group_id = $2 AND user_id = $3`
+	res, err := tx.ExecContext(ctx, query, ug.Role, ug.GroupID, ug.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to set member role: %w", err)
+	}
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+
+	event := models.MembershipEvent{GroupID: ug.GroupID, UserID: ug.UserID, Role: ug.Role}
+	if err = insertEvent(ctx, tx, models.EventMembershipRoleChanged, ug.GroupID, event); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (p *postgres) TransferGroupOwnership(ctx context.Context, groupID, fromUserID, toUserID string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := `UPDATE groups SET owner_id = $1 WHERE id = $2 AND owner_id = $3`
+	res, err := tx.ExecContext(ctx, query, toUserID, groupID, fromUserID)
+	if err != nil {
+		return fmt.Errorf("failed to transfer group ownership: %w", err)
+	}
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+
+	query = `UPDATE user_groups SET role = $1 WHERE group_id = $2 AND user_id = $3`
+	if _, err = tx.ExecContext(ctx, query, models.RoleAdmin, groupID, fromUserID); err != nil {
+		return fmt.Errorf("failed to demote previous owner: %w", err)
+	}
+
+	res, err = tx.ExecContext(ctx, query, models.RoleOwner, groupID, toUserID)
 	if err != nil {
-		return fmt.Errorf("failed to add user to group: %w", err)
+		return fmt.Errorf("failed to promote new owner: %w", err)
+	}
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+
+	event := models.OwnershipEvent{GroupID: groupID, FromUserID: fromUserID, ToUserID: toUserID}
+	if err = insertEvent(ctx, tx, models.EventGroupOwnershipChanged, groupID, event); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (p *postgres) CreateUser(ctx context.Context, user models.User) error {
+// CreateUser creates the user together with the job that provisions their
+// storage.
+func (p *postgres) CreateUser(ctx context.Context, user models.User, job models.LifecycleJob) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
 	query := `INSERT INTO users (id, name, birth_date) VALUES ($1, $2, $3)`
-	_, err := p.db.ExecContext(ctx, query, user.ID, user.Name, user.BirthDate)
+	_, err = tx.ExecContext(ctx, query, user.ID, user.Name, user.BirthDate)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
+	if err = createLifecycleJob(ctx, tx, job); err != nil {
+		return err
+	}
+
+	event := models.UserEvent{ID: user.ID, Name: &user.Name, BirthDate: &user.BirthDate}
+	if err = insertEvent(ctx, tx, models.EventUserCreated, user.ID, event); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
 func (p *postgres) GetUser(ctx context.Context, userID string) (models.User, error) {
-	query := `SELECT id, name, birth_date, avatar_url FROM users WHERE id = $1`
+	query := `SELECT id, name, birth_date, avatar_url FROM users WHERE id = $1 AND deleted_at IS NULL`
 	row := p.db.QueryRowContext(ctx, query, userID)
 
 	var user models.User
 
 	if err := row.Scan(&user.ID, &user.Name, &user.BirthDate, &user.Avatar); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, ErrNotFound
+		}
 		return models.User{}, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	return user, nil
 }
 
+func (p *postgres) GetUsers(ctx context.Context, userIDs []string) ([]models.User, error) {
+	query := `SELECT id, name, birth_date, avatar_url FROM users WHERE id = ANY($1) AND deleted_at IS NULL`
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0, len(userIDs))
+	for rows.Next() {
+		var user models.User
+		var birthDate sql.NullTime
+		if err := rows.Scan(&user.ID, &user.Name, &birthDate, &user.Avatar); err != nil {
+			return nil, fmt.Errorf("failed to get users: %w", err)
+		}
+		user.BirthDate = birthDate.Time
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	return users, nil
+}
+
 func (p *postgres) UpdateUser(ctx context.Context, user models.UpdateUser) error {
 	var setParts []string
 	var args []interface{}
@@ -104,9 +300,9 @@ func (p *postgres) UpdateUser(ctx context.Context, user models.UpdateUser) error
 		argPos++
 	}
 
-	if !user.BirthDate.IsZero() {
+	if user.BirthDate != nil {
 		setParts = append(setParts, fmt.Sprintf("birth_date = $%d", argPos))
-		args = append(args, user.BirthDate)
+		args = append(args, *user.BirthDate)
 		argPos++
 	}
 
@@ -122,67 +318,244 @@ func (p *postgres) UpdateUser(ctx context.Context, user models.UpdateUser) error
 
 	args = append(args, user.ID)
 
-	query := fmt.Sprintf(`UPDATE users SET %s WHERE id = $%d`, strings.Join(setParts, ", "), argPos)
+	query := fmt.Sprintf(`UPDATE users SET %s WHERE id = $%d AND deleted_at IS NULL`, strings.Join(setParts, ", "), argPos)
 
-	_, err := p.db.ExecContext(ctx, query, args...)
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	res, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+
+	event := models.UserEvent{ID: user.ID, Name: user.Name, Avatar: user.Avatar, BirthDate: user.BirthDate}
+	if err = insertEvent(ctx, tx, models.EventUserUpdated, user.ID, event); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
 	return nil
 }
 
-func (p *postgres) CreateGroup(ctx context.Context, name, groupID string, userIDs []string) error {
-	slog.Debug("postgres.CreateGroup", "name", name, "groupID", groupID, "userIDs", userIDs)
+// CreateGroup creates the group with its owner as the only member. Everyone
+// else joins later by accepting one of the given invitations.
+func (p *postgres) CreateGroup(ctx context.Context, group models.Group, invitations []models.Invitation) error {
+	slog.Debug("postgres.CreateGroup", "name", group.Name, "groupID", group.ID, "ownerID", group.OwnerID, "invitations", len(invitations))
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
 
-	query := `INSERT INTO groups (id, name) VALUES ($1, $2)`
-	_, err := p.db.ExecContext(ctx, query, groupID, name)
+	query := `INSERT INTO groups (id, name, avatar_url, owner_id) VALUES ($1, $2, $3, $4)`
+	_, err = tx.ExecContext(ctx, query, group.ID, group.Name, group.Avatar, group.OwnerID)
 	if err != nil {
 		return fmt.Errorf("failed to create group: %w", err)
 	}
 
-	for _, userID := range userIDs {
-		query := `INSERT INTO user_groups (group_id, user_id) VALUES ($1, $2)`
-		_, err := p.db.ExecContext(ctx, query, groupID, userID)
-		if err != nil {
-			return fmt.Errorf("failed to add user to group: %w", err)
+	query = `INSERT INTO user_groups (group_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err = tx.ExecContext(ctx, query, group.ID, group.OwnerID, models.RoleOwner); err != nil {
+		return fmt.Errorf("failed to add owner to group: %w", err)
+	}
+
+	for _, inv := range invitations {
+		if err = createInvitation(ctx, tx, inv); err != nil {
+			return err
 		}
 	}
 
+	event := models.GroupEvent{ID: group.ID, Name: &group.Name, Avatar: group.Avatar, OwnerID: &group.OwnerID}
+	if err = insertEvent(ctx, tx, models.EventGroupCreated, group.ID, event); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-func (p *postgres) GetGroup(ctx context.Context, groupID string) (models.Group, error) {
-	query := `SELECT g.id, g.name, g.avatar_url, u.user_id FROM groups g 
-              LEFT JOIN user_groups u ON g.id = u.group_id
-              WHERE g.id = $1`
-
-	rows, err := p.db.QueryContext(ctx, query, groupID)
+// GetGroup returns the group with its members, leaving out deleted users.
+// With profiles it also fills in member names and avatars.
+func (p *postgres) GetGroup(ctx context.Context, groupID string, withProfiles bool) (models.Group, error) {
+	rows, err := p.db.QueryContext(ctx, groupQuery(withProfiles), groupID)
 	if err != nil {
 		return models.Group{}, fmt.Errorf("failed to get group: %w", err)
 	}
 	defer rows.Close()
 
-	var members []*models.User
+	var members []models.Member
 	var groupIDOut, groupName string
-	var avatar sql.NullString
+	var avatar, ownerID sql.NullString
+	var version int64
 
 	for rows.Next() {
-		var userID string
-		if err := rows.Scan(&groupIDOut, &groupName, &avatar, &userID); err != nil {
+		var userID, role sql.NullString
+		var joinedAt sql.NullTime
+		var member models.Member
+		if err := rows.Scan(
+			&groupIDOut, &groupName, &avatar, &ownerID, &version, &userID, &role, &joinedAt, &member.Name, &member.Avatar,
+		); err != nil {
 			return models.Group{}, fmt.Errorf("failed to get group: %w", err)
 		}
-		members = append(members, &models.User{ID: userID})
+		if userID.Valid {
+			member.UserID = userID.String
+			member.Role = models.Role(role.String)
+			member.JoinedAt = joinedAt.Time
+			members = append(members, member)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return models.Group{}, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	if groupIDOut == "" {
+		return models.Group{}, ErrNotFound
 	}
 
 	return models.Group{
 		ID:      groupIDOut,
 		Name:    groupName,
 		Avatar:  &avatar.String,
+		OwnerID: ownerID.String,
+		Version: version,
 		Members: members,
 	}, nil
 }
 
+// groupQuery selects a group with a row per member. Deleted users aren't
+// members any more, the group still gets a row when it has none left.
+func groupQuery(withProfiles bool) string {
+	profileColumns := "NULL, NULL"
+	if withProfiles {
+		profileColumns = "usr.name, usr.avatar_url"
+	}
+
+	return fmt.Sprintf(`SELECT g.id, g.name, g.avatar_url, g.owner_id, g.version, u.user_id, u.role, u.joined_at, %s FROM groups g
+              LEFT JOIN (user_groups u JOIN users usr ON usr.id = u.user_id AND usr.deleted_at IS NULL) ON g.id = u.group_id
+              WHERE g.id = $1`, profileColumns)
+}
+
+// SetGroupAvatar replaces the avatar without a version check, but still
+// bumps the version so that concurrent UpdateGroup calls notice the change.
+func (p *postgres) SetGroupAvatar(ctx context.Context, groupID, avatar string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var version int64
+	query := `UPDATE groups SET avatar_url = $1, version = version + 1 WHERE id = $2 RETURNING version`
+	if err = tx.QueryRowContext(ctx, query, avatar, groupID).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to set group avatar: %w", err)
+	}
+
+	event := models.GroupEvent{ID: groupID, Avatar: &avatar, Version: &version}
+	if err = insertEvent(ctx, tx, models.EventGroupUpdated, groupID, event); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateGroup applies the update only if the stored version still matches
+// and returns the new version. A stale version gives ErrVersionConflict.
+func (p *postgres) UpdateGroup(ctx context.Context, group models.UpdateGroup) (int64, error) {
+	var setParts []string
+	var args []interface{}
+	var argPos int = 1
+
+	if group.Name != nil {
+		setParts = append(setParts, fmt.Sprintf("name = $%d", argPos))
+		args = append(args, *group.Name)
+		argPos++
+	}
+
+	if group.Avatar != nil {
+		setParts = append(setParts, fmt.Sprintf("avatar_url = $%d", argPos))
+		args = append(args, *group.Avatar)
+		argPos++
+	}
+
+	if len(setParts) == 0 {
+		return 0, ErrNothingToUpdate
+	}
+
+	setParts = append(setParts, "version = version + 1")
+	args = append(args, group.ID, group.Version)
+
+	query := fmt.Sprintf(`UPDATE groups SET %s WHERE id = $%d AND version = $%d RETURNING version`,
+		strings.Join(setParts, ", "), argPos, argPos+1)
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var version int64
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&version)
+	if err == nil {
+		event := models.GroupEvent{ID: group.ID, Name: group.Name, Avatar: group.Avatar, Version: &version}
+		if err = insertEvent(ctx, tx, models.EventGroupUpdated, group.ID, event); err != nil {
+			return 0, err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return 0, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		return version, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to update group: %w", err)
+	}
+
+	query = `SELECT version FROM groups WHERE id = $1`
+	if err = p.db.QueryRowContext(ctx, query, group.ID).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("failed to update group: %w", err)
+	}
+
+	return 0, ErrVersionConflict
+}
+
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 func New(conf config.DB) Repository {
 	db, err := sql.Open("postgres", getDsn(conf))
 	if err != nil {
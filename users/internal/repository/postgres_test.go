@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupQuery(t *testing.T) {
+	tests := []struct {
+		withProfiles bool
+		wantColumns  string
+	}{
+		{withProfiles: false, wantColumns: "NULL, NULL"},
+		{withProfiles: true, wantColumns: "usr.name, usr.avatar_url"},
+	}
+
+	for _, tt := range tests {
+		query := groupQuery(tt.withProfiles)
+		if !strings.Contains(query, tt.wantColumns) {
+			t.Errorf("groupQuery(%t) doesn't select %s:\n%s", tt.withProfiles, tt.wantColumns, query)
+		}
+		// the filter belongs to the join, in WHERE it would drop the group
+		// of only deleted members
+		join := query[strings.Index(query, "LEFT JOIN"):strings.Index(query, "WHERE")]
+		if !strings.Contains(join, "usr.deleted_at IS NULL") {
+			t.Errorf("groupQuery(%t) keeps deleted members:\n%s", tt.withProfiles, query)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/avran02/decoplan/users/internal/models"
+)
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, last_status_code, last_error,
+              next_attempt_at, created_at, updated_at`
+
+func (p *postgres) CreateWebhook(ctx context.Context, webhook models.Webhook) error {
+	query := `INSERT INTO webhooks (id, owner_id, url, secret, event_types, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := p.db.ExecContext(ctx, query,
+		webhook.ID, webhook.OwnerID, webhook.URL, webhook.Secret, pq.Array(webhook.EventTypes), webhook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (p *postgres) GetWebhook(ctx context.Context, webhookID string) (models.Webhook, error) {
+	query := `SELECT id, owner_id, url, secret, event_types, created_at FROM webhooks WHERE id = $1`
+
+	webhook, err := scanWebhook(p.db.QueryRowContext(ctx, query, webhookID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Webhook{}, ErrNotFound
+		}
+		return models.Webhook{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks returns the owner's webhooks without their secrets.
+func (p *postgres) ListWebhooks(ctx context.Context, ownerID string) ([]models.Webhook, error) {
+	query := `SELECT id, owner_id, url, '', event_types, created_at FROM webhooks WHERE owner_id = $1 ORDER BY created_at, id`
+	rows, err := p.db.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhooks: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook with its deliveries and dead letters.
+func (p *postgres) DeleteWebhook(ctx context.Context, webhookID, ownerID string) error {
+	query := `DELETE FROM webhooks WHERE id = $1 AND owner_id = $2`
+	res, err := p.db.ExecContext(ctx, query, webhookID, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+// ListWebhooksOf returns the webhooks of all the owners with their secrets.
+func (p *postgres) ListWebhooksOf(ctx context.Context, ownerIDs []string) ([]models.Webhook, error) {
+	query := `SELECT id, owner_id, url, secret, event_types, created_at FROM webhooks WHERE owner_id = ANY($1)`
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(ownerIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhooks: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// EnqueueDeliveries queues the body for each of the webhooks that still
+// exists. An event relayed twice is only queued once per webhook.
+func (p *postgres) EnqueueDeliveries(ctx context.Context, event models.Event, body []byte, webhookIDs []string, now time.Time) (int64, error) {
+	query := `INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, next_attempt_at, created_at, updated_at)
+              SELECT id, $1, $2, $3, $4, $5, $5, $5 FROM webhooks WHERE id = ANY($6)
+              ON CONFLICT (webhook_id, event_id) DO NOTHING`
+	res, err := p.db.ExecContext(ctx, query, event.ID, event.Type, body, models.DeliveryPending, now, pq.Array(webhookIDs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// ClaimDueDeliveries leases due deliveries the same way ClaimDueJobs leases
+// lifecycle jobs.
+func (p *postgres) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	query := `UPDATE webhook_deliveries SET next_attempt_at = $1, updated_at = $2
+              WHERE id IN (
+                  SELECT id FROM webhook_deliveries
+                  WHERE status = $3 AND next_attempt_at <= $2
+                  ORDER BY next_attempt_at
+                  LIMIT $4
+                  FOR UPDATE SKIP LOCKED
+              )
+              RETURNING ` + deliveryColumns
+
+	rows, err := p.db.QueryContext(ctx, query, leaseUntil, now, models.DeliveryPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+func (p *postgres) CompleteDelivery(ctx context.Context, deliveryID int64, statusCode int32, now time.Time) error {
+	query := `UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, last_status_code = $2, last_error = NULL,
+              updated_at = $3 WHERE id = $4`
+	if _, err := p.db.ExecContext(ctx, query, models.DeliveryDelivered, statusCode, now, deliveryID); err != nil {
+		return fmt.Errorf("failed to complete webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (p *postgres) FailDelivery(ctx context.Context, deliveryID int64, statusCode *int32, lastError string, nextAttemptAt, now time.Time) error {
+	query := `UPDATE webhook_deliveries SET attempts = attempts + 1, last_status_code = $1, last_error = $2,
+              next_attempt_at = $3, updated_at = $4 WHERE id = $5`
+	if _, err := p.db.ExecContext(ctx, query, statusCode, lastError, nextAttemptAt, now, deliveryID); err != nil {
+		return fmt.Errorf("failed to fail webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// KillDelivery records the last failed attempt, gives up on the delivery and
+// copies it to the dead letters.
+func (p *postgres) KillDelivery(ctx context.Context, deliveryID int64, statusCode *int32, lastError string, now time.Time) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := `UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, last_status_code = $2, last_error = $3,
+              updated_at = $4 WHERE id = $5`
+	if _, err = tx.ExecContext(ctx, query, models.DeliveryDead, statusCode, lastError, now, deliveryID); err != nil {
+		return fmt.Errorf("failed to kill webhook delivery: %w", err)
+	}
+
+	query = `INSERT INTO webhook_dead_letters (delivery_id, webhook_id, event_id, event_type, payload, attempts, last_error, created_at)
+             SELECT id, webhook_id, event_id, event_type, payload, attempts, last_error, $1 FROM webhook_deliveries WHERE id = $2
+             ON CONFLICT (delivery_id) DO NOTHING`
+	if _, err = tx.ExecContext(ctx, query, now, deliveryID); err != nil {
+		return fmt.Errorf("failed to dead-letter webhook delivery: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the webhook's deliveries from the newest.
+func (p *postgres) ListDeliveries(ctx context.Context, webhookID string, opts models.ListOptions) (models.DeliveryPage, error) {
+	c, hasCursor, err := decodeCursor(opts.PageToken, models.SortByID, true)
+	if err != nil {
+		return models.DeliveryPage{}, err
+	}
+
+	var b queryBuilder
+	b.where("webhook_id = " + b.arg(webhookID))
+	orderBy := b.keyset("id", "id", c, hasCursor, true)
+	query := fmt.Sprintf(`SELECT %s FROM webhook_deliveries %s %s LIMIT %s`,
+		deliveryColumns, b.whereClause(), orderBy, b.arg(opts.PageSize+1))
+
+	rows, err := p.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return models.DeliveryPage{}, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return models.DeliveryPage{}, err
+	}
+
+	page := models.DeliveryPage{Deliveries: deliveries}
+	if len(deliveries) > opts.PageSize {
+		page.Deliveries = deliveries[:opts.PageSize]
+		last := page.Deliveries[opts.PageSize-1]
+		page.NextPageToken = cursor{SortBy: models.SortByID, Desc: true, ID: strconv.FormatInt(last.ID, 10)}.encode()
+	}
+
+	return page, nil
+}
+
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	var eventTypes []string
+	if err := row.Scan(
+		&webhook.ID, &webhook.OwnerID, &webhook.URL, &webhook.Secret, pq.Array(&eventTypes), &webhook.CreatedAt,
+	); err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook.EventTypes = make([]models.EventType, 0, len(eventTypes))
+	for _, t := range eventTypes {
+		webhook.EventTypes = append(webhook.EventTypes, models.EventType(t))
+	}
+
+	return webhook, nil
+}
+
+func scanDeliveries(rows *sql.Rows) ([]models.WebhookDelivery, error) {
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(
+			&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.LastStatusCode, &d.LastError,
+			&d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
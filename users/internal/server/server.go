@@ -36,6 +36,10 @@ func (s UsersServer) GetGroup(ctx context.Context, req *pb.GetGroupRequest) (*pb
 	return s.UserController.GetGroup(ctx, req)
 }
 
+func (s UsersServer) UpdateGroup(ctx context.Context, req *pb.UpdateGroupRequest) (*pb.UpdateGroupResponse, error) {
+	return s.UserController.UpdateGroup(ctx, req)
+}
+
 func (s UsersServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
 	return s.UserController.GetUser(ctx, req)
 }
@@ -48,8 +52,92 @@ func (s UsersServer) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 	return s.UserController.UpdateUser(ctx, req)
 }
 
+func (s UsersServer) TransferGroupOwnership(ctx context.Context, req *pb.TransferGroupOwnershipRequest) (*pb.TransferGroupOwnershipResponse, error) {
+	return s.UserController.TransferGroupOwnership(ctx, req)
+}
+
+func (s UsersServer) SetMemberRole(ctx context.Context, req *pb.SetMemberRoleRequest) (*pb.SetMemberRoleResponse, error) {
+	return s.UserController.SetMemberRole(ctx, req)
+}
+
+func (s UsersServer) InviteToGroup(ctx context.Context, req *pb.InviteToGroupRequest) (*pb.InviteToGroupResponse, error) {
+	return s.UserController.InviteToGroup(ctx, req)
+}
+
+func (s UsersServer) ListMyInvitations(ctx context.Context, req *pb.ListMyInvitationsRequest) (*pb.ListMyInvitationsResponse, error) {
+	return s.UserController.ListMyInvitations(ctx, req)
+}
+
+func (s UsersServer) AcceptInvitation(ctx context.Context, req *pb.AcceptInvitationRequest) (*pb.AcceptInvitationResponse, error) {
+	return s.UserController.AcceptInvitation(ctx, req)
+}
+
+func (s UsersServer) DeclineInvitation(ctx context.Context, req *pb.DeclineInvitationRequest) (*pb.DeclineInvitationResponse, error) {
+	return s.UserController.DeclineInvitation(ctx, req)
+}
+
+func (s UsersServer) RedeemInviteCode(ctx context.Context, req *pb.RedeemInviteCodeRequest) (*pb.RedeemInviteCodeResponse, error) {
+	return s.UserController.RedeemInviteCode(ctx, req)
+}
+
+func (s UsersServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	return s.UserController.ListUsers(ctx, req)
+}
+
+func (s UsersServer) SearchUsers(ctx context.Context, req *pb.SearchUsersRequest) (*pb.SearchUsersResponse, error) {
+	return s.UserController.SearchUsers(ctx, req)
+}
+
+func (s UsersServer) ListGroups(ctx context.Context, req *pb.ListGroupsRequest) (*pb.ListGroupsResponse, error) {
+	return s.UserController.ListGroups(ctx, req)
+}
+
+func (s UsersServer) ListGroupsForUser(ctx context.Context, req *pb.ListGroupsForUserRequest) (*pb.ListGroupsForUserResponse, error) {
+	return s.UserController.ListGroupsForUser(ctx, req)
+}
+
+func (s UsersServer) ListGroupMembers(ctx context.Context, req *pb.ListGroupMembersRequest) (*pb.ListGroupMembersResponse, error) {
+	return s.UserController.ListGroupMembers(ctx, req)
+}
+
+func (s UsersServer) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.GetUsersResponse, error) {
+	return s.UserController.GetUsers(ctx, req)
+}
+
+func (s UsersServer) UploadAvatar(stream pb.UsersService_UploadAvatarServer) error {
+	return s.UserController.UploadAvatar(stream)
+}
+
+func (s UsersServer) GetUserLifecycle(ctx context.Context, req *pb.GetUserLifecycleRequest) (*pb.GetUserLifecycleResponse, error) {
+	return s.UserController.GetUserLifecycle(ctx, req)
+}
+
 func New(controller *controller.UserController) UsersServer {
 	return UsersServer{
 		UserController: controller,
 	}
 }
+
+func (s UsersServer) WatchEvents(req *pb.WatchEventsRequest, stream pb.UsersService_WatchEventsServer) error {
+	return s.UserController.WatchEvents(req, stream)
+}
+
+func (s UsersServer) CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.CreateWebhookResponse, error) {
+	return s.UserController.CreateWebhook(ctx, req)
+}
+
+func (s UsersServer) ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	return s.UserController.ListWebhooks(ctx, req)
+}
+
+func (s UsersServer) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	return s.UserController.DeleteWebhook(ctx, req)
+}
+
+func (s UsersServer) TestWebhook(ctx context.Context, req *pb.TestWebhookRequest) (*pb.TestWebhookResponse, error) {
+	return s.UserController.TestWebhook(ctx, req)
+}
+
+func (s UsersServer) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	return s.UserController.ListWebhookDeliveries(ctx, req)
+}
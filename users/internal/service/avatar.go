@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+
+	"github.com/avran02/decoplan/users/internal/avatar"
+	"github.com/avran02/decoplan/users/internal/config"
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/google/uuid"
+)
+
+// UploadAvatar validates the image, stores it with its thumbnails in the
+// avatars bucket of the files service and points the user or group at it.
+// Users can only change their own avatar, group avatars need an owner or
+// admin.
+func (s *userService) UploadAvatar(ctx context.Context, callerID string, target models.AvatarTarget, content io.Reader) (models.Avatar, error) {
+	prefix, err := s.avatarPrefix(ctx, callerID, target)
+	if err != nil {
+		return models.Avatar{}, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, s.avatars.MaxSize+1))
+	if err != nil {
+		return models.Avatar{}, fmt.Errorf("failed to read avatar: %w", err)
+	}
+
+	limits := avatar.Limits{
+		MaxSize:      s.avatars.MaxSize,
+		MaxDimension: s.avatars.MaxDimension,
+		MinDimension: s.avatars.MinDimension,
+	}
+
+	img, err := avatar.Process(data, limits, config.AvatarThumbnailSizes)
+	if err != nil {
+		return models.Avatar{}, err
+	}
+
+	res := models.Avatar{
+		Ref:        path.Join(s.avatars.Bucket, prefix),
+		Original:   path.Join(prefix, "original."+img.Extension),
+		Thumbnails: make(map[int]string, len(img.Thumbnails)),
+	}
+
+	if err = s.files.UploadFile(ctx, s.avatars.Bucket, res.Original, bytes.NewReader(img.Original)); err != nil {
+		return models.Avatar{}, fmt.Errorf("failed to store avatar: %w", err)
+	}
+
+	for size, thumb := range img.Thumbnails {
+		thumbPath := path.Join(prefix, strconv.Itoa(size)+".png")
+		if err = s.files.UploadFile(ctx, s.avatars.Bucket, thumbPath, bytes.NewReader(thumb)); err != nil {
+			return models.Avatar{}, fmt.Errorf("failed to store avatar thumbnail: %w", err)
+		}
+		res.Thumbnails[size] = thumbPath
+	}
+
+	if target.UserID != "" {
+		err = s.repo.UpdateUser(ctx, models.UpdateUser{ID: target.UserID, Avatar: &res.Ref})
+	} else {
+		err = s.repo.SetGroupAvatar(ctx, target.GroupID, res.Ref)
+	}
+	if err != nil {
+		return models.Avatar{}, fmt.Errorf("failed to save avatar: %w", err)
+	}
+
+	return res, nil
+}
+
+// avatarPrefix checks the caller's rights on the target and returns a fresh
+// prefix, so replaced avatars never overwrite files someone may still show.
+func (s *userService) avatarPrefix(ctx context.Context, callerID string, target models.AvatarTarget) (string, error) {
+	if callerID == "" {
+		return "", ErrEmptyCallerID
+	}
+
+	switch {
+	case target.UserID != "" && target.GroupID != "", target.UserID == "" && target.GroupID == "":
+		return "", ErrInvalidAvatarTarget
+	case target.UserID != "":
+		if target.UserID != callerID {
+			return "", ErrPermissionDenied
+		}
+		return path.Join("users", target.UserID, uuid.NewString()), nil
+	default:
+		if _, err := s.authorize(ctx, target.GroupID, callerID); err != nil {
+			return "", err
+		}
+		return path.Join("groups", target.GroupID, uuid.NewString()), nil
+	}
+}
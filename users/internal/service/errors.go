@@ -0,0 +1,31 @@
+package service
+
+import "errors"
+
+var (
+	ErrEmptyCallerID    = errors.New("empty caller id")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrNotGroupMember   = errors.New("user is not a group member")
+	ErrInvalidRole      = errors.New("invalid role")
+	ErrOwnerRemoval     = errors.New("group owner can't be removed, transfer ownership first")
+
+	ErrAlreadyMember     = errors.New("user is already a group member")
+	ErrNotInvitee        = errors.New("invitation is addressed to another user")
+	ErrInvitationClosed  = errors.New("invitation is no longer pending")
+	ErrInvitationExpired = errors.New("invitation has expired")
+	ErrInvalidMaxUses    = errors.New("max uses must be positive")
+
+	ErrVersionRequired = errors.New("version is required")
+
+	ErrInvalidPageSize   = errors.New("page size must not be negative")
+	ErrInvalidSortField  = errors.New("invalid sort field")
+	ErrInvalidSearchMode = errors.New("invalid search mode")
+	ErrEmptySearchQuery  = errors.New("empty search query")
+	ErrBatchTooLarge     = errors.New("too many ids in one batch")
+
+	ErrInvalidAvatarTarget = errors.New("exactly one of user id and group id must be set")
+
+	ErrInvalidEventType = errors.New("invalid event type")
+
+	ErrInvalidWebhookURL = errors.New("webhook url must be an absolute http or https url")
+)
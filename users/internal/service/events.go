@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/models"
+)
+
+// WatchEvents sends events of the given types, or of every type if none are
+// given, until ctx is done or send fails. Without afterSequence only new
+// events are sent, with it the stream first replays what's still in the
+// outbox. Events are read from the outbox, the hub only tells us when to
+// look, and the poll interval covers events relayed by other instances.
+func (s *userService) WatchEvents(ctx context.Context, types []models.EventType, afterSequence *int64, send func(models.Event) error) error {
+	for _, t := range types {
+		if !t.IsValid() {
+			return ErrInvalidEventType
+		}
+	}
+
+	wake, stop := s.events.Subscribe()
+	defer stop()
+
+	var last int64
+	if afterSequence != nil {
+		last = *afterSequence
+	} else {
+		var err error
+		if last, err = s.repo.LastEventSequence(ctx); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(s.outbox.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		batch, err := s.repo.ListEvents(ctx, last, s.outbox.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range batch {
+			last = event.Sequence
+			if len(types) != 0 && !slices.Contains(types, event.Type) {
+				continue
+			}
+
+			if err = send(event); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) == s.outbox.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/internal/repository"
+	"github.com/google/uuid"
+)
+
+const inviteCodeBytes = 12
+
+// InviteToGroup creates a pending invitation. With an invitee it's a
+// personal invitation, without one it's a code that any authenticated user
+// can redeem.
+func (s *userService) InviteToGroup(ctx context.Context, callerID, groupID string, inviteeID *string, maxUses *int32) (models.Invitation, error) {
+	if _, err := s.authorize(ctx, groupID, callerID); err != nil {
+		return models.Invitation{}, err
+	}
+
+	if maxUses != nil && *maxUses <= 0 {
+		return models.Invitation{}, ErrInvalidMaxUses
+	}
+
+	if inviteeID != nil {
+		if err := s.ensureNotMember(ctx, groupID, *inviteeID); err != nil {
+			return models.Invitation{}, err
+		}
+	}
+
+	inv, err := s.newInvitation(groupID, callerID, inviteeID)
+	if err != nil {
+		return models.Invitation{}, err
+	}
+	inv.MaxUses = maxUses
+
+	if err = s.repo.CreateInvitation(ctx, inv); err != nil {
+		return models.Invitation{}, fmt.Errorf("failed to invite to group: %w", err)
+	}
+
+	return s.withLink(inv), nil
+}
+
+func (s *userService) ListMyInvitations(ctx context.Context, callerID string) ([]models.Invitation, error) {
+	if callerID == "" {
+		return nil, ErrEmptyCallerID
+	}
+
+	return s.repo.ListPendingInvitations(ctx, callerID, s.now())
+}
+
+func (s *userService) AcceptInvitation(ctx context.Context, callerID, invitationID string) (string, error) {
+	if callerID == "" {
+		return "", ErrEmptyCallerID
+	}
+
+	inv, err := s.repo.GetInvitation(ctx, invitationID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	if inv.InviteeID == nil || *inv.InviteeID != callerID {
+		return "", ErrNotInvitee
+	}
+
+	return s.accept(ctx, callerID, inv)
+}
+
+func (s *userService) DeclineInvitation(ctx context.Context, callerID, invitationID string) error {
+	if callerID == "" {
+		return ErrEmptyCallerID
+	}
+
+	inv, err := s.repo.GetInvitation(ctx, invitationID)
+	if err != nil {
+		return fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	if inv.InviteeID == nil || *inv.InviteeID != callerID {
+		return ErrNotInvitee
+	}
+
+	if inv.Status != models.InvitationPending {
+		return ErrInvitationClosed
+	}
+
+	if err = s.repo.DeclineInvitation(ctx, inv.ID, callerID, s.now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvitationClosed
+		}
+		return fmt.Errorf("failed to decline invitation: %w", err)
+	}
+
+	return nil
+}
+
+func (s *userService) RedeemInviteCode(ctx context.Context, callerID, code string) (string, error) {
+	if callerID == "" {
+		return "", ErrEmptyCallerID
+	}
+
+	inv, err := s.repo.GetInvitationByCode(ctx, code)
+	if err != nil {
+		return "", fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	if inv.InviteeID != nil && *inv.InviteeID != callerID {
+		return "", ErrNotInvitee
+	}
+
+	return s.accept(ctx, callerID, inv)
+}
+
+func (s *userService) accept(ctx context.Context, userID string, inv models.Invitation) (string, error) {
+	if inv.Status != models.InvitationPending || inv.IsExhausted() {
+		return "", ErrInvitationClosed
+	}
+
+	now := s.now()
+	if inv.IsExpired(now) {
+		return "", ErrInvitationExpired
+	}
+
+	if err := s.ensureNotMember(ctx, inv.GroupID, userID); err != nil {
+		return "", err
+	}
+
+	if err := s.repo.AcceptInvitation(ctx, inv, userID, now); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrInvitationClosed
+		}
+		return "", fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	return inv.GroupID, nil
+}
+
+func (s *userService) ensureNotMember(ctx context.Context, groupID, userID string) error {
+	_, err := s.repo.GetMemberRole(ctx, groupID, userID)
+	switch {
+	case err == nil:
+		return ErrAlreadyMember
+	case errors.Is(err, repository.ErrNotFound):
+		return nil
+	default:
+		return fmt.Errorf("failed to get member role: %w", err)
+	}
+}
+
+func (s *userService) newInvitation(groupID, inviterID string, inviteeID *string) (models.Invitation, error) {
+	now := s.now()
+	inv := models.Invitation{
+		ID:        uuid.NewString(),
+		GroupID:   groupID,
+		InviterID: inviterID,
+		InviteeID: inviteeID,
+		Status:    models.InvitationPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.invitations.TTL),
+	}
+
+	if inviteeID == nil {
+		code, err := newInviteCode()
+		if err != nil {
+			return models.Invitation{}, err
+		}
+		inv.Code = &code
+	}
+
+	return inv, nil
+}
+
+func (s *userService) withLink(inv models.Invitation) models.Invitation {
+	if inv.Code != nil && s.invitations.LinkBaseURL != "" {
+		link := s.invitations.LinkBaseURL + *inv.Code
+		inv.Link = &link
+	}
+
+	return inv
+}
+
+func (s *userService) now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func newInviteCode() (string, error) {
+	buf := make([]byte, inviteCodeBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate invite code: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
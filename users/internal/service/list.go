@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/avran02/decoplan/users/internal/models"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+	maxBatchSize    = 500
+)
+
+func (s *userService) ListUsers(ctx context.Context, opts models.ListOptions, filter models.UserFilter) (models.UserPage, error) {
+	opts, err := normalizeListOptions(opts)
+	if err != nil {
+		return models.UserPage{}, err
+	}
+
+	page, err := s.repo.ListUsers(ctx, opts, filter)
+	if err != nil {
+		return models.UserPage{}, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *userService) SearchUsers(ctx context.Context, text string, mode models.SearchMode, opts models.ListOptions) (models.UserPage, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return models.UserPage{}, ErrEmptySearchQuery
+	}
+
+	switch mode {
+	case models.SearchPrefix:
+		opts.SortBy = models.SortByName
+	case models.SearchTrigram:
+		opts.SortBy = models.SortByRelevance
+	default:
+		return models.UserPage{}, ErrInvalidSearchMode
+	}
+	opts.Desc = false
+
+	opts, err := normalizeListOptions(opts)
+	if err != nil {
+		return models.UserPage{}, err
+	}
+
+	page, err := s.repo.SearchUsers(ctx, text, mode, opts)
+	if err != nil {
+		return models.UserPage{}, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *userService) ListGroups(ctx context.Context, opts models.ListOptions, filter models.GroupFilter) (models.GroupPage, error) {
+	opts, err := normalizeListOptions(opts)
+	if err != nil {
+		return models.GroupPage{}, err
+	}
+
+	page, err := s.repo.ListGroups(ctx, opts, filter)
+	if err != nil {
+		return models.GroupPage{}, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *userService) ListGroupsForUser(ctx context.Context, userID string, opts models.ListOptions, filter models.GroupFilter) (models.GroupPage, error) {
+	if filter.Role != nil && !filter.Role.IsValid() {
+		return models.GroupPage{}, ErrInvalidRole
+	}
+
+	opts, err := normalizeListOptions(opts)
+	if err != nil {
+		return models.GroupPage{}, err
+	}
+
+	page, err := s.repo.ListGroupsForUser(ctx, userID, opts, filter)
+	if err != nil {
+		return models.GroupPage{}, fmt.Errorf("failed to list groups for user: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *userService) ListGroupMembers(ctx context.Context, groupID string, opts models.ListOptions, filter models.GroupFilter) (models.MemberPage, error) {
+	if filter.Role != nil && !filter.Role.IsValid() {
+		return models.MemberPage{}, ErrInvalidRole
+	}
+
+	opts, err := normalizeListOptions(opts)
+	if err != nil {
+		return models.MemberPage{}, err
+	}
+
+	page, err := s.repo.ListGroupMembers(ctx, groupID, opts, filter)
+	if err != nil {
+		return models.MemberPage{}, fmt.Errorf("failed to list group members: %w", err)
+	}
+
+	return page, nil
+}
+
+func normalizeListOptions(opts models.ListOptions) (models.ListOptions, error) {
+	switch {
+	case opts.PageSize < 0:
+		return opts, ErrInvalidPageSize
+	case opts.PageSize == 0:
+		opts.PageSize = defaultPageSize
+	case opts.PageSize > maxPageSize:
+		opts.PageSize = maxPageSize
+	}
+
+	switch opts.SortBy {
+	case "":
+		opts.SortBy = models.SortByName
+	case models.SortByName, models.SortByID, models.SortByRelevance:
+	default:
+		return opts, ErrInvalidSortField
+	}
+
+	return opts, nil
+}
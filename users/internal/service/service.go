@@ -2,56 +2,249 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"time"
 
+	"github.com/avran02/decoplan/users/internal/config"
+	"github.com/avran02/decoplan/users/internal/events"
+	"github.com/avran02/decoplan/users/internal/files"
+	"github.com/avran02/decoplan/users/internal/lifecycle"
 	"github.com/avran02/decoplan/users/internal/models"
 	"github.com/avran02/decoplan/users/internal/repository"
+	"github.com/avran02/decoplan/users/internal/webhook"
 	"github.com/google/uuid"
 )
 
 type UserService interface {
-	AddUserToGroup(ctx context.Context, userGroup models.UserGroup) error
-	CreateGroup(ctx context.Context, name string, userIDs []string) (string, error)
-	DeleteGroup(ctx context.Context, groupID string) error
-	GetGroup(ctx context.Context, groupID string) (models.Group, error)
-	RemoveUserFromGroup(ctx context.Context, userGroup models.UserGroup) error
+	AddUserToGroup(ctx context.Context, callerID string, userGroup models.UserGroup) error
+	CreateGroup(ctx context.Context, callerID, name string, avatar *string, userIDs []string) (string, error)
+	DeleteGroup(ctx context.Context, callerID, groupID string) error
+	GetGroup(ctx context.Context, groupID string, withProfiles bool) (models.Group, error)
+	ListGroupMembers(ctx context.Context, groupID string, opts models.ListOptions, filter models.GroupFilter) (models.MemberPage, error)
+	UpdateGroup(ctx context.Context, callerID string, group models.UpdateGroup) (int64, error)
+	RemoveUserFromGroup(ctx context.Context, callerID string, userGroup models.UserGroup) error
+	SetMemberRole(ctx context.Context, callerID string, userGroup models.UserGroup) error
+	TransferGroupOwnership(ctx context.Context, callerID, groupID, newOwnerID string) error
 	CreateUser(ctx context.Context, id, name string, birthDate time.Time) error
-	DeleteUser(ctx context.Context, userID string) error
+	DeleteUser(ctx context.Context, userID string) (string, error)
+	GetUserLifecycle(ctx context.Context, userID string) (models.UserLifecycle, error)
 	GetUser(ctx context.Context, userID string) (models.User, error)
+	GetUsers(ctx context.Context, userIDs []string) ([]models.User, error)
 	UpdateUser(ctx context.Context, user models.UpdateUser) error
+	UploadAvatar(ctx context.Context, callerID string, target models.AvatarTarget, content io.Reader) (models.Avatar, error)
+
+	InviteToGroup(ctx context.Context, callerID, groupID string, inviteeID *string, maxUses *int32) (models.Invitation, error)
+	ListMyInvitations(ctx context.Context, callerID string) ([]models.Invitation, error)
+	AcceptInvitation(ctx context.Context, callerID, invitationID string) (string, error)
+	DeclineInvitation(ctx context.Context, callerID, invitationID string) error
+	RedeemInviteCode(ctx context.Context, callerID, code string) (string, error)
+
+	ListUsers(ctx context.Context, opts models.ListOptions, filter models.UserFilter) (models.UserPage, error)
+	SearchUsers(ctx context.Context, text string, mode models.SearchMode, opts models.ListOptions) (models.UserPage, error)
+	ListGroups(ctx context.Context, opts models.ListOptions, filter models.GroupFilter) (models.GroupPage, error)
+	ListGroupsForUser(ctx context.Context, userID string, opts models.ListOptions, filter models.GroupFilter) (models.GroupPage, error)
+
+	WatchEvents(ctx context.Context, types []models.EventType, afterSequence *int64, send func(models.Event) error) error
+
+	CreateWebhook(ctx context.Context, callerID, url string, types []models.EventType) (models.Webhook, error)
+	ListWebhooks(ctx context.Context, callerID string) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, callerID, webhookID string) error
+	TestWebhook(ctx context.Context, callerID, webhookID string) (models.DeliveryResult, error)
+	ListWebhookDeliveries(ctx context.Context, callerID, webhookID string, opts models.ListOptions) (models.DeliveryPage, error)
 }
 
 type userService struct {
-	repo repository.Repository
+	repo        repository.Repository
+	files       files.Client
+	lifecycle   lifecycle.Coordinator
+	events      *events.Hub
+	sender      *webhook.Sender
+	invitations config.Invitations
+	avatars     config.Avatars
+	outbox      config.Outbox
+	webhooks    config.Webhooks
 }
 
-func (s *userService) AddUserToGroup(ctx context.Context, userGroup models.UserGroup) error {
-	return s.repo.AddUserToGroup(ctx, userGroup)
+// AddUserToGroup no longer adds the user directly, it sends them a personal
+// invitation that they have to accept.
+func (s *userService) AddUserToGroup(ctx context.Context, callerID string, userGroup models.UserGroup) error {
+	_, err := s.InviteToGroup(ctx, callerID, userGroup.GroupID, &userGroup.UserID, nil)
+	return err
 }
 
-func (s *userService) CreateGroup(ctx context.Context, name string, userIDs []string) (string, error) {
+// CreateGroup makes the caller the owner and invites the other users.
+func (s *userService) CreateGroup(ctx context.Context, callerID, name string, avatar *string, userIDs []string) (string, error) {
+	if callerID == "" {
+		return "", ErrEmptyCallerID
+	}
+
 	groupID := uuid.NewString()
-	if err := s.repo.CreateGroup(ctx, name, groupID, userIDs); err != nil {
+	invitations := make([]models.Invitation, 0, len(userIDs))
+	for _, userID := range slices.Compact(slices.Sorted(slices.Values(userIDs))) {
+		if userID == callerID {
+			continue
+		}
+
+		inv, err := s.newInvitation(groupID, callerID, &userID)
+		if err != nil {
+			return "", err
+		}
+		invitations = append(invitations, inv)
+	}
+
+	group := models.Group{
+		ID:      groupID,
+		Name:    name,
+		Avatar:  avatar,
+		OwnerID: callerID,
+	}
+
+	if err := s.repo.CreateGroup(ctx, group, invitations); err != nil {
 		return "", fmt.Errorf("failed to create group: %w", err)
 	}
 
 	return groupID, nil
 }
 
-func (s *userService) DeleteGroup(ctx context.Context, groupID string) error {
+func (s *userService) DeleteGroup(ctx context.Context, callerID, groupID string) error {
+	if _, err := s.authorize(ctx, groupID, callerID); err != nil {
+		return err
+	}
+
 	return s.repo.DeleteGroup(ctx, groupID)
+}
 
+func (s *userService) GetGroup(ctx context.Context, groupID string, withProfiles bool) (models.Group, error) {
+	return s.repo.GetGroup(ctx, groupID, withProfiles)
 }
 
-func (s *userService) GetGroup(ctx context.Context, groupID string) (models.Group, error) {
-	return s.repo.GetGroup(ctx, groupID)
+func (s *userService) UpdateGroup(ctx context.Context, callerID string, group models.UpdateGroup) (int64, error) {
+	if group.Version <= 0 {
+		return 0, ErrVersionRequired
+	}
+
+	if _, err := s.authorize(ctx, group.ID, callerID); err != nil {
+		return 0, err
+	}
+
+	version, err := s.repo.UpdateGroup(ctx, group)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update group: %w", err)
+	}
+
+	return version, nil
 }
 
-func (s *userService) RemoveUserFromGroup(ctx context.Context, userGroup models.UserGroup) error {
+// RemoveUserFromGroup lets owners and admins remove members and lets any
+// member leave the group on their own. Only the owner can remove admins,
+// and the owner can't be removed at all.
+func (s *userService) RemoveUserFromGroup(ctx context.Context, callerID string, userGroup models.UserGroup) error {
+	targetRole, err := s.memberRole(ctx, userGroup.GroupID, userGroup.UserID)
+	if err != nil {
+		return err
+	}
+
+	if targetRole == models.RoleOwner {
+		return ErrOwnerRemoval
+	}
+
+	if callerID != userGroup.UserID {
+		callerRole, err := s.authorize(ctx, userGroup.GroupID, callerID)
+		if err != nil {
+			return err
+		}
+
+		if targetRole == models.RoleAdmin && callerRole != models.RoleOwner {
+			return ErrPermissionDenied
+		}
+	}
+
 	return s.repo.RemoveUserFromGroup(ctx, userGroup)
+}
 
+// SetMemberRole promotes or demotes a member. Only the owner can change
+// roles, and ownership itself moves through TransferGroupOwnership.
+func (s *userService) SetMemberRole(ctx context.Context, callerID string, userGroup models.UserGroup) error {
+	if userGroup.Role != models.RoleAdmin && userGroup.Role != models.RoleMember {
+		return ErrInvalidRole
+	}
+
+	if _, err := s.authorize(ctx, userGroup.GroupID, callerID, models.RoleOwner); err != nil {
+		return err
+	}
+
+	targetRole, err := s.memberRole(ctx, userGroup.GroupID, userGroup.UserID)
+	if err != nil {
+		return err
+	}
+
+	if targetRole == models.RoleOwner {
+		return ErrPermissionDenied
+	}
+
+	return s.repo.SetMemberRole(ctx, userGroup)
 }
+
+func (s *userService) TransferGroupOwnership(ctx context.Context, callerID, groupID, newOwnerID string) error {
+	if _, err := s.authorize(ctx, groupID, callerID, models.RoleOwner); err != nil {
+		return err
+	}
+
+	if _, err := s.memberRole(ctx, groupID, newOwnerID); err != nil {
+		return err
+	}
+
+	if err := s.repo.TransferGroupOwnership(ctx, groupID, callerID, newOwnerID); err != nil {
+		return fmt.Errorf("failed to transfer group ownership: %w", err)
+	}
+
+	return nil
+}
+
+// authorize checks that the caller is a member of the group with one of the
+// allowed roles. Without explicit roles, owners and admins are allowed.
+func (s *userService) authorize(ctx context.Context, groupID, callerID string, allowed ...models.Role) (models.Role, error) {
+	if callerID == "" {
+		return "", ErrEmptyCallerID
+	}
+
+	role, err := s.repo.GetMemberRole(ctx, groupID, callerID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrPermissionDenied
+		}
+		return "", fmt.Errorf("failed to get caller role: %w", err)
+	}
+
+	if len(allowed) == 0 {
+		if !role.CanManageGroup() {
+			return "", ErrPermissionDenied
+		}
+		return role, nil
+	}
+
+	if !slices.Contains(allowed, role) {
+		return "", ErrPermissionDenied
+	}
+
+	return role, nil
+}
+
+func (s *userService) memberRole(ctx context.Context, groupID, userID string) (models.Role, error) {
+	role, err := s.repo.GetMemberRole(ctx, groupID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrNotGroupMember
+		}
+		return "", fmt.Errorf("failed to get member role: %w", err)
+	}
+
+	return role, nil
+}
+
 func (s *userService) CreateUser(ctx context.Context, id, name string, birthDate time.Time) error {
 	user := models.User{
 		ID:        id,
@@ -59,7 +252,12 @@ func (s *userService) CreateUser(ctx context.Context, id, name string, birthDate
 		BirthDate: birthDate,
 	}
 
-	return s.repo.CreateUser(ctx, user)
+	if err := s.repo.CreateUser(ctx, user, s.newLifecycleJob(id, models.LifecycleProvision)); err != nil {
+		return err
+	}
+	s.lifecycle.Kick()
+
+	return nil
 }
 
 func (s *userService) GetUser(ctx context.Context, userID string) (models.User, error) {
@@ -71,14 +269,78 @@ func (s *userService) GetUser(ctx context.Context, userID string) (models.User,
 	return user, nil
 }
 
+// GetUsers returns the found users in the order of userIDs, duplicates and
+// unknown IDs are skipped.
+func (s *userService) GetUsers(ctx context.Context, userIDs []string) ([]models.User, error) {
+	if len(userIDs) > maxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	users, err := s.repo.GetUsers(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	byID := make(map[string]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	ordered := make([]models.User, 0, len(users))
+	for _, id := range userIDs {
+		if user, ok := byID[id]; ok {
+			ordered = append(ordered, user)
+			delete(byID, id)
+		}
+	}
+
+	return ordered, nil
+}
+
 func (s *userService) UpdateUser(ctx context.Context, user models.UpdateUser) error {
 	return s.repo.UpdateUser(ctx, user)
 }
 
-func (s *userService) DeleteUser(ctx context.Context, userID string) error {
-	return s.repo.DeleteUser(ctx, userID)
+// DeleteUser hides the user right away and queues a purge job. The job
+// removes the user's bucket in the files service and only then the user row,
+// GetUserLifecycle reports how far it got.
+func (s *userService) DeleteUser(ctx context.Context, userID string) (string, error) {
+	job := s.newLifecycleJob(userID, models.LifecyclePurge)
+	if err := s.repo.MarkUserDeleted(ctx, userID, job); err != nil {
+		return "", fmt.Errorf("failed to delete user: %w", err)
+	}
+	s.lifecycle.Kick()
+
+	return job.ID, nil
+}
+
+func (s *userService) GetUserLifecycle(ctx context.Context, userID string) (models.UserLifecycle, error) {
+	return s.repo.GetUserLifecycle(ctx, userID)
 }
 
-func New(repo repository.Repository) UserService {
-	return &userService{repo: repo}
+func (s *userService) newLifecycleJob(userID string, kind models.LifecycleJobKind) models.LifecycleJob {
+	now := s.now()
+	return models.LifecycleJob{
+		ID:            uuid.NewString(),
+		UserID:        userID,
+		Kind:          kind,
+		Status:        models.LifecycleJobPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+func New(repo repository.Repository, files files.Client, lifecycle lifecycle.Coordinator, hub *events.Hub, sender *webhook.Sender, conf *config.Config) UserService {
+	return &userService{
+		repo:        repo,
+		files:       files,
+		lifecycle:   lifecycle,
+		events:      hub,
+		sender:      sender,
+		invitations: conf.Invitations,
+		avatars:     conf.Avatars,
+		outbox:      conf.Outbox,
+		webhooks:    conf.Webhooks,
+	}
 }
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/avran02/decoplan/users/internal/events"
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/google/uuid"
+)
+
+const webhookSecretBytes = 32
+
+// CreateWebhook subscribes the caller's URL to the given event types. The
+// returned webhook is the only place the signing secret is ever shown.
+func (s *userService) CreateWebhook(ctx context.Context, callerID, rawURL string, types []models.EventType) (models.Webhook, error) {
+	if callerID == "" {
+		return models.Webhook{}, ErrEmptyCallerID
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.Webhook{}, ErrInvalidWebhookURL
+	}
+
+	for _, t := range types {
+		if !t.IsValid() {
+			return models.Webhook{}, ErrInvalidEventType
+		}
+	}
+
+	secret := make([]byte, webhookSecretBytes)
+	if _, err = rand.Read(secret); err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	webhook := models.Webhook{
+		ID:         uuid.NewString(),
+		OwnerID:    callerID,
+		URL:        u.String(),
+		Secret:     hex.EncodeToString(secret),
+		EventTypes: slices.Compact(slices.Sorted(slices.Values(types))),
+		CreatedAt:  s.now(),
+	}
+
+	if err = s.repo.CreateWebhook(ctx, webhook); err != nil {
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func (s *userService) ListWebhooks(ctx context.Context, callerID string) ([]models.Webhook, error) {
+	if callerID == "" {
+		return nil, ErrEmptyCallerID
+	}
+
+	return s.repo.ListWebhooks(ctx, callerID)
+}
+
+func (s *userService) DeleteWebhook(ctx context.Context, callerID, webhookID string) error {
+	if callerID == "" {
+		return ErrEmptyCallerID
+	}
+
+	return s.repo.DeleteWebhook(ctx, webhookID, callerID)
+}
+
+// TestWebhook sends a signed webhook.test event right away and reports what
+// the receiver answered. Test deliveries aren't retried or logged.
+func (s *userService) TestWebhook(ctx context.Context, callerID, webhookID string) (models.DeliveryResult, error) {
+	webhook, err := s.getOwnWebhook(ctx, callerID, webhookID)
+	if err != nil {
+		return models.DeliveryResult{}, err
+	}
+
+	payload, err := json.Marshal(map[string]string{"webhookID": webhook.ID})
+	if err != nil {
+		return models.DeliveryResult{}, fmt.Errorf("failed to encode test payload: %w", err)
+	}
+
+	event := models.Event{
+		ID:          uuid.NewString(),
+		Type:        models.EventWebhookTest,
+		AggregateID: webhook.ID,
+		Payload:     payload,
+		CreatedAt:   s.now(),
+	}
+
+	body, err := events.Marshal(event)
+	if err != nil {
+		return models.DeliveryResult{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.webhooks.Timeout)
+	defer cancel()
+
+	return s.sender.Send(ctx, webhook, event.ID, event.Type, body), nil
+}
+
+func (s *userService) ListWebhookDeliveries(ctx context.Context, callerID, webhookID string, opts models.ListOptions) (models.DeliveryPage, error) {
+	if _, err := s.getOwnWebhook(ctx, callerID, webhookID); err != nil {
+		return models.DeliveryPage{}, err
+	}
+
+	opts.SortBy = models.SortByID
+	opts, err := normalizeListOptions(opts)
+	if err != nil {
+		return models.DeliveryPage{}, err
+	}
+
+	page, err := s.repo.ListDeliveries(ctx, webhookID, opts)
+	if err != nil {
+		return models.DeliveryPage{}, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *userService) getOwnWebhook(ctx context.Context, callerID, webhookID string) (models.Webhook, error) {
+	if callerID == "" {
+		return models.Webhook{}, ErrEmptyCallerID
+	}
+
+	webhook, err := s.repo.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	if webhook.OwnerID != callerID {
+		return models.Webhook{}, ErrPermissionDenied
+	}
+
+	return webhook, nil
+}
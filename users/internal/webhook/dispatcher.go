@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/config"
+	"github.com/avran02/decoplan/users/internal/events"
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/internal/repository"
+)
+
+const (
+	batchSize     = 20
+	deliveryLease = time.Minute
+)
+
+// Dispatcher queues a delivery per subscribed webhook for every relayed
+// event and sends them, retrying with exponential backoff. After MaxAttempts
+// the delivery goes to the dead letters.
+type Dispatcher struct {
+	repo   repository.Repository
+	sender *Sender
+	conf   config.Webhooks
+	kick   chan struct{}
+}
+
+// Publish makes the dispatcher an events.Sink. It only queues deliveries, so
+// a slow receiver never holds up the relay. Webhooks only get events about
+// their owner and the owner's groups.
+func (d *Dispatcher) Publish(ctx context.Context, event models.Event) error {
+	webhooks, err := d.repo.ListWebhooksOf(ctx, event.Audience)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if webhook.Wants(event) {
+			ids = append(ids, webhook.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	body, err := events.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	n, err := d.repo.EnqueueDeliveries(ctx, event, body, ids, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		d.Kick()
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	slog.Info("starting webhook dispatcher")
+	ticker := time.NewTicker(d.conf.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.kick:
+		}
+	}
+}
+
+func (d *Dispatcher) Kick() {
+	select {
+	case d.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) sendDue(ctx context.Context) {
+	for {
+		now := time.Now().UTC()
+		deliveries, err := d.repo.ClaimDueDeliveries(ctx, now, now.Add(deliveryLease), batchSize)
+		if err != nil {
+			slog.Error("failed to claim webhook deliveries", "error", err)
+			return
+		}
+
+		webhooks := make(map[string]models.Webhook)
+		for _, delivery := range deliveries {
+			webhook, ok := webhooks[delivery.WebhookID]
+			if !ok {
+				if webhook, err = d.repo.GetWebhook(ctx, delivery.WebhookID); err != nil {
+					// deleted in the meantime, its deliveries are gone with it
+					slog.Warn("failed to get webhook", "webhook", delivery.WebhookID, "error", err)
+					continue
+				}
+				webhooks[delivery.WebhookID] = webhook
+			}
+
+			d.send(ctx, webhook, delivery)
+		}
+
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) {
+	deliveryID := strconv.FormatInt(delivery.ID, 10)
+	sendCtx, cancel := context.WithTimeout(ctx, d.conf.Timeout)
+	result := d.sender.Send(sendCtx, webhook, deliveryID, delivery.EventType, delivery.Payload)
+	cancel()
+
+	now := time.Now().UTC()
+	if result.Err == nil {
+		if err := d.repo.CompleteDelivery(ctx, delivery.ID, result.StatusCode, now); err != nil {
+			slog.Error("failed to complete webhook delivery", "delivery", delivery.ID, "error", err)
+		}
+		return
+	}
+
+	var statusCode *int32
+	if result.StatusCode != 0 {
+		statusCode = &result.StatusCode
+	}
+
+	attempt := delivery.Attempts + 1
+	if int(attempt) >= d.conf.MaxAttempts {
+		slog.Warn("webhook delivery dead", "delivery", delivery.ID, "webhook", webhook.ID, "attempts", attempt, "error", result.Err)
+		if err := d.repo.KillDelivery(ctx, delivery.ID, statusCode, result.Err.Error(), now); err != nil {
+			slog.Error("failed to dead-letter webhook delivery", "delivery", delivery.ID, "error", err)
+		}
+		return
+	}
+
+	next := now.Add(d.backoff(delivery.Attempts))
+	slog.Warn("webhook delivery failed", "delivery", delivery.ID, "webhook", webhook.ID, "attempt", attempt, "retryAt", next, "error", result.Err)
+	if err := d.repo.FailDelivery(ctx, delivery.ID, statusCode, result.Err.Error(), next, now); err != nil {
+		slog.Error("failed to record webhook delivery failure", "delivery", delivery.ID, "error", err)
+	}
+}
+
+func (d *Dispatcher) backoff(attempts int32) time.Duration {
+	delay := d.conf.RetryBaseDelay
+	for i := int32(0); i < attempts && delay < d.conf.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, d.conf.RetryMaxDelay)
+}
+
+func NewDispatcher(repo repository.Repository, sender *Sender, conf config.Webhooks) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		sender: sender,
+		conf:   conf,
+		kick:   make(chan struct{}, 1),
+	}
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/avran02/decoplan/users/internal/config"
+	"github.com/avran02/decoplan/users/internal/models"
+	"github.com/avran02/decoplan/users/internal/repository"
+)
+
+// fakeRepo serves the webhook queries from memory. Any other call panics.
+type fakeRepo struct {
+	repository.Repository
+	webhooks []models.Webhook
+	enqueued []string
+}
+
+func (f *fakeRepo) ListWebhooksOf(_ context.Context, ownerIDs []string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	for _, webhook := range f.webhooks {
+		if slices.Contains(ownerIDs, webhook.OwnerID) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	return webhooks, nil
+}
+
+func (f *fakeRepo) EnqueueDeliveries(_ context.Context, _ models.Event, _ []byte, webhookIDs []string, _ time.Time) (int64, error) {
+	f.enqueued = append(f.enqueued, webhookIDs...)
+	return int64(len(webhookIDs)), nil
+}
+
+func TestDispatcherPublish(t *testing.T) {
+	webhooks := []models.Webhook{
+		{ID: "alice-all", OwnerID: "alice"},
+		{ID: "alice-users", OwnerID: "alice", EventTypes: []models.EventType{models.EventUserUpdated}},
+		{ID: "bob-all", OwnerID: "bob"},
+		{ID: "bob-groups", OwnerID: "bob", EventTypes: []models.EventType{models.EventGroupUpdated}},
+	}
+
+	tests := []struct {
+		name  string
+		event models.Event
+		want  []string
+	}{
+		{
+			name:  "own account",
+			event: models.Event{Type: models.EventUserUpdated, AggregateID: "alice", Audience: []string{"alice"}},
+			want:  []string{"alice-all", "alice-users"},
+		},
+		{
+			name:  "other user's account",
+			event: models.Event{Type: models.EventUserUpdated, AggregateID: "carol", Audience: []string{"carol"}},
+			want:  nil,
+		},
+		{
+			name:  "shared group",
+			event: models.Event{Type: models.EventGroupUpdated, AggregateID: "g1", Audience: []string{"alice", "bob"}},
+			want:  []string{"alice-all", "bob-all", "bob-groups"},
+		},
+		{
+			name:  "group of another user",
+			event: models.Event{Type: models.EventGroupUpdated, AggregateID: "g2", Audience: []string{"alice"}},
+			want:  []string{"alice-all"},
+		},
+		{
+			name:  "no audience",
+			event: models.Event{Type: models.EventGroupDeleted, AggregateID: "g3"},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{webhooks: webhooks}
+			d := NewDispatcher(repo, nil, config.Webhooks{})
+
+			if err := d.Publish(context.Background(), tt.event); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			if !slices.Equal(repo.enqueued, tt.want) {
+				t.Errorf("enqueued = %v, want %v", repo.enqueued, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := &Dispatcher{conf: config.Webhooks{RetryBaseDelay: 10 * time.Second, RetryMaxDelay: time.Minute}}
+
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{attempts: 0, want: 10 * time.Second},
+		{attempts: 1, want: 20 * time.Second},
+		{attempts: 2, want: 40 * time.Second},
+		{attempts: 3, want: time.Minute},
+		{attempts: 100, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package webhook
+
+import "errors"
+
+var ErrUnexpectedStatus = errors.New("unexpected response status")
@@ -0,0 +1,5 @@
+ALTER TABLE "user_groups" DROP CONSTRAINT IF EXISTS "user_groups_role_check";
+ALTER TABLE "user_groups" DROP COLUMN IF EXISTS "role";
+
+ALTER TABLE "groups" DROP CONSTRAINT IF EXISTS "groups_owner_id_fkey";
+ALTER TABLE "groups" DROP COLUMN IF EXISTS "owner_id";
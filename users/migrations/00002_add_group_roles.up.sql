@@ -0,0 +1,7 @@
+ALTER TABLE "groups" ADD COLUMN IF NOT EXISTS "owner_id" VARCHAR(255);
+ALTER TABLE "groups" ADD CONSTRAINT "groups_owner_id_fkey"
+    FOREIGN KEY("owner_id") REFERENCES "users"("id") ON DELETE SET NULL;
+
+ALTER TABLE "user_groups" ADD COLUMN IF NOT EXISTS "role" VARCHAR(16) NOT NULL DEFAULT 'member';
+ALTER TABLE "user_groups" ADD CONSTRAINT "user_groups_role_check"
+    CHECK ("role" IN ('owner', 'admin', 'member'));
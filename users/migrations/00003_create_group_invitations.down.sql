@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "group_invitations_invitee_pending_idx";
+DROP TABLE IF EXISTS "group_invitations";
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS "group_invitations"(
+    "id" VARCHAR(255) NOT NULL,
+    "group_id" VARCHAR(255) NOT NULL,
+    "inviter_id" VARCHAR(255),
+    "invitee_id" VARCHAR(255),
+    "code" VARCHAR(64) UNIQUE,
+    "status" VARCHAR(16) NOT NULL DEFAULT 'pending',
+    "max_uses" INTEGER,
+    "uses" INTEGER NOT NULL DEFAULT 0,
+    "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    "expires_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    "responded_at" TIMESTAMP(0) WITHOUT TIME ZONE,
+    PRIMARY KEY("id"),
+    FOREIGN KEY("group_id") REFERENCES "groups"("id") ON DELETE CASCADE,
+    FOREIGN KEY("inviter_id") REFERENCES "users"("id") ON DELETE SET NULL,
+    FOREIGN KEY("invitee_id") REFERENCES "users"("id") ON DELETE CASCADE,
+    CHECK ("status" IN ('pending', 'accepted', 'declined')),
+    CHECK ("invitee_id" IS NOT NULL OR "code" IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS "group_invitations_invitee_pending_idx"
+    ON "group_invitations"("invitee_id", "expires_at")
+    WHERE "status" = 'pending';
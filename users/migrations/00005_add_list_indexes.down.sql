@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "groups_owner_id_idx";
+DROP INDEX IF EXISTS "groups_name_id_idx";
+
+DROP INDEX IF EXISTS "users_name_trgm_idx";
+DROP INDEX IF EXISTS "users_name_id_idx";
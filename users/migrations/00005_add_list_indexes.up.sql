@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+
+CREATE INDEX IF NOT EXISTS "users_name_id_idx" ON "users"(LOWER("name"), "id");
+CREATE INDEX IF NOT EXISTS "users_name_trgm_idx" ON "users" USING GIN (LOWER("name") gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS "groups_name_id_idx" ON "groups"(LOWER("name"), "id");
+CREATE INDEX IF NOT EXISTS "groups_owner_id_idx" ON "groups"("owner_id");
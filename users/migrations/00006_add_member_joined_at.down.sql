@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "user_groups_group_id_idx";
+
+ALTER TABLE "user_groups" DROP COLUMN IF EXISTS "joined_at";
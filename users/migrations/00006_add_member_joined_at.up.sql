@@ -0,0 +1,4 @@
+ALTER TABLE "user_groups" ADD COLUMN IF NOT EXISTS "joined_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL
+    DEFAULT (NOW() AT TIME ZONE 'utc');
+
+CREATE INDEX IF NOT EXISTS "user_groups_group_id_idx" ON "user_groups"("group_id");
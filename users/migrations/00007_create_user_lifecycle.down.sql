@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "user_lifecycle_jobs_due_idx";
+DROP INDEX IF EXISTS "user_lifecycle_jobs_user_id_idx";
+DROP TABLE IF EXISTS "user_lifecycle_jobs";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "deleted_at";
@@ -0,0 +1,20 @@
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMP(0) WITHOUT TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS "user_lifecycle_jobs"(
+    "id" VARCHAR(255) NOT NULL,
+    "user_id" VARCHAR(255) NOT NULL,
+    "kind" VARCHAR(16) NOT NULL,
+    "status" VARCHAR(16) NOT NULL DEFAULT 'pending',
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "last_error" TEXT,
+    "next_attempt_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    "updated_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    PRIMARY KEY("id"),
+    CHECK ("kind" IN ('provision', 'purge')),
+    CHECK ("status" IN ('pending', 'done'))
+);
+
+CREATE INDEX IF NOT EXISTS "user_lifecycle_jobs_user_id_idx" ON "user_lifecycle_jobs"("user_id");
+CREATE INDEX IF NOT EXISTS "user_lifecycle_jobs_due_idx" ON "user_lifecycle_jobs"("next_attempt_at")
+    WHERE "status" = 'pending';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "outbox_published_at_idx";
+DROP INDEX IF EXISTS "outbox_unpublished_idx";
+DROP TABLE IF EXISTS "outbox";
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS "outbox"(
+    "sequence" BIGSERIAL NOT NULL,
+    "id" VARCHAR(255) NOT NULL UNIQUE,
+    "type" VARCHAR(64) NOT NULL,
+    "aggregate_type" VARCHAR(32) NOT NULL,
+    "aggregate_id" VARCHAR(255) NOT NULL,
+    "payload" JSONB NOT NULL,
+    "created_at" TIMESTAMP(3) WITHOUT TIME ZONE NOT NULL,
+    "published_at" TIMESTAMP(3) WITHOUT TIME ZONE,
+    PRIMARY KEY("sequence")
+);
+
+CREATE INDEX IF NOT EXISTS "outbox_unpublished_idx" ON "outbox"("sequence")
+    WHERE "published_at" IS NULL;
+CREATE INDEX IF NOT EXISTS "outbox_published_at_idx" ON "outbox"("published_at");
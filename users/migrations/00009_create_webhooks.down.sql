@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS "webhook_dead_letters_webhook_id_idx";
+DROP TABLE IF EXISTS "webhook_dead_letters";
+
+DROP INDEX IF EXISTS "webhook_deliveries_due_idx";
+DROP TABLE IF EXISTS "webhook_deliveries";
+
+DROP INDEX IF EXISTS "webhooks_owner_id_idx";
+DROP TABLE IF EXISTS "webhooks";
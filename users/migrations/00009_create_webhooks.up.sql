@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS "webhooks"(
+    "id" VARCHAR(255) NOT NULL,
+    "owner_id" VARCHAR(255) NOT NULL,
+    "url" TEXT NOT NULL,
+    "secret" VARCHAR(255) NOT NULL,
+    "event_types" TEXT[] NOT NULL DEFAULT '{}',
+    "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    PRIMARY KEY("id"),
+    FOREIGN KEY("owner_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "webhooks_owner_id_idx" ON "webhooks"("owner_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries"(
+    "id" BIGSERIAL NOT NULL,
+    "webhook_id" VARCHAR(255) NOT NULL,
+    "event_id" VARCHAR(255) NOT NULL,
+    "event_type" VARCHAR(64) NOT NULL,
+    "payload" JSONB NOT NULL,
+    "status" VARCHAR(16) NOT NULL DEFAULT 'pending',
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "last_status_code" INTEGER,
+    "last_error" TEXT,
+    "next_attempt_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    "updated_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    PRIMARY KEY("id"),
+    UNIQUE("webhook_id", "event_id"),
+    FOREIGN KEY("webhook_id") REFERENCES "webhooks"("id") ON DELETE CASCADE,
+    CHECK ("status" IN ('pending', 'delivered', 'dead'))
+);
+
+CREATE INDEX IF NOT EXISTS "webhook_deliveries_due_idx" ON "webhook_deliveries"("next_attempt_at")
+    WHERE "status" = 'pending';
+
+CREATE TABLE IF NOT EXISTS "webhook_dead_letters"(
+    "delivery_id" BIGINT NOT NULL,
+    "webhook_id" VARCHAR(255) NOT NULL,
+    "event_id" VARCHAR(255) NOT NULL,
+    "event_type" VARCHAR(64) NOT NULL,
+    "payload" JSONB NOT NULL,
+    "attempts" INTEGER NOT NULL,
+    "last_error" TEXT,
+    "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    PRIMARY KEY("delivery_id"),
+    FOREIGN KEY("delivery_id") REFERENCES "webhook_deliveries"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "webhook_dead_letters_webhook_id_idx" ON "webhook_dead_letters"("webhook_id");
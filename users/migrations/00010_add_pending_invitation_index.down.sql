@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "group_invitations_pending_invitee_idx";
@@ -0,0 +1,9 @@
+DELETE FROM "group_invitations" AS "older"
+    USING "group_invitations" AS "newer"
+    WHERE "older"."status" = 'pending' AND "newer"."status" = 'pending'
+    AND "older"."group_id" = "newer"."group_id" AND "older"."invitee_id" = "newer"."invitee_id"
+    AND ("older"."created_at", "older"."id") < ("newer"."created_at", "newer"."id");
+
+CREATE UNIQUE INDEX IF NOT EXISTS "group_invitations_pending_invitee_idx"
+    ON "group_invitations"("group_id", "invitee_id")
+    WHERE "status" = 'pending';
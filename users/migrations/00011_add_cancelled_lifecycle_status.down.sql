@@ -0,0 +1,5 @@
+DELETE FROM "user_lifecycle_jobs" WHERE "status" = 'cancelled';
+
+ALTER TABLE "user_lifecycle_jobs" DROP CONSTRAINT IF EXISTS "user_lifecycle_jobs_status_check";
+ALTER TABLE "user_lifecycle_jobs" ADD CONSTRAINT "user_lifecycle_jobs_status_check"
+    CHECK ("status" IN ('pending', 'done'));
@@ -0,0 +1,2 @@
+DROP SEQUENCE IF EXISTS "outbox_published_sequence_seq";
+ALTER TABLE "outbox" DROP COLUMN IF EXISTS "published_sequence";
@@ -0,0 +1,7 @@
+ALTER TABLE "outbox" ADD COLUMN IF NOT EXISTS "published_sequence" BIGINT UNIQUE;
+
+-- events already out keep their sequence, so consumers can resume from them
+UPDATE "outbox" SET "published_sequence" = "sequence" WHERE "published_at" IS NOT NULL;
+
+CREATE SEQUENCE IF NOT EXISTS "outbox_published_sequence_seq";
+SELECT setval('outbox_published_sequence_seq', (SELECT COALESCE(MAX("sequence"), 0) + 1 FROM "outbox"), false);
@@ -0,0 +1,9 @@
+ALTER TABLE "outbox" ADD COLUMN IF NOT EXISTS "audience" VARCHAR(255)[] NOT NULL DEFAULT '{}';
+
+-- events still waiting for the relay go to the user they're about, or to the
+-- group's current members
+UPDATE "outbox" SET "audience" = ARRAY["aggregate_id"]
+WHERE "published_at" IS NULL AND "aggregate_type" = 'user';
+
+UPDATE "outbox" o SET "audience" = ARRAY(SELECT "user_id" FROM "user_groups" WHERE "group_id" = o."aggregate_id")
+WHERE "published_at" IS NULL AND "aggregate_type" <> 'user';
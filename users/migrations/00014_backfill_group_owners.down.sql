@@ -0,0 +1 @@
+-- backfilled owners can't be told apart from real ones, they stay
@@ -0,0 +1,13 @@
+-- groups created before roles get the member who joined first as owner,
+-- ties broken by id
+UPDATE "groups" SET "owner_id" = (
+    SELECT "user_groups"."user_id" FROM "user_groups"
+    JOIN "users" ON "users"."id" = "user_groups"."user_id" AND "users"."deleted_at" IS NULL
+    WHERE "user_groups"."group_id" = "groups"."id"
+    ORDER BY "user_groups"."joined_at", "user_groups"."user_id"
+    LIMIT 1
+) WHERE "owner_id" IS NULL;
+
+UPDATE "user_groups" SET "role" = 'owner'
+    FROM "groups"
+    WHERE "groups"."id" = "user_groups"."group_id" AND "groups"."owner_id" = "user_groups"."user_id";